@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/nobonobo/webrtc"
+	"github.com/nobonobo/webrtc/signaling"
+)
+
+func main() {
+	addr := flag.String("serve", "", "run signaling server on this address")
+	url := flag.String("url", "ws://localhost:8080/", "signaling server url")
+	room := flag.String("room", "test", "room id")
+	flag.Parse()
+
+	if *addr != "" {
+		log.Fatalln(http.ListenAndServe(*addr, signaling.NewServer()))
+	}
+
+	config := webrtc.NewConfiguration()
+	config.AddIceServer("stun:stun.l.google.com:19302")
+	pc, err := webrtc.NewPeerConnection(config)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer pc.Close()
+	dc, err := pc.CreateDataChannel("chat")
+	if err != nil {
+		log.Fatalln(err)
+	}
+	dc.OnOpen(func() {
+		dc.Send([]byte("hello from " + *room))
+	})
+	dc.OnMessage(func(b []byte) {
+		log.Println("recv:", string(b))
+	})
+	s, err := signaling.Dial(*url, *room)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer s.Close()
+	log.Fatalln(webrtc.Connect(pc, s))
+}
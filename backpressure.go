@@ -0,0 +1,82 @@
+package webrtc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrDataChannelNotOpen ...
+var ErrDataChannelNotOpen = errors.New("data channel is not open")
+
+// maxBufferedAmount is how much SendContext lets queue up in a channel,
+// well below the point where browsers close the channel.
+const maxBufferedAmount = 1 << 20
+
+// bufferedAmountPoll bounds the wait for a bufferedamountlow event that may
+// never come when the threshold is not crossed.
+const bufferedAmountPoll = 10 * time.Millisecond
+
+// notifier wakes every waiter on broadcast.
+type notifier struct {
+	mu sync.Mutex
+	ch chan struct{}
+}
+
+func (n *notifier) wait() <-chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.ch == nil {
+		n.ch = make(chan struct{})
+	}
+	return n.ch
+}
+
+func (n *notifier) broadcast() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.ch != nil {
+		close(n.ch)
+		n.ch = nil
+	}
+}
+
+// BufferedAmountLowEvent ...
+type BufferedAmountLowEvent struct{}
+
+// Type ...
+func (BufferedAmountLowEvent) Type() string { return "bufferedamountlow" }
+
+// SendContext sends data once the channel has buffer space for it, waiting
+// for the buffered amount to drain if needed. It fails with
+// ErrDataChannelNotOpen unless the channel is open, and returns the errors
+// that Send only logs.
+func (c *DataChannel) SendContext(ctx context.Context, data []byte) error {
+	return c.sendContext(ctx, len(data), func() error {
+		return c.sendMessage(data, false)
+	})
+}
+
+func (c *DataChannel) sendContext(ctx context.Context, n int, send func() error) error {
+	var ticker *time.Ticker
+	for {
+		if c.ReadyState() != "open" {
+			return ErrDataChannelNotOpen
+		}
+		buffered := c.BufferedAmount()
+		if buffered == 0 || buffered+n <= maxBufferedAmount {
+			return send()
+		}
+		if ticker == nil {
+			ticker = time.NewTicker(bufferedAmountPoll)
+			defer ticker.Stop()
+		}
+		select {
+		case <-c.low.wait():
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// +build mock
+
+package webrtc
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSendClosed(t *testing.T) {
+	var buf bytes.Buffer
+	SetLogger(slog.NewTextHandler(&buf, nil))
+	defer SetLogger(nil)
+	pc, err := NewPeerConnection(&Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	dc, err := pc.CreateDataChannel("closed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dc.Close()
+	if err := dc.sendMessage([]byte("lost"), false); err != ErrDataChannelNotOpen {
+		t.Fatalf("sendMessage: got %v, want %v", err, ErrDataChannelNotOpen)
+	}
+	if err := dc.SendContext(context.Background(), []byte("lost")); err != ErrDataChannelNotOpen {
+		t.Fatalf("SendContext: got %v, want %v", err, ErrDataChannelNotOpen)
+	}
+	dc.SendText("lost")
+	if !strings.Contains(buf.String(), "data channel send") {
+		t.Fatalf("send failure not logged: %q", buf.String())
+	}
+}
+
+// fill makes dc look like it has n bytes waiting to be sent.
+func fill(dc *DataChannel, n int) {
+	dc.mu.Lock()
+	dc.buffered += n
+	dc.mu.Unlock()
+}
+
+func TestSendContextBlocks(t *testing.T) {
+	dc, rc := channelPair(t, "blocked")
+	events := rc.Events()
+	defer rc.Unsubscribe(events)
+	low := dc.Events()
+	defer dc.Unsubscribe(low)
+
+	// room for the message: sent at once.
+	fill(dc, maxBufferedAmount-10)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := dc.SendContext(ctx, make([]byte, 10)); err != nil {
+		t.Fatal(err)
+	}
+	if m := nextMessage(t, events); len(m.Data) != 10 {
+		t.Fatalf("got %d bytes", len(m.Data))
+	}
+
+	// one byte over the limit waits for bufferedamountlow.
+	fill(dc, 10)
+	errc := make(chan error, 1)
+	go func() { errc <- dc.SendContext(ctx, []byte("x")) }()
+	select {
+	case err := <-errc:
+		t.Fatalf("sent over the limit: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	dc.delivered(maxBufferedAmount)
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("send not resumed after bufferedamountlow")
+	}
+	if m := nextMessage(t, events); string(m.Data) != "x" {
+		t.Fatalf("got %q", m.Data)
+	}
+	for {
+		ev, ok := next(t, low)
+		if !ok {
+			t.Fatal("events closed")
+		}
+		if _, ok := ev.(BufferedAmountLowEvent); ok {
+			break
+		}
+	}
+}
+
+func TestSendContextCancel(t *testing.T) {
+	dc, _ := channelPair(t, "cancelled")
+	fill(dc, maxBufferedAmount)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	if err := dc.SendContext(ctx, []byte("x")); err != context.Canceled {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("returned after %v", d)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := dc.SendTextContext(ctx, "x"); err != context.DeadlineExceeded {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+	if n := dc.BufferedAmount(); n != maxBufferedAmount {
+		t.Fatalf("buffered %d after the cancelled sends", n)
+	}
+}
@@ -0,0 +1,76 @@
+package webrtc
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrCandidateDropped is reported for a queued remote candidate that was
+// never applied.
+var ErrCandidateDropped = errors.New("ice candidate dropped")
+
+// maxPendingCandidates bounds the remote candidates queued before a remote
+// description is set.
+const maxPendingCandidates = 256
+
+// candidateQueue buffers remote candidates until a remote description exists.
+type candidateQueue struct {
+	mu      sync.Mutex
+	ready   bool
+	pending []*IceCandidate
+	onError func(*IceCandidate, error)
+}
+
+func (q *candidateQueue) add(ic *IceCandidate, apply func(*IceCandidate) error) error {
+	q.mu.Lock()
+	if q.ready {
+		q.mu.Unlock()
+		return apply(ic)
+	}
+	if len(q.pending) >= maxPendingCandidates {
+		q.mu.Unlock()
+		q.report(ic, ErrCandidateDropped)
+		return ErrCandidateDropped
+	}
+	q.pending = append(q.pending, ic)
+	q.mu.Unlock()
+	return nil
+}
+
+func (q *candidateQueue) flush(apply func(*IceCandidate) error) {
+	q.mu.Lock()
+	pending := q.pending
+	q.pending = nil
+	q.ready = true
+	q.mu.Unlock()
+	for _, ic := range pending {
+		if err := apply(ic); err != nil {
+			q.report(ic, err)
+		}
+	}
+}
+
+func (q *candidateQueue) drop() {
+	q.mu.Lock()
+	pending := q.pending
+	q.pending = nil
+	q.mu.Unlock()
+	for _, ic := range pending {
+		q.report(ic, ErrCandidateDropped)
+	}
+}
+
+func (q *candidateQueue) report(ic *IceCandidate, err error) {
+	q.mu.Lock()
+	cb := q.onError
+	q.mu.Unlock()
+	if cb != nil {
+		cb(ic, err)
+	}
+}
+
+func (q *candidateQueue) setOnError(cb func(*IceCandidate, error)) {
+	q.mu.Lock()
+	q.onError = cb
+	q.mu.Unlock()
+}
@@ -0,0 +1,98 @@
+package webrtc
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+)
+
+// queueLog records the candidates applied and reported by a queue.
+type queueLog struct {
+	applied  []string
+	reported []string
+	errs     []error
+}
+
+func (l *queueLog) apply(ic *IceCandidate) error {
+	if ic.Candidate == "bad" {
+		return errors.New("bad candidate")
+	}
+	l.applied = append(l.applied, ic.Candidate)
+	return nil
+}
+
+func (l *queueLog) report(ic *IceCandidate, err error) {
+	l.reported = append(l.reported, ic.Candidate)
+	l.errs = append(l.errs, err)
+}
+
+func TestCandidateQueueOrder(t *testing.T) {
+	var q candidateQueue
+	var l queueLog
+	q.setOnError(l.report)
+	for _, c := range []string{"1", "bad", "2", "3"} {
+		if err := q.add(NewIceCandidate(c, "0", 0), l.apply); err != nil {
+			t.Fatalf("add %s: %v", c, err)
+		}
+	}
+	if len(l.applied) != 0 {
+		t.Fatalf("applied before the remote description: %v", l.applied)
+	}
+	q.flush(l.apply)
+	if got := fmt.Sprint(l.applied); got != "[1 2 3]" {
+		t.Fatalf("applied %q, want in arrival order", got)
+	}
+	if len(l.reported) != 1 || l.reported[0] != "bad" || l.errs[0] == nil || l.errs[0] == ErrCandidateDropped {
+		t.Fatalf("reported %v: %v", l.reported, l.errs)
+	}
+	// once ready, candidates are applied at once and errors returned.
+	if err := q.add(NewIceCandidate("4", "0", 0), l.apply); err != nil || len(l.applied) != 4 {
+		t.Fatalf("add after flush: %v, applied %v", err, l.applied)
+	}
+	if err := q.add(NewIceCandidate("bad", "0", 0), l.apply); err == nil {
+		t.Fatal("add after flush hid the error")
+	}
+}
+
+func TestCandidateQueueOverflow(t *testing.T) {
+	var q candidateQueue
+	var l queueLog
+	q.setOnError(l.report)
+	for i := 0; i < maxPendingCandidates; i++ {
+		if err := q.add(NewIceCandidate(strconv.Itoa(i), "0", 0), l.apply); err != nil {
+			t.Fatalf("add %d: %v", i, err)
+		}
+	}
+	if err := q.add(NewIceCandidate("over", "0", 0), l.apply); err != ErrCandidateDropped {
+		t.Fatalf("add over the limit: got %v, want %v", err, ErrCandidateDropped)
+	}
+	if len(l.reported) != 1 || l.reported[0] != "over" || l.errs[0] != ErrCandidateDropped {
+		t.Fatalf("reported %v: %v", l.reported, l.errs)
+	}
+	q.flush(l.apply)
+	if len(l.applied) != maxPendingCandidates || l.applied[0] != "0" {
+		t.Fatalf("applied %d candidates", len(l.applied))
+	}
+}
+
+func TestCandidateQueueDrop(t *testing.T) {
+	var q candidateQueue
+	var l queueLog
+	q.setOnError(l.report)
+	q.add(NewIceCandidate("1", "0", 0), l.apply)
+	q.add(NewIceCandidate("2", "0", 0), l.apply)
+	q.drop()
+	if got := fmt.Sprint(l.reported); got != "[1 2]" {
+		t.Fatalf("reported %q", got)
+	}
+	for _, err := range l.errs {
+		if err != ErrCandidateDropped {
+			t.Fatalf("reported %v, want %v", err, ErrCandidateDropped)
+		}
+	}
+	q.flush(l.apply)
+	if len(l.applied) != 0 {
+		t.Fatalf("dropped candidates applied: %v", l.applied)
+	}
+}
@@ -0,0 +1,154 @@
+package webrtc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrIceGatheringTimeout ...
+var ErrIceGatheringTimeout = errors.New("ice gathering timeout")
+
+// gatheringState tracks the ICE gathering state of a PeerConnection.
+type gatheringState struct {
+	mu    sync.Mutex
+	state IceGatheringState
+	done  chan struct{}
+}
+
+func (g *gatheringState) set(state IceGatheringState) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.state = state
+	if g.done == nil {
+		g.done = make(chan struct{})
+	}
+	complete := state == IceGatheringStateComplete
+	select {
+	case <-g.done:
+		if !complete {
+			g.done = make(chan struct{})
+		}
+	default:
+		if complete {
+			close(g.done)
+		}
+	}
+}
+
+func (g *gatheringState) get() IceGatheringState {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.state
+}
+
+func (g *gatheringState) wait() <-chan struct{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.done == nil {
+		g.done = make(chan struct{})
+	}
+	return g.done
+}
+
+// WaitIceGatheringComplete waits until ICE gathering is complete.
+// A timeout of zero or less waits forever.
+func (pc *PeerConnection) WaitIceGatheringComplete(timeout time.Duration) error {
+	ctx, cancel := timeoutContext(timeout)
+	defer cancel()
+	return gatheringTimeout(pc.WaitIceGatheringCompleteContext(ctx))
+}
+
+// WaitIceGatheringCompleteContext ...
+func (pc *PeerConnection) WaitIceGatheringCompleteContext(ctx context.Context) error {
+	select {
+	case <-pc.gathering.wait():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func timeoutContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(context.Background(), timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// withContext runs the blocking call f, returning early with ctx.Err() when
+// ctx is done. f itself still runs to completion in the background.
+func withContext(ctx context.Context, f func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- f()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func gatheringTimeout(err error) error {
+	if err == context.DeadlineExceeded {
+		return ErrIceGatheringTimeout
+	}
+	return err
+}
+
+// complete creates a description with create, sets it and waits for ICE
+// gathering. With timeout set, a deadline hit while gathering is reported as
+// ErrIceGatheringTimeout; the earlier steps return ctx.Err() unchanged.
+func (pc *PeerConnection) complete(ctx context.Context, create func(context.Context) (*SessionDescription, error), timeout bool) (*SessionDescription, error) {
+	sd, err := create(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := pc.SetLocalDescriptionContext(ctx, sd); err != nil {
+		return nil, err
+	}
+	if err := pc.WaitIceGatheringCompleteContext(ctx); err != nil {
+		if timeout {
+			err = gatheringTimeout(err)
+		}
+		return pc.LocalDescription(), err
+	}
+	return pc.LocalDescription(), nil
+}
+
+// CreateOfferComplete creates an offer, sets it as the local description
+// and waits for ICE gathering to complete, for signaling without trickle ICE.
+// The returned LocalDescription carries every gathered candidate. On timeout
+// while gathering the description gathered so far is returned with
+// ErrIceGatheringTimeout; a timeout in an earlier step returns
+// context.DeadlineExceeded.
+func (pc *PeerConnection) CreateOfferComplete(timeout time.Duration) (*SessionDescription, error) {
+	ctx, cancel := timeoutContext(timeout)
+	defer cancel()
+	return pc.complete(ctx, pc.CreateOfferContext, true)
+}
+
+// CreateOfferCompleteContext is CreateOfferComplete bounded by ctx instead of
+// a timeout. When ctx is done while gathering, the description gathered so
+// far is returned with ctx.Err().
+func (pc *PeerConnection) CreateOfferCompleteContext(ctx context.Context) (*SessionDescription, error) {
+	return pc.complete(ctx, pc.CreateOfferContext, false)
+}
+
+// CreateAnswerComplete is CreateOfferComplete for an answer.
+func (pc *PeerConnection) CreateAnswerComplete(timeout time.Duration) (*SessionDescription, error) {
+	ctx, cancel := timeoutContext(timeout)
+	defer cancel()
+	return pc.complete(ctx, pc.CreateAnswerContext, true)
+}
+
+// CreateAnswerCompleteContext is CreateOfferCompleteContext for an answer.
+func (pc *PeerConnection) CreateAnswerCompleteContext(ctx context.Context) (*SessionDescription, error) {
+	return pc.complete(ctx, pc.CreateAnswerContext, false)
+}
@@ -0,0 +1,202 @@
+// +build mock
+
+package webrtc
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCreateOfferComplete(t *testing.T) {
+	pc, err := NewPeerConnection(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	if _, err := pc.CreateDataChannel("x"); err != nil {
+		t.Fatal(err)
+	}
+	sd, err := pc.CreateOfferComplete(5 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sd.Type != "offer" || pc.IceGatheringState() != IceGatheringStateComplete {
+		t.Fatalf("%s, gathering %s", sd.Type, pc.IceGatheringState())
+	}
+}
+
+func TestGatheringTimeout(t *testing.T) {
+	pc, err := NewPeerConnection(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	// the mock gathers only from the new state, so gathering never ends.
+	pc.gathering.set(IceGatheringStateGathering)
+	sd, err := pc.CreateOfferComplete(50 * time.Millisecond)
+	if err != ErrIceGatheringTimeout {
+		t.Fatalf("got %v, want %v", err, ErrIceGatheringTimeout)
+	}
+	if sd == nil || sd.Type != "offer" {
+		t.Fatalf("description so far %v", sd)
+	}
+	if err := pc.WaitIceGatheringComplete(10 * time.Millisecond); err != ErrIceGatheringTimeout {
+		t.Fatalf("wait: got %v, want %v", err, ErrIceGatheringTimeout)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := pc.WaitIceGatheringCompleteContext(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("wait context: got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestEarlierStepTimeout(t *testing.T) {
+	pc, err := NewPeerConnection(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	for name, create := range map[string]func(context.Context) (*SessionDescription, error){
+		"offer":  pc.CreateOfferContext,
+		"answer": pc.CreateAnswerContext,
+		// the offer is made, setting it times out.
+		"set local": func(context.Context) (*SessionDescription, error) {
+			return pc.CreateOffer()
+		},
+	} {
+		sd, err := pc.complete(ctx, create, true)
+		if err != context.DeadlineExceeded || sd != nil {
+			t.Fatalf("%s: got %v, %v, want %v", name, sd, err, context.DeadlineExceeded)
+		}
+	}
+	if pc.SignalingState() != SignalingStateStable || pc.LocalDescription() != nil {
+		t.Fatalf("signaling %s after the timeout", pc.SignalingState())
+	}
+}
+
+func TestWithContext(t *testing.T) {
+	// an expired ctx doesn't start f.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := withContext(ctx, func() error {
+		t.Error("f called with a done ctx")
+		return nil
+	}); err != context.Canceled {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+
+	// cancelling returns at once while f is still blocked.
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	start := time.Now()
+	err := withContext(ctx, func() error {
+		defer close(finished)
+		<-release
+		return nil
+	})
+	if err != context.Canceled {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("returned after %v", d)
+	}
+	close(release)
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("f blocked after its caller left")
+	}
+}
+
+func TestContextVariants(t *testing.T) {
+	a, err := NewPeerConnection(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := NewPeerConnection(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	if _, err := a.CreateDataChannel("x"); err != nil {
+		t.Fatal(err)
+	}
+	offer, err := a.CreateOffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	candidate := NewIceCandidate("candidate:1 1 udp 2130706431 192.0.2.1 5000 typ host", "0", 0)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	for _, ctx := range []context.Context{cancelled, expired} {
+		for name, call := range map[string]func(context.Context) error{
+			"CreateOfferContext": func(ctx context.Context) error {
+				_, err := a.CreateOfferContext(ctx)
+				return err
+			},
+			"CreateOfferCompleteContext": func(ctx context.Context) error {
+				_, err := a.CreateOfferCompleteContext(ctx)
+				return err
+			},
+			"SetLocalDescriptionContext": func(ctx context.Context) error {
+				return a.SetLocalDescriptionContext(ctx, offer)
+			},
+			"SetRemoteDescriptionContext": func(ctx context.Context) error {
+				return b.SetRemoteDescriptionContext(ctx, offer)
+			},
+			"CreateAnswerContext": func(ctx context.Context) error {
+				_, err := b.CreateAnswerContext(ctx)
+				return err
+			},
+			"AddIceCandidateContext": func(ctx context.Context) error {
+				return b.AddIceCandidateContext(ctx, candidate)
+			},
+		} {
+			start := time.Now()
+			if err := call(ctx); err != ctx.Err() {
+				t.Errorf("%s: got %v, want %v", name, err, ctx.Err())
+			}
+			if d := time.Since(start); d > time.Second {
+				t.Errorf("%s: returned after %v", name, d)
+			}
+		}
+	}
+	for _, pc := range []*PeerConnection{a, b} {
+		if pc.SignalingState() != SignalingStateStable || pc.LocalDescription() != nil || pc.RemoteDescription() != nil {
+			t.Fatalf("signaling %s after cancelled calls", pc.SignalingState())
+		}
+	}
+
+	// both stay usable.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	offer, err = a.CreateOfferCompleteContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SetRemoteDescriptionContext(ctx, offer); err != nil {
+		t.Fatal(err)
+	}
+	answer, err := b.CreateAnswerCompleteContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SetRemoteDescriptionContext(ctx, answer); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.AddIceCandidateContext(ctx, candidate); err != nil {
+		t.Fatal(err)
+	}
+	if a.ConnectionState() != PeerConnectionStateConnected {
+		t.Fatalf("connection state %s", a.ConnectionState())
+	}
+}
@@ -0,0 +1,373 @@
+package webrtc
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nobonobo/webrtc/sdp"
+)
+
+// maxWriteChunk is the largest message Conn.Write sends; every browser
+// accepts messages of this size.
+const maxWriteChunk = 16 * 1024
+
+// Addr is one end of a DataChannel, taken from the selected ICE candidate
+// pair. go-webrtc does not report that pair, so on that backend an Addr only
+// carries the Label.
+type Addr struct {
+	Label         string
+	Protocol      string
+	IP            string
+	Port          int
+	CandidateType string
+}
+
+// Network ...
+func (a *Addr) Network() string {
+	return "webrtc"
+}
+
+// String ...
+func (a *Addr) String() string {
+	if a.IP == "" {
+		return a.Label
+	}
+	return net.JoinHostPort(a.IP, strconv.Itoa(a.Port))
+}
+
+func newAddr(label string, ic *IceCandidate) *Addr {
+	a := &Addr{Label: label}
+	if ic == nil {
+		return a
+	}
+	if c, err := sdp.ParseCandidate(ic.Candidate); err == nil {
+		a.Protocol = c.Protocol
+		a.IP = c.Address
+		a.Port = c.Port
+		a.CandidateType = c.Type
+	}
+	return a
+}
+
+// sdpCandidates returns the candidates embedded in sd.
+func sdpCandidates(sd *SessionDescription) []*IceCandidate {
+	if sd == nil {
+		return nil
+	}
+	s, err := sd.Session()
+	if err != nil {
+		return nil
+	}
+	var cands []*IceCandidate
+	for i, m := range s.Media {
+		mc, _ := m.Candidates()
+		for _, c := range mc {
+			cands = append(cands, NewIceCandidate(c.String(), m.Mid(), i))
+		}
+	}
+	return cands
+}
+
+// bestCandidate returns the highest priority candidate among cands and the
+// candidates embedded in sd.
+func bestCandidate(cands []*IceCandidate, sd *SessionDescription) *IceCandidate {
+	cands = append(cands, sdpCandidates(sd)...)
+	var best *IceCandidate
+	var prio uint32
+	for _, ic := range cands {
+		c, err := sdp.ParseCandidate(ic.Candidate)
+		if err != nil {
+			continue
+		}
+		if best == nil || c.Priority > prio {
+			best, prio = ic, c.Priority
+		}
+	}
+	return best
+}
+
+// candidateLog records the candidates seen by a PeerConnection, for backends
+// that cannot report the selected candidate pair.
+type candidateLog struct {
+	mu     sync.Mutex
+	local  []*IceCandidate
+	remote []*IceCandidate
+}
+
+func (l *candidateLog) addLocal(ic *IceCandidate) {
+	l.mu.Lock()
+	l.local = append(l.local, ic)
+	l.mu.Unlock()
+}
+
+func (l *candidateLog) addRemote(ic *IceCandidate) {
+	l.mu.Lock()
+	l.remote = append(l.remote, ic)
+	l.mu.Unlock()
+}
+
+func (l *candidateLog) all() (local, remote []*IceCandidate) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]*IceCandidate{}, l.local...), append([]*IceCandidate{}, l.remote...)
+}
+
+// pair approximates the selected pair by the best candidate of each side.
+func (l *candidateLog) pair(localSD, remoteSD *SessionDescription) (*IceCandidate, *IceCandidate) {
+	local, remote := l.all()
+	return bestCandidate(local, localSD), bestCandidate(remote, remoteSD)
+}
+
+// deadline is a resettable timer exposed as a channel.
+type deadline struct {
+	mu    sync.Mutex
+	timer *time.Timer
+	done  chan struct{}
+}
+
+func newDeadline() *deadline {
+	return &deadline{done: make(chan struct{})}
+}
+
+func (d *deadline) set(t time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	select {
+	case <-d.done:
+		d.done = make(chan struct{})
+	default:
+	}
+	if t.IsZero() {
+		return
+	}
+	done := d.done
+	dur := time.Until(t)
+	if dur <= 0 {
+		close(done)
+		return
+	}
+	d.timer = time.AfterFunc(dur, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		select {
+		case <-done:
+		default:
+			close(done)
+		}
+	})
+}
+
+func (d *deadline) wait() <-chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.done
+}
+
+// dataChannelConn adapts a DataChannel to net.Conn.
+type dataChannelConn struct {
+	c      *DataChannel
+	events <-chan Event
+	msgs   chan []byte
+	opened chan struct{}
+	closed chan struct{}
+	eof    chan struct{}
+	once   sync.Once
+
+	rmu  sync.Mutex
+	rbuf []byte
+
+	// wmu keeps the chunks of one Write together.
+	wmu sync.Mutex
+
+	readDeadline  *deadline
+	writeDeadline *deadline
+}
+
+// Conn returns a net.Conn reading and writing messages of the DataChannel.
+// Writes wait for the channel to open, are split into messages of at most
+// 16KiB and block while the channel buffer is full; concurrent Writes do not
+// interleave their messages. Closing the Conn closes
+// the DataChannel. Messages received before the first Conn or Events call
+// are kept for it, so Conn may be called any time after the channel is
+// created or announced by a DataChannelEvent.
+func (c *DataChannel) Conn() net.Conn {
+	conn := &dataChannelConn{
+		c:             c,
+		events:        c.Events(),
+		msgs:          make(chan []byte),
+		opened:        make(chan struct{}),
+		closed:        make(chan struct{}),
+		eof:           make(chan struct{}),
+		readDeadline:  newDeadline(),
+		writeDeadline: newDeadline(),
+	}
+	if c.ReadyState() == "open" {
+		close(conn.opened)
+	}
+	go conn.pump()
+	return conn
+}
+
+func (conn *dataChannelConn) pump() {
+	defer close(conn.eof)
+	defer close(conn.msgs)
+	for ev := range conn.events {
+		switch ev := ev.(type) {
+		case OpenEvent:
+			select {
+			case <-conn.opened:
+			default:
+				close(conn.opened)
+			}
+		case MessageEvent:
+			select {
+			case conn.msgs <- ev.Data:
+			case <-conn.closed:
+				return
+			}
+		}
+	}
+}
+
+// Read ...
+func (conn *dataChannelConn) Read(b []byte) (int, error) {
+	conn.rmu.Lock()
+	defer conn.rmu.Unlock()
+	for len(conn.rbuf) == 0 {
+		select {
+		case <-conn.closed:
+			return 0, net.ErrClosed
+		default:
+		}
+		select {
+		case m, ok := <-conn.msgs:
+			if !ok {
+				return 0, io.EOF
+			}
+			conn.rbuf = m
+		case <-conn.closed:
+			return 0, net.ErrClosed
+		case <-conn.readDeadline.wait():
+			return 0, os.ErrDeadlineExceeded
+		}
+	}
+	n := copy(b, conn.rbuf)
+	conn.rbuf = conn.rbuf[n:]
+	return n, nil
+}
+
+// Write ...
+func (conn *dataChannelConn) Write(b []byte) (int, error) {
+	conn.wmu.Lock()
+	defer conn.wmu.Unlock()
+	select {
+	case <-conn.opened:
+	case <-conn.closed:
+		return 0, net.ErrClosed
+	case <-conn.eof:
+		return 0, io.ErrClosedPipe
+	case <-conn.writeDeadline.wait():
+		return 0, os.ErrDeadlineExceeded
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-conn.closed:
+		case <-conn.eof:
+		case <-conn.writeDeadline.wait():
+		case <-ctx.Done():
+		}
+		cancel()
+	}()
+	n := 0
+	for len(b) > 0 {
+		chunk := b
+		if len(chunk) > maxWriteChunk {
+			chunk = chunk[:maxWriteChunk]
+		}
+		if err := conn.c.SendContext(ctx, chunk); err != nil {
+			return n, conn.writeError(err)
+		}
+		n += len(chunk)
+		b = b[len(chunk):]
+	}
+	return n, nil
+}
+
+func (conn *dataChannelConn) writeError(err error) error {
+	select {
+	case <-conn.closed:
+		return net.ErrClosed
+	default:
+	}
+	select {
+	case <-conn.eof:
+		return io.ErrClosedPipe
+	default:
+	}
+	if err == context.Canceled {
+		return os.ErrDeadlineExceeded
+	}
+	if err == ErrDataChannelNotOpen {
+		return io.ErrClosedPipe
+	}
+	return err
+}
+
+// Close ...
+func (conn *dataChannelConn) Close() error {
+	err := net.ErrClosed
+	conn.once.Do(func() {
+		close(conn.closed)
+		conn.c.Unsubscribe(conn.events)
+		err = conn.c.Close()
+	})
+	return err
+}
+
+// LocalAddr ...
+func (conn *dataChannelConn) LocalAddr() net.Addr {
+	var local *IceCandidate
+	if conn.c.pc != nil {
+		local, _ = conn.c.pc.candidatePair()
+	}
+	return newAddr(conn.c.Label(), local)
+}
+
+// RemoteAddr ...
+func (conn *dataChannelConn) RemoteAddr() net.Addr {
+	var remote *IceCandidate
+	if conn.c.pc != nil {
+		_, remote = conn.c.pc.candidatePair()
+	}
+	return newAddr(conn.c.Label(), remote)
+}
+
+// SetDeadline ...
+func (conn *dataChannelConn) SetDeadline(t time.Time) error {
+	conn.readDeadline.set(t)
+	conn.writeDeadline.set(t)
+	return nil
+}
+
+// SetReadDeadline ...
+func (conn *dataChannelConn) SetReadDeadline(t time.Time) error {
+	conn.readDeadline.set(t)
+	return nil
+}
+
+// SetWriteDeadline ...
+func (conn *dataChannelConn) SetWriteDeadline(t time.Time) error {
+	conn.writeDeadline.set(t)
+	return nil
+}
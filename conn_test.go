@@ -0,0 +1,109 @@
+// +build mock
+
+package webrtc
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConnEarlyMessages(t *testing.T) {
+	offerer, answerer, err := NewPair(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer offerer.Close()
+	defer answerer.Close()
+	events := answerer.Events()
+	dc, err := offerer.CreateDataChannel("early")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := dc.Conn()
+	if _, err := w.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	var rc *DataChannel
+	for rc == nil {
+		ev, ok := next(t, events)
+		if !ok {
+			t.Fatal("events closed")
+		}
+		if ev, ok := ev.(DataChannelEvent); ok && ev.Channel.Label() == "early" {
+			rc = ev.Channel
+		}
+	}
+	// the message has arrived before anyone reads the channel.
+	time.Sleep(50 * time.Millisecond)
+	r := rc.Conn()
+	r.SetReadDeadline(time.Now().Add(time.Second))
+	b := make([]byte, 5)
+	if _, err := io.ReadFull(r, b); err != nil || string(b) != "hello" {
+		t.Fatalf("read %q, %v", b, err)
+	}
+	if got := r.RemoteAddr().(*Addr).Label; got != "early" {
+		t.Fatalf("remote addr label %q", got)
+	}
+	w.Close()
+	if _, err := r.Read(b); err != io.EOF {
+		t.Fatalf("read after close: %v", err)
+	}
+}
+
+func TestConnConcurrentWrite(t *testing.T) {
+	offerer, answerer, err := NewPair(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer offerer.Close()
+	defer answerer.Close()
+	accepted := make(chan *DataChannel, 1)
+	answerer.OnDataChannel(func(c *DataChannel) {
+		if c.Label() == "writers" {
+			accepted <- c
+		}
+	})
+	dc, err := offerer.CreateDataChannel("writers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := dc.Conn()
+	var rc *DataChannel
+	select {
+	case rc = <-accepted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("channel not announced")
+	}
+	r := rc.Conn()
+
+	// each writer sends many chunks of its own byte, more than the channel
+	// buffers, so that the writers wait for each other.
+	const writers, size = 4, maxBufferedAmount/2 + 1
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(v byte) {
+			defer wg.Done()
+			if _, err := w.Write(bytes.Repeat([]byte{v}, size)); err != nil {
+				t.Error(err)
+			}
+		}(byte('a' + i))
+	}
+	r.SetReadDeadline(time.Now().Add(10 * time.Second))
+	got := make([]byte, writers*size)
+	if _, err := io.ReadFull(r, got); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+	seen := map[byte]bool{}
+	for off := 0; off < len(got); off += size {
+		v := got[off]
+		if seen[v] || !bytes.Equal(got[off:off+size], bytes.Repeat([]byte{v}, size)) {
+			t.Fatalf("writes interleaved at %d", off)
+		}
+		seen[v] = true
+	}
+}
@@ -0,0 +1,75 @@
+package webrtc
+
+import (
+	"fmt"
+)
+
+// DataChannelInit holds the RTCDataChannelInit members. Nil members are
+// unset and take their WebRTC defaults, so the zero value asks for a
+// reliable and ordered channel with its ID chosen in-band.
+type DataChannelInit struct {
+	Ordered           *bool
+	MaxPacketLifeTime *int // milliseconds
+	MaxRetransmits    *int
+	Protocol          string
+	Negotiated        bool
+	ID                *int
+}
+
+// NewDataChannelInit returns the defaults: a reliable and ordered channel
+// with its ID chosen in-band.
+func NewDataChannelInit() *DataChannelInit {
+	return &DataChannelInit{}
+}
+
+// dataChannelParams is a DataChannelInit with the defaults filled in and -1
+// for the unset numbers.
+type dataChannelParams struct {
+	ordered     bool
+	lifetime    int
+	retransmits int
+	protocol    string
+	negotiated  bool
+	id          int
+}
+
+// dataChannelOptions resolves the optional argument of CreateDataChannel.
+func dataChannelOptions(opts []*DataChannelInit) (dataChannelParams, error) {
+	p := dataChannelParams{ordered: true, lifetime: -1, retransmits: -1, id: -1}
+	if len(opts) > 1 {
+		return p, fmt.Errorf("create data channel: %d DataChannelInit given, want at most one", len(opts))
+	}
+	if len(opts) == 0 || opts[0] == nil {
+		return p, nil
+	}
+	opt := opts[0]
+	if opt.Ordered != nil {
+		p.ordered = *opt.Ordered
+	}
+	for _, v := range []struct {
+		name string
+		src  *int
+		dst  *int
+	}{
+		{"maxPacketLifeTime", opt.MaxPacketLifeTime, &p.lifetime},
+		{"maxRetransmits", opt.MaxRetransmits, &p.retransmits},
+		{"id", opt.ID, &p.id},
+	} {
+		if v.src == nil {
+			continue
+		}
+		if *v.src < 0 || *v.src > 65535 {
+			return p, fmt.Errorf("create data channel: %s %d out of range", v.name, *v.src)
+		}
+		*v.dst = *v.src
+	}
+	if p.lifetime >= 0 && p.retransmits >= 0 {
+		return p, fmt.Errorf("create data channel: both maxPacketLifeTime and maxRetransmits set")
+	}
+	p.protocol = opt.Protocol
+	p.negotiated = opt.Negotiated
+	if p.negotiated && p.id < 0 {
+		return p, fmt.Errorf("create data channel: negotiated channel needs an id")
+	}
+	return p, nil
+}
@@ -0,0 +1,47 @@
+package webrtc
+
+import (
+	"testing"
+)
+
+func TestDataChannelOptions(t *testing.T) {
+	zero, no := 0, false
+	defaults := dataChannelParams{ordered: true, lifetime: -1, retransmits: -1, id: -1}
+	for _, tc := range []struct {
+		name string
+		opts []*DataChannelInit
+		want dataChannelParams
+	}{
+		{"none", nil, defaults},
+		{"nil", []*DataChannelInit{nil}, defaults},
+		{"zero value", []*DataChannelInit{{}}, defaults},
+		{"new", []*DataChannelInit{NewDataChannelInit()}, defaults},
+		{"protocol only", []*DataChannelInit{{Protocol: "x"}},
+			dataChannelParams{ordered: true, lifetime: -1, retransmits: -1, protocol: "x", id: -1}},
+		{"unreliable", []*DataChannelInit{{Ordered: &no, MaxRetransmits: &zero}},
+			dataChannelParams{lifetime: -1, retransmits: 0, id: -1}},
+		{"negotiated", []*DataChannelInit{{Negotiated: true, ID: &zero}},
+			dataChannelParams{ordered: true, lifetime: -1, retransmits: -1, negotiated: true, id: 0}},
+	} {
+		got, err := dataChannelOptions(tc.opts)
+		if err != nil || got != tc.want {
+			t.Errorf("%s: got %+v, %v, want %+v", tc.name, got, err, tc.want)
+		}
+	}
+
+	big, neg := 65536, -1
+	for _, tc := range []struct {
+		name string
+		opts []*DataChannelInit
+	}{
+		{"two", []*DataChannelInit{{}, {}}},
+		{"negotiated without id", []*DataChannelInit{{Negotiated: true}}},
+		{"id out of range", []*DataChannelInit{{ID: &big}}},
+		{"negative retransmits", []*DataChannelInit{{MaxRetransmits: &neg}}},
+		{"both limits", []*DataChannelInit{{MaxRetransmits: &zero, MaxPacketLifeTime: &zero}}},
+	} {
+		if _, err := dataChannelOptions(tc.opts); err == nil {
+			t.Errorf("%s: no error", tc.name)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package webrtc
+
+import (
+	"github.com/nobonobo/webrtc/sdp"
+)
+
+// Session parses Sdp.
+func (sd *SessionDescription) Session() (*sdp.Session, error) {
+	return sdp.ParseString(sd.Sdp)
+}
+
+// SetSession replaces Sdp with s.
+func (sd *SessionDescription) SetSession(s *sdp.Session) {
+	sd.Sdp = s.String()
+}
+
+// Edit parses Sdp, calls f and stores the edited session back.
+// Sdp is left untouched if parsing or f fails.
+func (sd *SessionDescription) Edit(f func(s *sdp.Session) error) error {
+	s, err := sd.Session()
+	if err != nil {
+		return err
+	}
+	if err := f(s); err != nil {
+		return err
+	}
+	sd.SetSession(s)
+	return nil
+}
@@ -0,0 +1,13 @@
+// Package webrtc is one WebRTC API over several implementations, chosen by
+// build tags:
+//
+//	native (default)  github.com/keroserene/go-webrtc, DataChannels only
+//	-tags pion        github.com/pion/webrtc, pure Go
+//	GOOS=js           the browser, through GopherJS or WebAssembly
+//	-tags mock        in-memory peers for tests
+//
+// Outside tests, media needs the pion tag or a browser build. go-webrtc binds
+// DataChannels only, so on the native backend the stream, track and
+// transceiver functions return ErrMediaNotSupported and the media callbacks
+// are never called.
+package webrtc
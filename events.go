@@ -0,0 +1,322 @@
+package webrtc
+
+import (
+	"sync"
+)
+
+// Event is delivered by PeerConnection.Events and DataChannel.Events.
+// Type returns the name of the corresponding DOM event.
+type Event interface {
+	Type() string
+}
+
+// NegotiationNeededEvent ...
+type NegotiationNeededEvent struct{}
+
+// Type ...
+func (NegotiationNeededEvent) Type() string { return "negotiationneeded" }
+
+// IceCandidateEvent ...
+type IceCandidateEvent struct {
+	Candidate *IceCandidate
+}
+
+// Type ...
+func (IceCandidateEvent) Type() string { return "icecandidate" }
+
+// IceCandidateErrorEvent ...
+type IceCandidateErrorEvent struct{}
+
+// Type ...
+func (IceCandidateErrorEvent) Type() string { return "icecandidateerror" }
+
+// SignalingStateChangeEvent ...
+type SignalingStateChangeEvent struct {
+	State SignalingState
+}
+
+// Type ...
+func (SignalingStateChangeEvent) Type() string { return "signalingstatechange" }
+
+// IceConnectionStateChangeEvent ...
+type IceConnectionStateChangeEvent struct {
+	State IceConnectionState
+}
+
+// Type ...
+func (IceConnectionStateChangeEvent) Type() string { return "iceconnectionstatechange" }
+
+// IceGatheringStateChangeEvent ...
+type IceGatheringStateChangeEvent struct {
+	State IceGatheringState
+}
+
+// Type ...
+func (IceGatheringStateChangeEvent) Type() string { return "icegatheringstatechange" }
+
+// ConnectionStateChangeEvent ...
+type ConnectionStateChangeEvent struct {
+	State PeerConnectionState
+}
+
+// Type ...
+func (ConnectionStateChangeEvent) Type() string { return "connectionstatechange" }
+
+// DataChannelEvent ...
+type DataChannelEvent struct {
+	Channel *DataChannel
+}
+
+// Type ...
+func (DataChannelEvent) Type() string { return "datachannel" }
+
+// AddStreamEvent ...
+type AddStreamEvent struct {
+	Stream *MediaStream
+}
+
+// Type ...
+func (AddStreamEvent) Type() string { return "addstream" }
+
+// RemoveStreamEvent ...
+type RemoveStreamEvent struct {
+	Stream *MediaStream
+}
+
+// Type ...
+func (RemoveStreamEvent) Type() string { return "removestream" }
+
+// TrackEvent ...
+type TrackEvent struct {
+	Track   *MediaStreamTrack
+	Streams []*MediaStream
+}
+
+// Type ...
+func (TrackEvent) Type() string { return "track" }
+
+// OpenEvent ...
+type OpenEvent struct{}
+
+// Type ...
+func (OpenEvent) Type() string { return "open" }
+
+// CloseEvent ...
+type CloseEvent struct{}
+
+// Type ...
+func (CloseEvent) Type() string { return "close" }
+
+// MessageEvent ...
+type MessageEvent struct {
+	Data     []byte
+	IsString bool
+}
+
+// Type ...
+func (MessageEvent) Type() string { return "message" }
+
+// subscriber queues events without bound so that publishing never blocks
+// the (possibly JS or cgo) callback that produced them.
+type subscriber struct {
+	mu     sync.Mutex
+	queue  []Event
+	closed bool
+	notify chan struct{}
+	ch     chan Event
+}
+
+func (s *subscriber) wake() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *subscriber) push(ev Event) {
+	s.mu.Lock()
+	if !s.closed {
+		s.queue = append(s.queue, ev)
+	}
+	s.mu.Unlock()
+	s.wake()
+}
+
+func (s *subscriber) close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.wake()
+}
+
+// run delivers queued events, then closes ch once closed and drained.
+func (s *subscriber) run() {
+	defer close(s.ch)
+	for {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return
+			}
+			<-s.notify
+			continue
+		}
+		ev := s.queue[0]
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+		s.ch <- ev
+	}
+}
+
+// broker fans events out to subscribers. While holding, events are also
+// kept in backlog and handed to the next subscriber, so that a DataChannel
+// does not lose the messages that arrive before anyone subscribes.
+type broker struct {
+	mu      sync.Mutex
+	subs    map[<-chan Event]*subscriber
+	closed  bool
+	holding bool
+	backlog []Event
+}
+
+// hold starts keeping events for the next subscriber.
+func (b *broker) hold() {
+	b.mu.Lock()
+	b.holding = true
+	b.mu.Unlock()
+}
+
+// release stops holding and drops the events kept so far.
+func (b *broker) release() {
+	b.mu.Lock()
+	b.holding = false
+	b.backlog = nil
+	b.mu.Unlock()
+}
+
+func (b *broker) subscribe() <-chan Event {
+	s := &subscriber{
+		notify: make(chan struct{}, 1),
+		ch:     make(chan Event),
+	}
+	b.mu.Lock()
+	s.queue, b.backlog, b.holding = b.backlog, nil, false
+	if b.closed {
+		s.closed = true
+	} else {
+		if b.subs == nil {
+			b.subs = map[<-chan Event]*subscriber{}
+		}
+		b.subs[s.ch] = s
+	}
+	b.mu.Unlock()
+	go s.run()
+	return s.ch
+}
+
+func (b *broker) unsubscribe(ch <-chan Event) {
+	b.mu.Lock()
+	s, ok := b.subs[ch]
+	delete(b.subs, ch)
+	b.mu.Unlock()
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	s.queue = nil
+	s.mu.Unlock()
+	s.close()
+	// drain so run can return even if nobody reads ch anymore.
+	go func() {
+		for range ch {
+		}
+	}()
+}
+
+func (b *broker) publish(ev Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.holding {
+		b.backlog = append(b.backlog, ev)
+	}
+	for _, s := range b.subs {
+		s.push(ev)
+	}
+}
+
+// close ends every subscription after its pending events are delivered.
+func (b *broker) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for ch, s := range b.subs {
+		s.close()
+		delete(b.subs, ch)
+	}
+}
+
+// Events subscribes to the events of the PeerConnection. The channel is
+// closed by Unsubscribe or when the PeerConnection is closed. Only events
+// fired after Events returns are delivered; events fired while nobody is
+// subscribed are dropped, so subscribe before the call that causes them.
+func (pc *PeerConnection) Events() <-chan Event {
+	return pc.events.subscribe()
+}
+
+// Unsubscribe ends a subscription returned by Events.
+func (pc *PeerConnection) Unsubscribe(ch <-chan Event) {
+	pc.events.unsubscribe(ch)
+}
+
+// Events subscribes to the events of the DataChannel. The channel is closed
+// by Unsubscribe or after the CloseEvent. The first subscription also
+// receives the events fired since the channel was created, unless OnMessage
+// or OnMessageData was set before; later subscriptions only see new events.
+func (c *DataChannel) Events() <-chan Event {
+	return c.events.subscribe()
+}
+
+// Unsubscribe ends a subscription returned by Events.
+func (c *DataChannel) Unsubscribe(ch <-chan Event) {
+	c.events.unsubscribe(ch)
+}
+
+// dispatcher runs functions one at a time in the order they were posted,
+// like the event loop of a browser, without blocking the poster.
+type dispatcher struct {
+	mu      sync.Mutex
+	queue   []func()
+	running bool
+}
+
+func (d *dispatcher) post(f func()) {
+	d.mu.Lock()
+	d.queue = append(d.queue, f)
+	if d.running {
+		d.mu.Unlock()
+		return
+	}
+	d.running = true
+	d.mu.Unlock()
+	go d.run()
+}
+
+func (d *dispatcher) run() {
+	for {
+		d.mu.Lock()
+		if len(d.queue) == 0 {
+			d.running = false
+			d.mu.Unlock()
+			return
+		}
+		f := d.queue[0]
+		d.queue = d.queue[1:]
+		d.mu.Unlock()
+		f()
+	}
+}
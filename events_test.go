@@ -0,0 +1,97 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+)
+
+func next(t *testing.T, ch <-chan Event) (Event, bool) {
+	t.Helper()
+	select {
+	case ev, ok := <-ch:
+		return ev, ok
+	case <-time.After(time.Second):
+		t.Fatal("no event")
+		return nil, false
+	}
+}
+
+func TestBroker(t *testing.T) {
+	var b broker
+	b.publish(OpenEvent{}) // nobody subscribed: dropped.
+	a := b.subscribe()
+	c := b.subscribe()
+	for i := 0; i < 100; i++ {
+		b.publish(MessageEvent{Data: []byte{byte(i)}})
+	}
+	for _, ch := range []<-chan Event{a, c} {
+		for i := 0; i < 100; i++ {
+			ev, ok := next(t, ch)
+			if m, _ := ev.(MessageEvent); !ok || m.Data[0] != byte(i) {
+				t.Fatalf("event %d: %#v", i, ev)
+			}
+		}
+	}
+	b.unsubscribe(a)
+	if _, ok := next(t, a); ok {
+		t.Fatal("unsubscribed channel not closed")
+	}
+	b.publish(CloseEvent{})
+	b.close()
+	if ev, ok := next(t, c); !ok || ev.Type() != "close" {
+		t.Fatalf("pending event lost on close: %v", ev)
+	}
+	if _, ok := next(t, c); ok {
+		t.Fatal("channel not closed")
+	}
+	if _, ok := next(t, b.subscribe()); ok {
+		t.Fatal("subscription after close not closed")
+	}
+}
+
+func TestBrokerBacklog(t *testing.T) {
+	var b broker
+	b.hold()
+	b.publish(OpenEvent{})
+	b.publish(MessageEvent{Data: []byte("early")})
+	b.publish(CloseEvent{})
+	b.close()
+	ch := b.subscribe()
+	for _, typ := range []string{"open", "message", "close"} {
+		if ev, ok := next(t, ch); !ok || ev.Type() != typ {
+			t.Fatalf("got %v, want %s", ev, typ)
+		}
+	}
+	if _, ok := next(t, ch); ok {
+		t.Fatal("channel not closed")
+	}
+	// only the first subscription gets the backlog.
+	if _, ok := next(t, b.subscribe()); ok {
+		t.Fatal("backlog delivered twice")
+	}
+
+	var r broker
+	r.hold()
+	r.publish(MessageEvent{})
+	r.release()
+	r.publish(OpenEvent{})
+	ch = r.subscribe()
+	r.publish(CloseEvent{})
+	if ev, _ := next(t, ch); ev.Type() != "close" {
+		t.Fatalf("released backlog delivered: %v", ev)
+	}
+}
+
+func TestDispatcherOrder(t *testing.T) {
+	var d dispatcher
+	got := make(chan int, 100)
+	for i := 0; i < 100; i++ {
+		i := i
+		d.post(func() { got <- i })
+	}
+	for i := 0; i < 100; i++ {
+		if v := <-got; v != i {
+			t.Fatalf("ran %d, want %d", v, i)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package webrtc
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrMessageTooLarge ...
+var ErrMessageTooLarge = errors.New("message too large")
+
+// ErrFramedChannelClosed ...
+var ErrFramedChannelClosed = errors.New("framed channel closed")
+
+const (
+	// DefaultFrameLimit is the message size limit of Framed(0).
+	DefaultFrameLimit = 16 << 20
+
+	// defaultMaxMessageSize applies when the remote SDP has no
+	// a=max-message-size (RFC 8841).
+	defaultMaxMessageSize = 65536
+
+	// maxFrameSize caps fragments even when the peer accepts more, so that
+	// one large message does not hold up the others for long.
+	maxFrameSize = 64 * 1024
+
+	// maxPartialMessages bounds reassembly state kept for messages whose
+	// fragments were lost on an unreliable channel.
+	maxPartialMessages = 64
+)
+
+// frame header: message id, fragment index, fragment count, flags.
+const (
+	frameHeaderSize = 13
+	frameFlagString = 1
+)
+
+// maxMessageSize returns the a=max-message-size of the remote data section,
+// 0 meaning unlimited.
+func maxMessageSize(sd *SessionDescription) int {
+	if sd == nil {
+		return defaultMaxMessageSize
+	}
+	s, err := sd.Session()
+	if err != nil {
+		return defaultMaxMessageSize
+	}
+	for _, m := range s.Media {
+		if m.Kind() != "application" {
+			continue
+		}
+		v, ok := m.Attribute("max-message-size")
+		if !ok {
+			break
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			break
+		}
+		return n
+	}
+	return defaultMaxMessageSize
+}
+
+type partialMessage struct {
+	frags    map[uint32][]byte
+	count    uint32
+	size     int
+	isString bool
+}
+
+// FramedChannel sends messages of any size up to a limit over a
+// DataChannel, fragmenting them to fit the peer's max-message-size. Both
+// ends must use framed mode.
+type FramedChannel struct {
+	c      Channel
+	limit  int
+	nextID uint32
+	events <-chan Event
+	msgs   chan Message
+	closed chan struct{}
+	once   sync.Once
+
+	partial map[uint32]*partialMessage
+	order   []uint32
+}
+
+// Framed switches the DataChannel to framed mode. limit is the largest
+// message sent or accepted, DefaultFrameLimit if 0. Messages received
+// afterwards are consumed by the FramedChannel.
+func (c *DataChannel) Framed(limit int) *FramedChannel {
+	return NewFramedChannel(c, limit)
+}
+
+// NewFramedChannel is Framed for any Channel, so that fakes can implement
+// Channel.Framed. Fragments fit the peer's max-message-size only when c
+// is a *DataChannel; otherwise they are at most 64KiB.
+func NewFramedChannel(c Channel, limit int) *FramedChannel {
+	if limit <= 0 {
+		limit = DefaultFrameLimit
+	}
+	f := &FramedChannel{
+		c:       c,
+		limit:   limit,
+		events:  c.Events(),
+		msgs:    make(chan Message),
+		closed:  make(chan struct{}),
+		partial: map[uint32]*partialMessage{},
+	}
+	go f.pump()
+	return f
+}
+
+// DataChannel returns the underlying DataChannel, nil if the FramedChannel
+// was made from another Channel.
+func (f *FramedChannel) DataChannel() *DataChannel {
+	dc, _ := f.c.(*DataChannel)
+	return dc
+}
+
+// Channel returns the Channel the FramedChannel was made from.
+func (f *FramedChannel) Channel() Channel {
+	return f.c
+}
+
+// frameSize returns the largest fragment payload the peer accepts.
+func (f *FramedChannel) frameSize() int {
+	n := maxFrameSize
+	if dc, ok := f.c.(*DataChannel); ok && dc.pc != nil {
+		if peer := maxMessageSize(dc.pc.RemoteDescription()); peer > 0 && peer < n {
+			n = peer
+		}
+	}
+	if n <= frameHeaderSize {
+		n = frameHeaderSize + 1
+	}
+	return n - frameHeaderSize
+}
+
+// Send sends data as one binary message.
+func (f *FramedChannel) Send(ctx context.Context, data []byte) error {
+	return f.send(ctx, data, 0)
+}
+
+// SendText sends text as one string message.
+func (f *FramedChannel) SendText(ctx context.Context, text string) error {
+	return f.send(ctx, []byte(text), frameFlagString)
+}
+
+func (f *FramedChannel) send(ctx context.Context, data []byte, flags byte) error {
+	if len(data) > f.limit {
+		return ErrMessageTooLarge
+	}
+	size := f.frameSize()
+	count := (len(data) + size - 1) / size
+	if count == 0 {
+		count = 1
+	}
+	id := atomic.AddUint32(&f.nextID, 1)
+	for i := 0; i < count; i++ {
+		chunk := data
+		if len(chunk) > size {
+			chunk = chunk[:size]
+		}
+		data = data[len(chunk):]
+		frame := make([]byte, frameHeaderSize+len(chunk))
+		binary.BigEndian.PutUint32(frame[0:], id)
+		binary.BigEndian.PutUint32(frame[4:], uint32(i))
+		binary.BigEndian.PutUint32(frame[8:], uint32(count))
+		frame[12] = flags
+		copy(frame[frameHeaderSize:], chunk)
+		if err := f.c.SendContext(ctx, frame); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Recv returns the next reassembled message.
+func (f *FramedChannel) Recv(ctx context.Context) (Message, error) {
+	select {
+	case m, ok := <-f.msgs:
+		if !ok {
+			return Message{}, ErrFramedChannelClosed
+		}
+		return m, nil
+	case <-f.closed:
+		return Message{}, ErrFramedChannelClosed
+	case <-ctx.Done():
+		return Message{}, ctx.Err()
+	}
+}
+
+// Close leaves framed mode. The DataChannel stays open.
+func (f *FramedChannel) Close() error {
+	f.once.Do(func() {
+		close(f.closed)
+		f.c.Unsubscribe(f.events)
+	})
+	return nil
+}
+
+func (f *FramedChannel) pump() {
+	defer close(f.msgs)
+	for ev := range f.events {
+		ev, ok := ev.(MessageEvent)
+		if !ok {
+			continue
+		}
+		m, ok := f.reassemble(ev.Data)
+		if !ok {
+			continue
+		}
+		select {
+		case f.msgs <- m:
+		case <-f.closed:
+			return
+		}
+	}
+}
+
+// reassemble adds one frame and returns the message it completes. Malformed
+// frames and messages over the limit are dropped.
+func (f *FramedChannel) reassemble(frame []byte) (Message, bool) {
+	if len(frame) < frameHeaderSize {
+		return Message{}, false
+	}
+	id := binary.BigEndian.Uint32(frame[0:])
+	index := binary.BigEndian.Uint32(frame[4:])
+	count := binary.BigEndian.Uint32(frame[8:])
+	isString := frame[12]&frameFlagString != 0
+	payload := frame[frameHeaderSize:]
+	if count == 0 || index >= count || uint64(count) > uint64(f.limit)+1 {
+		return Message{}, false
+	}
+	if count == 1 {
+		if len(payload) > f.limit {
+			return Message{}, false
+		}
+		return Message{Data: payload, IsString: isString}, true
+	}
+	p, ok := f.partial[id]
+	if !ok {
+		if len(f.order) >= maxPartialMessages {
+			delete(f.partial, f.order[0])
+			f.order = f.order[1:]
+		}
+		p = &partialMessage{
+			frags:    map[uint32][]byte{},
+			count:    count,
+			isString: isString,
+		}
+		f.partial[id] = p
+		f.order = append(f.order, id)
+	}
+	if _, dup := p.frags[index]; dup || count != p.count {
+		return Message{}, false
+	}
+	p.size += len(payload)
+	if p.size > f.limit {
+		f.forget(id)
+		return Message{}, false
+	}
+	p.frags[index] = payload
+	if uint32(len(p.frags)) < p.count {
+		return Message{}, false
+	}
+	f.forget(id)
+	data := make([]byte, 0, p.size)
+	for i := uint32(0); i < p.count; i++ {
+		data = append(data, p.frags[i]...)
+	}
+	return Message{Data: data, IsString: p.isString}, true
+}
+
+func (f *FramedChannel) forget(id uint32) {
+	delete(f.partial, id)
+	for i, v := range f.order {
+		if v == id {
+			f.order = append(f.order[:i], f.order[i+1:]...)
+			break
+		}
+	}
+}
@@ -0,0 +1,271 @@
+// +build mock
+
+package webrtc
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMaxMessageSize(t *testing.T) {
+	data := "v=0\r\nm=application 9 UDP/DTLS/SCTP webrtc-datachannel\r\na=mid:0\r\n"
+	for _, tc := range []struct {
+		name string
+		sd   *SessionDescription
+		want int
+	}{
+		{"no description", nil, defaultMaxMessageSize},
+		{"no data section", NewSessionDescription("answer", "v=0\r\nm=audio 9 UDP/TLS/RTP/SAVPF 111\r\n"), defaultMaxMessageSize},
+		{"no attribute", NewSessionDescription("answer", data), defaultMaxMessageSize},
+		{"set", NewSessionDescription("answer", data+"a=max-message-size:1200\r\n"), 1200},
+		{"unlimited", NewSessionDescription("answer", data+"a=max-message-size:0\r\n"), 0},
+		{"malformed", NewSessionDescription("answer", data+"a=max-message-size:big\r\n"), defaultMaxMessageSize},
+	} {
+		if got := maxMessageSize(tc.sd); got != tc.want {
+			t.Errorf("%s: got %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
+
+var maxMessageSizeLine = regexp.MustCompile(`a=max-message-size:\d+`)
+
+// setMaxMessageSize rewrites the a=max-message-size pc got from its peer.
+func setMaxMessageSize(pc *PeerConnection, n int) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	sdp := maxMessageSizeLine.ReplaceAllString(pc.remote.Sdp, "a=max-message-size:"+strconv.Itoa(n))
+	pc.remote = NewSessionDescription(pc.remote.Type, sdp)
+}
+
+func TestFragmentSize(t *testing.T) {
+	offerer, answerer, err := NewPair(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer offerer.Close()
+	defer answerer.Close()
+	events := answerer.Events()
+	dc, err := offerer.CreateDataChannel("frames")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opened := dc.Events()
+	for {
+		ev, ok := next(t, opened)
+		if !ok {
+			t.Fatal("events closed")
+		}
+		if _, ok := ev.(OpenEvent); ok {
+			break
+		}
+	}
+	dc.Unsubscribe(opened)
+	var rc *DataChannel
+	for rc == nil {
+		ev, ok := next(t, events)
+		if !ok {
+			t.Fatal("events closed")
+		}
+		if ev, ok := ev.(DataChannelEvent); ok && ev.Channel.Label() == "frames" {
+			rc = ev.Channel
+		}
+	}
+	raw := rc.Events()
+	defer rc.Unsubscribe(raw)
+	f := dc.Framed(0)
+	defer f.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// largest returns the size of the biggest of the frames of one message.
+	largest := func(count int) int {
+		t.Helper()
+		n := 0
+		for i := 0; i < count; i++ {
+			ev, ok := next(t, raw)
+			if !ok {
+				t.Fatal("events closed")
+			}
+			m, ok := ev.(MessageEvent)
+			if !ok {
+				i--
+				continue
+			}
+			if len(m.Data) > n {
+				n = len(m.Data)
+			}
+		}
+		return n
+	}
+
+	// the peer accepts 256KiB, but fragments stay at 64KiB.
+	if err := f.Send(ctx, make([]byte, 200<<10)); err != nil {
+		t.Fatal(err)
+	}
+	if n := largest(4); n != maxFrameSize {
+		t.Fatalf("largest frame %d, want %d", n, maxFrameSize)
+	}
+
+	setMaxMessageSize(offerer, 1200)
+	if err := f.Send(ctx, make([]byte, 10000)); err != nil {
+		t.Fatal(err)
+	}
+	if n := largest((10000 + 1200 - frameHeaderSize - 1) / (1200 - frameHeaderSize)); n != 1200 {
+		t.Fatalf("largest frame %d, want 1200", n)
+	}
+
+	// a limit smaller than the header still makes progress.
+	setMaxMessageSize(offerer, 5)
+	if got := f.frameSize(); got != 1 {
+		t.Fatalf("frame size %d, want 1", got)
+	}
+}
+
+// fakeFramedPair connects two FramedChannels over fakeChannels.
+func fakeFramedPair(t *testing.T, sendLimit, recvLimit int) (*FramedChannel, *FramedChannel) {
+	t.Helper()
+	a, b := &fakeChannel{}, &fakeChannel{}
+	a.peer, b.peer = b, a
+	fa, fb := NewFramedChannel(a, sendLimit), NewFramedChannel(b, recvLimit)
+	t.Cleanup(func() {
+		fa.Close()
+		fb.Close()
+	})
+	return fa, fb
+}
+
+func TestFramedTooLarge(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	fa, _ := fakeFramedPair(t, 1000, 0)
+	if err := fa.Send(ctx, make([]byte, 1001)); err != ErrMessageTooLarge {
+		t.Fatalf("Send: got %v, want %v", err, ErrMessageTooLarge)
+	}
+	if err := fa.SendText(ctx, strings.Repeat("x", 1001)); err != ErrMessageTooLarge {
+		t.Fatalf("SendText: got %v, want %v", err, ErrMessageTooLarge)
+	}
+
+	// the receiver drops messages over its own limit, in one frame or many,
+	// and goes on with the next one.
+	fa, fb := fakeFramedPair(t, 0, 100<<10)
+	for _, n := range []int{(100 << 10) + 1, 300 << 10} {
+		if err := fa.Send(ctx, make([]byte, n)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fa.SendText(ctx, "small"); err != nil {
+		t.Fatal(err)
+	}
+	m, err := fb.Recv(ctx)
+	if err != nil || string(m.Data) != "small" || !m.IsString {
+		t.Fatalf("received %d bytes (string %v), %v", len(m.Data), m.IsString, err)
+	}
+}
+
+// frameOf builds fragment index of count of message id.
+func frameOf(id, index, count uint32, payload string) []byte {
+	b := make([]byte, frameHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(b[0:], id)
+	binary.BigEndian.PutUint32(b[4:], index)
+	binary.BigEndian.PutUint32(b[8:], count)
+	copy(b[frameHeaderSize:], payload)
+	return b
+}
+
+func TestReassemble(t *testing.T) {
+	f := &FramedChannel{limit: DefaultFrameLimit, partial: map[uint32]*partialMessage{}}
+
+	// fragments may arrive out of order; a duplicate is ignored.
+	for _, fr := range [][]byte{frameOf(1, 2, 3, "c"), frameOf(1, 0, 3, "a"), frameOf(1, 0, 3, "x")} {
+		if m, ok := f.reassemble(fr); ok {
+			t.Fatalf("completed early: %q", m.Data)
+		}
+	}
+	m, ok := f.reassemble(frameOf(1, 1, 3, "b"))
+	if !ok || string(m.Data) != "abc" {
+		t.Fatalf("got %q, %v", m.Data, ok)
+	}
+	if len(f.partial) != 0 || len(f.order) != 0 {
+		t.Fatalf("%d partial messages left", len(f.partial))
+	}
+
+	// a fragment that disagrees on the count is dropped.
+	f.reassemble(frameOf(2, 0, 2, "a"))
+	if _, ok := f.reassemble(frameOf(2, 1, 3, "b")); ok {
+		t.Fatal("completed with a mismatched count")
+	}
+	if m, ok := f.reassemble(frameOf(2, 1, 2, "b")); !ok || string(m.Data) != "ab" {
+		t.Fatalf("got %q, %v", m.Data, ok)
+	}
+
+	// messages that lost a fragment are forgotten, oldest first.
+	for id := uint32(100); id < 100+maxPartialMessages+10; id++ {
+		f.reassemble(frameOf(id, 0, 2, "a"))
+	}
+	if len(f.partial) != maxPartialMessages || len(f.order) != maxPartialMessages {
+		t.Fatalf("%d partial messages kept, want %d", len(f.partial), maxPartialMessages)
+	}
+	if _, ok := f.partial[100]; ok {
+		t.Fatal("oldest partial message kept")
+	}
+	if _, ok := f.reassemble(frameOf(100, 1, 2, "b")); ok {
+		t.Fatal("forgotten message completed")
+	}
+
+	// malformed frames are dropped.
+	for _, fr := range [][]byte{
+		make([]byte, frameHeaderSize-1),
+		frameOf(3, 0, 0, "a"),
+		frameOf(3, 2, 2, "a"),
+	} {
+		if _, ok := f.reassemble(fr); ok {
+			t.Fatalf("malformed frame %x accepted", fr)
+		}
+	}
+}
+
+func TestFramedInterleaved(t *testing.T) {
+	fa, fb := fakeFramedPair(t, 0, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	const senders, messages = 4, 5
+	var wg sync.WaitGroup
+	for s := 0; s < senders; s++ {
+		wg.Add(1)
+		go func(s int) {
+			defer wg.Done()
+			for i := 0; i < messages; i++ {
+				msg := bytes.Repeat([]byte{byte(s*messages + i)}, 3*maxFrameSize)
+				if err := fa.Send(ctx, msg); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(s)
+	}
+	seen := map[byte]bool{}
+	for len(seen) < senders*messages {
+		m, err := fb.Recv(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(m.Data) != 3*maxFrameSize {
+			t.Fatalf("message of %d bytes", len(m.Data))
+		}
+		if bytes.Count(m.Data, m.Data[:1]) != len(m.Data) {
+			t.Fatalf("message %d mixed with another", m.Data[0])
+		}
+		if seen[m.Data[0]] {
+			t.Fatalf("message %d received twice", m.Data[0])
+		}
+		seen[m.Data[0]] = true
+	}
+	wg.Wait()
+}
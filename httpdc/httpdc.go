@@ -0,0 +1,125 @@
+// Package httpdc carries HTTP/1.1 between peers, one DataChannel per
+// connection.
+//
+// NewTransport gives an http.Client access to the handler a peer serves
+// with Serve. Connections are the net.Conn of the DataChannel, so request
+// and response bodies stream with the backpressure of the channel.
+//
+//	// serving peer
+//	go httpdc.Serve(pc, http.FileServer(http.Dir(".")))
+//
+//	// calling peer; the host part of the URL is ignored
+//	client := &http.Client{Transport: httpdc.NewTransport(pc)}
+//	resp, err := client.Get("http://peer/index.html")
+package httpdc
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/nobonobo/webrtc"
+)
+
+// Protocol is the DataChannel subprotocol of HTTP connections.
+const Protocol = "http/1.1"
+
+// Label is the label of the DataChannels opened by NewTransport.
+const Label = "http"
+
+// NewTransport returns an http.RoundTripper sending every request to the
+// peer of pc, whatever the host of the URL. Idle connections are kept for
+// reuse like with any http.Transport.
+func NewTransport(pc *webrtc.PeerConnection) http.RoundTripper {
+	return &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return Dial(ctx, pc)
+		},
+	}
+}
+
+// Dial opens an HTTP DataChannel to the peer and waits until it is open.
+func Dial(ctx context.Context, pc *webrtc.PeerConnection) (net.Conn, error) {
+	opt := webrtc.NewDataChannelInit()
+	opt.Protocol = Protocol
+	dc, err := pc.CreateDataChannel(Label, opt)
+	if err != nil {
+		return nil, err
+	}
+	events := dc.Events()
+	defer dc.Unsubscribe(events)
+	for dc.ReadyState() != "open" {
+		select {
+		case ev, ok := <-events:
+			if _, closed := ev.(webrtc.CloseEvent); closed || !ok {
+				return nil, net.ErrClosed
+			}
+		case <-ctx.Done():
+			dc.Close()
+			return nil, ctx.Err()
+		}
+	}
+	return dc.Conn(), nil
+}
+
+// Listener accepts the HTTP DataChannels the peer opens. Channels with
+// another subprotocol are left to other subscribers of the PeerConnection.
+type Listener struct {
+	pc     *webrtc.PeerConnection
+	events <-chan webrtc.Event
+	closed chan struct{}
+	once   sync.Once
+}
+
+var _ net.Listener = (*Listener)(nil)
+
+// Listen starts accepting HTTP DataChannels of pc.
+func Listen(pc *webrtc.PeerConnection) *Listener {
+	return &Listener{
+		pc:     pc,
+		events: pc.Events(),
+		closed: make(chan struct{}),
+	}
+}
+
+// Accept returns the next HTTP DataChannel as a net.Conn. The channel keeps
+// its messages from the moment the backend announces it until Conn
+// subscribes, so a request written right after the channel opens is not
+// lost however late Accept runs.
+func (l *Listener) Accept() (net.Conn, error) {
+	for {
+		select {
+		case ev, ok := <-l.events:
+			if !ok {
+				return nil, net.ErrClosed
+			}
+			dce, ok := ev.(webrtc.DataChannelEvent)
+			if !ok || dce.Channel.Protocol() != Protocol {
+				continue
+			}
+			return dce.Channel.Conn(), nil
+		case <-l.closed:
+			return nil, net.ErrClosed
+		}
+	}
+}
+
+// Close stops accepting. Connections already accepted stay open.
+func (l *Listener) Close() error {
+	l.once.Do(func() {
+		close(l.closed)
+		l.pc.Unsubscribe(l.events)
+	})
+	return nil
+}
+
+// Addr ...
+func (l *Listener) Addr() net.Addr {
+	return &webrtc.Addr{Label: Label}
+}
+
+// Serve serves handler to the HTTP DataChannels of pc until pc is closed.
+func Serve(pc *webrtc.PeerConnection, handler http.Handler) error {
+	return (&http.Server{Handler: handler}).Serve(Listen(pc))
+}
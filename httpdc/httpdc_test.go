@@ -0,0 +1,76 @@
+// +build mock
+
+package httpdc
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/nobonobo/webrtc/webrtctest"
+)
+
+func hello(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintf(w, "hello %s", r.URL.Path[1:])
+}
+
+func TestTransport(t *testing.T) {
+	client, server := webrtctest.Pair(t)
+	l := Listen(server)
+	defer l.Close()
+	go http.Serve(l, http.HandlerFunc(hello))
+	c := &http.Client{Transport: NewTransport(client), Timeout: 10 * time.Second}
+	for i := 0; i < 5; i++ {
+		resp, err := c.Get(fmt.Sprintf("http://peer/%d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil || string(b) != fmt.Sprintf("hello %d", i) {
+			t.Fatalf("got %q, %v", b, err)
+		}
+	}
+}
+
+func TestEarlyRequest(t *testing.T) {
+	client, server := webrtctest.Pair(t)
+	l := Listen(server)
+	defer l.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conn, err := Dial(ctx, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	if _, err := fmt.Fprint(conn, "GET /early HTTP/1.1\r\nHost: peer\r\n\r\n"); err != nil {
+		t.Fatal(err)
+	}
+	// the request has arrived before the server accepts the channel.
+	time.Sleep(100 * time.Millisecond)
+	go http.Serve(l, http.HandlerFunc(hello))
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil || string(b) != "hello early" {
+		t.Fatalf("got %q, %v", b, err)
+	}
+}
+
+func TestListenerClose(t *testing.T) {
+	_, server := webrtctest.Pair(t)
+	l := Listen(server)
+	l.Close()
+	if _, err := l.Accept(); err == nil {
+		t.Fatal("accept after close")
+	}
+}
@@ -0,0 +1,250 @@
+package webrtc
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+// Peer is the method set of every backend's *PeerConnection, with the
+// channels, tracks and transceivers as interfaces so that a fake can
+// implement it. NewPeer adapts a *PeerConnection; code that accepts a Peer
+// can also be tested against the in-memory backend selected with the
+// "mock" build tag.
+type Peer interface {
+	CreateOffer() (*SessionDescription, error)
+	CreateOfferContext(ctx context.Context) (*SessionDescription, error)
+	CreateAnswer() (*SessionDescription, error)
+	CreateAnswerContext(ctx context.Context) (*SessionDescription, error)
+	SetLocalDescription(sdp *SessionDescription) error
+	SetLocalDescriptionContext(ctx context.Context, sdp *SessionDescription) error
+	SetRemoteDescription(sdp *SessionDescription) error
+	SetRemoteDescriptionContext(ctx context.Context, sdp *SessionDescription) error
+	LocalDescription() *SessionDescription
+	RemoteDescription() *SessionDescription
+	AddIceCandidate(ic *IceCandidate) error
+	AddIceCandidateContext(ctx context.Context, ic *IceCandidate) error
+	CreateDataChannel(label string, opts ...*DataChannelInit) (Channel, error)
+	AddTrack(track Track, streams ...*MediaStream) error
+	RemoveTrack(track Track) error
+	AddTransceiver(kind string, direction RTPTransceiverDirection) (Transceiver, error)
+	GetTransceivers() []Transceiver
+	GetSenders() []Sender
+	GetReceivers() []Receiver
+
+	SignalingState() SignalingState
+	IceConnectionState() IceConnectionState
+	IceGatheringState() IceGatheringState
+	ConnectionState() PeerConnectionState
+	GetStats(ctx context.Context) (*StatsReport, error)
+
+	OnNegotiationNeeded(cb func())
+	OnIceCandidate(cb func(*IceCandidate))
+	OnSignalingStateChange(cb func(SignalingState))
+	OnIceConnectionStateChange(cb func(IceConnectionState))
+	OnIceGatheringStateChange(cb func(IceGatheringState))
+	OnConnectionStateChange(cb func(PeerConnectionState))
+	OnDataChannel(cb func(Channel))
+	OnTrack(cb func(Track, []*MediaStream))
+	OnRemoteCandidateError(cb func(*IceCandidate, error))
+	Events() <-chan Event
+	Unsubscribe(ch <-chan Event)
+
+	Close() error
+}
+
+// Channel is the method set every backend's *DataChannel has. A fake can
+// implement Framed with NewFramedChannel.
+type Channel interface {
+	Label() string
+	ID() int
+	ReadyState() string
+	Ordered() bool
+	MaxPacketLifeTime() int
+	MaxRetransmits() int
+	Protocol() string
+	Negotiated() bool
+
+	Send(data []byte)
+	SendText(text string)
+	SendContext(ctx context.Context, data []byte) error
+	SendTextContext(ctx context.Context, text string) error
+	BufferedAmount() int
+	BufferedAmountLowThreshold() int
+	SetBufferedAmountLowThreshold(n int)
+
+	OnOpen(cb func())
+	OnClose(cb func())
+	OnMessage(cb func([]byte))
+	OnMessageData(cb func(Message))
+	OnBufferedAmountLow(cb func())
+	Events() <-chan Event
+	Unsubscribe(ch <-chan Event)
+
+	Conn() net.Conn
+	Framed(limit int) *FramedChannel
+	Close() error
+}
+
+// Track is the method set every backend's *MediaStreamTrack has.
+type Track interface {
+	ID() string
+	Kind() string
+	Stop()
+}
+
+// Sender is the sending half of a Transceiver.
+type Sender interface {
+	// Track returns nil when nothing is sent.
+	Track() Track
+}
+
+// Receiver is the receiving half of a Transceiver.
+type Receiver interface {
+	Track() Track
+}
+
+// Transceiver is the method set of every backend's *RTPTransceiver, with
+// the sender and receiver as interfaces.
+type Transceiver interface {
+	Mid() string
+	Kind() string
+	Direction() RTPTransceiverDirection
+	SetDirection(d RTPTransceiverDirection) error
+	Stop() error
+	Sender() Sender
+	Receiver() Receiver
+}
+
+var (
+	_ Peer    = peer{}
+	_ Channel = (*DataChannel)(nil)
+	_ Track   = (*MediaStreamTrack)(nil)
+)
+
+// NewPeer returns pc as a Peer.
+func NewPeer(pc *PeerConnection) Peer {
+	return peer{pc}
+}
+
+// peer converts the concrete types of *PeerConnection to the interfaces.
+type peer struct {
+	*PeerConnection
+}
+
+func (p peer) CreateDataChannel(label string, opts ...*DataChannelInit) (Channel, error) {
+	dc, err := p.PeerConnection.CreateDataChannel(label, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return dc, nil
+}
+
+// track returns the backend's track behind t.
+func track(t Track) (*MediaStreamTrack, error) {
+	mt, ok := t.(*MediaStreamTrack)
+	if !ok || mt == nil {
+		return nil, fmt.Errorf("%T is not a track of this backend", t)
+	}
+	return mt, nil
+}
+
+func (p peer) AddTrack(t Track, streams ...*MediaStream) error {
+	mt, err := track(t)
+	if err != nil {
+		return fmt.Errorf("add track: %w", err)
+	}
+	return p.PeerConnection.AddTrack(mt, streams...)
+}
+
+func (p peer) RemoveTrack(t Track) error {
+	mt, err := track(t)
+	if err != nil {
+		return fmt.Errorf("remove track: %w", err)
+	}
+	return p.PeerConnection.RemoveTrack(mt)
+}
+
+func (p peer) AddTransceiver(kind string, direction RTPTransceiverDirection) (Transceiver, error) {
+	t, err := p.PeerConnection.AddTransceiver(kind, direction)
+	if err != nil {
+		return nil, err
+	}
+	return transceiver{t}, nil
+}
+
+func (p peer) GetTransceivers() []Transceiver {
+	var list []Transceiver
+	for _, t := range p.PeerConnection.GetTransceivers() {
+		list = append(list, transceiver{t})
+	}
+	return list
+}
+
+func (p peer) GetSenders() []Sender {
+	var list []Sender
+	for _, s := range p.PeerConnection.GetSenders() {
+		list = append(list, sender{s})
+	}
+	return list
+}
+
+func (p peer) GetReceivers() []Receiver {
+	var list []Receiver
+	for _, r := range p.PeerConnection.GetReceivers() {
+		list = append(list, receiver{r})
+	}
+	return list
+}
+
+func (p peer) OnDataChannel(cb func(Channel)) {
+	p.PeerConnection.OnDataChannel(func(dc *DataChannel) {
+		cb(dc)
+	})
+}
+
+func (p peer) OnTrack(cb func(Track, []*MediaStream)) {
+	p.PeerConnection.OnTrack(func(t *MediaStreamTrack, streams []*MediaStream) {
+		cb(t, streams)
+	})
+}
+
+type transceiver struct {
+	*RTPTransceiver
+}
+
+func (t transceiver) Sender() Sender {
+	if s := t.RTPTransceiver.Sender(); s != nil {
+		return sender{s}
+	}
+	return nil
+}
+
+func (t transceiver) Receiver() Receiver {
+	if r := t.RTPTransceiver.Receiver(); r != nil {
+		return receiver{r}
+	}
+	return nil
+}
+
+type sender struct {
+	s *RTPSender
+}
+
+func (s sender) Track() Track {
+	if t := s.s.Track(); t != nil {
+		return t
+	}
+	return nil
+}
+
+type receiver struct {
+	r *RTPReceiver
+}
+
+func (r receiver) Track() Track {
+	if t := r.r.Track(); t != nil {
+		return t
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+// +build mock
+
+package webrtc
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// negotiatedPeers is negotiatedPair seen through the Peer interface.
+func negotiatedPeers(t *testing.T) (Peer, Peer) {
+	t.Helper()
+	a, b, errc := negotiatedPair(t, func(polite, impolite *Negotiator) {})
+	a.CreateDataChannel("up")
+	waitConnected(t, errc, a, b)
+	return NewPeer(a), NewPeer(b)
+}
+
+func TestPeerChannel(t *testing.T) {
+	a, b := negotiatedPeers(t)
+	accepted := make(chan Channel, 1)
+	b.OnDataChannel(func(c Channel) {
+		if c.Label() == "chat" {
+			accepted <- c
+		}
+	})
+	c, err := a.CreateDataChannel("chat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := c.Conn()
+	if _, err := w.Write([]byte("hi")); err != nil {
+		t.Fatal(err)
+	}
+	var rc Channel
+	select {
+	case rc = <-accepted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("channel not announced")
+	}
+	r := rc.Conn()
+	r.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 2)
+	if _, err := io.ReadFull(r, buf); err != nil || string(buf) != "hi" {
+		t.Fatalf("read %q, %v", buf, err)
+	}
+	if _, err := a.CreateDataChannel("x", &DataChannelInit{}, &DataChannelInit{}); err == nil {
+		t.Fatal("two DataChannelInit accepted")
+	}
+}
+
+func TestPeerTrack(t *testing.T) {
+	a, b := negotiatedPeers(t)
+	got := make(chan Track, 1)
+	b.OnTrack(func(track Track, streams []*MediaStream) {
+		if len(streams) != 1 || streams[0].ID() != "stream" {
+			t.Errorf("streams %v", streams)
+		}
+		got <- track
+	})
+	track, err := NewSampleTrack("cam", "video/VP8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AddTrack(track, NewMediaStream("stream")); err != nil {
+		t.Fatal(err)
+	}
+	var remote Track
+	select {
+	case remote = <-got:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no track")
+	}
+	if remote.Kind() != "video" {
+		t.Fatalf("remote kind %s", remote.Kind())
+	}
+	if err := track.WriteSample([]byte("frame"), 33*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 1500)
+	n, err := remote.(*MediaStreamTrack).ReadRTP(buf)
+	if err != nil || string(buf[12:n]) != "frame" {
+		t.Fatalf("read %q, %v", buf[:n], err)
+	}
+
+	senders := a.GetSenders()
+	if len(senders) != 1 || senders[0].Track() != Track(track) {
+		t.Fatalf("senders %v", senders)
+	}
+	if len(b.GetReceivers()) != 1 {
+		t.Fatalf("receivers %v", b.GetReceivers())
+	}
+	if err := a.AddTrack(fakeTrack{}); err == nil {
+		t.Fatal("foreign track accepted")
+	}
+	if err := a.RemoveTrack(track); err != nil {
+		t.Fatal(err)
+	}
+	if s := a.GetSenders()[0]; s.Track() != nil {
+		t.Fatalf("removed track still sent: %v", s.Track())
+	}
+}
+
+func TestPeerTransceiver(t *testing.T) {
+	a, _ := negotiatedPeers(t)
+	tr, err := a.AddTransceiver("audio", RTPTransceiverDirectionRecvonly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Kind() != "audio" || tr.Direction() != RTPTransceiverDirectionRecvonly {
+		t.Fatalf("transceiver %s %s", tr.Kind(), tr.Direction())
+	}
+	if tr.Sender() == nil || tr.Sender().Track() != nil {
+		t.Fatal("recvonly transceiver sends a track")
+	}
+	if err := tr.SetDirection(RTPTransceiverDirectionInactive); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if tr.Direction() != RTPTransceiverDirectionStopped || len(a.GetTransceivers()) != 0 {
+		t.Fatalf("direction after stop %s, %d transceivers", tr.Direction(), len(a.GetTransceivers()))
+	}
+	if _, err := a.AddTransceiver("text", RTPTransceiverDirectionSendrecv); err == nil {
+		t.Fatal("unknown kind accepted")
+	}
+}
+
+type fakeTrack struct{}
+
+func (fakeTrack) ID() string   { return "fake" }
+func (fakeTrack) Kind() string { return "audio" }
+func (fakeTrack) Stop()        {}
+
+// fakeChannel implements the part of Channel that FramedChannel uses,
+// delivering what it sends to its peer.
+type fakeChannel struct {
+	Channel
+	events broker
+	peer   *fakeChannel
+}
+
+func (c *fakeChannel) Events() <-chan Event        { return c.events.subscribe() }
+func (c *fakeChannel) Unsubscribe(ch <-chan Event) { c.events.unsubscribe(ch) }
+
+func (c *fakeChannel) SendContext(ctx context.Context, data []byte) error {
+	c.peer.events.publish(MessageEvent{Data: append([]byte(nil), data...)})
+	return nil
+}
+
+func TestFramedFake(t *testing.T) {
+	a, b := &fakeChannel{}, &fakeChannel{}
+	a.peer, b.peer = b, a
+	fa, fb := NewFramedChannel(a, 0), NewFramedChannel(b, 0)
+	defer fa.Close()
+	defer fb.Close()
+	if fa.DataChannel() != nil || fa.Channel() != Channel(a) {
+		t.Fatal("wrong underlying channel")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	msg := make([]byte, 200<<10)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	if err := fa.Send(ctx, msg); err != nil {
+		t.Fatal(err)
+	}
+	m, err := fb.Recv(ctx)
+	if err != nil || len(m.Data) != len(msg) || m.Data[len(msg)-1] != msg[len(msg)-1] {
+		t.Fatalf("received %d bytes, %v", len(m.Data), err)
+	}
+}
+
+func TestStreamCallbacks(t *testing.T) {
+	var buf bytes.Buffer
+	SetLogger(slog.NewTextHandler(&buf, nil))
+	defer SetLogger(nil)
+	pc, err := NewPeerConnection(&Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	pc.OnAddStream(func(*MediaStream) {})
+	pc.OnRemoveStream(func(*MediaStream) {})
+	for _, name := range []string{"OnAddStream", "OnRemoveStream"} {
+		if !strings.Contains(buf.String(), name+" is never called") {
+			t.Errorf("%s not logged: %q", name, buf.String())
+		}
+	}
+	if err := pc.AddStream(NewMediaStream("s")); err != ErrMediaNotSupported {
+		t.Fatalf("AddStream: got %v, want %v", err, ErrMediaNotSupported)
+	}
+}
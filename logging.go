@@ -0,0 +1,118 @@
+package webrtc
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// discardHandler drops every record.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+type handlerBox struct{ h slog.Handler }
+
+var (
+	logHandler atomic.Value
+	lastLogID  uint64
+)
+
+// SetLogger sends the logs of every PeerConnection to h: state changes and
+// gathered candidates at debug level, failures at warn level. Logs are
+// discarded until SetLogger is called, and again after SetLogger(nil).
+func SetLogger(h slog.Handler) {
+	if h == nil {
+		h = discardHandler{}
+	}
+	logHandler.Store(handlerBox{h})
+}
+
+func currentHandler() slog.Handler {
+	if b, ok := logHandler.Load().(handlerBox); ok {
+		return b.h
+	}
+	return discardHandler{}
+}
+
+// pcLog holds the logging attributes of one PeerConnection.
+type pcLog struct {
+	id    uint64
+	mu    sync.Mutex
+	attrs []interface{}
+}
+
+func (l *pcLog) init() {
+	l.id = atomic.AddUint64(&lastLogID, 1)
+}
+
+func (l *pcLog) logger() *slog.Logger {
+	l.mu.Lock()
+	attrs := l.attrs
+	l.mu.Unlock()
+	return slog.New(currentHandler()).With("pc", l.id).With(attrs...)
+}
+
+func (l *pcLog) event(ev Event) {
+	logger := l.logger()
+	switch ev := ev.(type) {
+	case IceCandidateEvent:
+		logger.Debug("ice candidate", "candidate", ev.Candidate.Candidate)
+	case IceCandidateErrorEvent:
+		logger.Warn("ice candidate error")
+	case SignalingStateChangeEvent:
+		logger.Debug("signaling state", "state", ev.State.String())
+	case IceGatheringStateChangeEvent:
+		logger.Debug("ice gathering state", "state", ev.State.String())
+	case IceConnectionStateChangeEvent:
+		level := slog.LevelDebug
+		if ev.State == IceConnectionStateFailed {
+			level = slog.LevelWarn
+		}
+		logger.Log(context.Background(), level, "ice connection state", "state", ev.State.String())
+	case ConnectionStateChangeEvent:
+		level := slog.LevelDebug
+		if ev.State == PeerConnectionStateFailed {
+			level = slog.LevelWarn
+		}
+		logger.Log(context.Background(), level, "connection state", "state", ev.State.String())
+	case DataChannelEvent:
+		logger.Debug("data channel", "label", ev.Channel.Label())
+	case TrackEvent:
+		logger.Debug("track", "kind", ev.Track.Kind(), "id", ev.Track.ID())
+	default:
+		logger.Debug(ev.Type())
+	}
+}
+
+// SetLogAttrs adds attributes, as for slog.Logger.With, to the logs of the
+// PeerConnection. Every PeerConnection logs a "pc" attribute numbering it.
+func (pc *PeerConnection) SetLogAttrs(args ...interface{}) {
+	pc.log.mu.Lock()
+	pc.log.attrs = append(pc.log.attrs, args...)
+	pc.log.mu.Unlock()
+}
+
+// Logger returns the logger of the PeerConnection, for applications that
+// log next to it.
+func (pc *PeerConnection) Logger() *slog.Logger {
+	return pc.log.logger()
+}
+
+// sendFailed logs an error of Send or SendText, which cannot return it.
+func (c *DataChannel) sendFailed(err error) {
+	if err == nil || c.pc == nil {
+		return
+	}
+	c.pc.log.logger().Warn("data channel send", "label", c.Label(), "error", err)
+}
+
+// publish logs and publishes an event of the PeerConnection.
+func (pc *PeerConnection) publish(ev Event) {
+	pc.log.event(ev)
+	pc.events.publish(ev)
+}
@@ -0,0 +1,173 @@
+// +build mock
+
+package webrtc
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+)
+
+// logRecord is a record kept by logRecorder, with the attributes of the logger
+// merged into its own.
+type logRecord struct {
+	level slog.Level
+	msg   string
+	attrs map[string]string
+}
+
+// logRecorder is a slog.Handler that keeps the records at or above level.
+type logRecorder struct {
+	level   slog.Level
+	attrs   []slog.Attr
+	mu      *sync.Mutex
+	records *[]logRecord
+}
+
+func newLogRecorder(level slog.Level) *logRecorder {
+	return &logRecorder{level: level, mu: &sync.Mutex{}, records: &[]logRecord{}}
+}
+
+func (h *logRecorder) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *logRecorder) Handle(_ context.Context, r slog.Record) error {
+	rec := logRecord{level: r.Level, msg: r.Message, attrs: map[string]string{}}
+	for _, a := range h.attrs {
+		rec.attrs[a.Key] = a.Value.String()
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		rec.attrs[a.Key] = a.Value.String()
+		return true
+	})
+	h.mu.Lock()
+	*h.records = append(*h.records, rec)
+	h.mu.Unlock()
+	return nil
+}
+
+func (h *logRecorder) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.attrs = append(append([]slog.Attr{}, h.attrs...), attrs...)
+	return &c
+}
+
+func (h *logRecorder) WithGroup(string) slog.Handler { return h }
+
+// find returns the records with message msg.
+func (h *logRecorder) find(msg string) []logRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var found []logRecord
+	for _, r := range *h.records {
+		if r.msg == msg {
+			found = append(found, r)
+		}
+	}
+	return found
+}
+
+func (h *logRecorder) all() []logRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]logRecord{}, *h.records...)
+}
+
+// logActivity makes a PeerConnection tagged with peer log a negotiationneeded
+// event, signaling and gathering state changes, and a failed send.
+func logActivity(t *testing.T, peer string) {
+	t.Helper()
+	pc, err := NewPeerConnection(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	pc.SetLogAttrs("peer", peer)
+	events := pc.Events()
+	defer pc.Unsubscribe(events)
+	dc, err := pc.CreateDataChannel("chat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pc.CreateOfferComplete(0); err != nil {
+		t.Fatal(err)
+	}
+	// the channel is not open yet, so the send fails.
+	dc.SendText("lost")
+	// wait until the events before are published, and so logged.
+	for {
+		ev, ok := next(t, events)
+		if !ok {
+			t.Fatal("events closed")
+		}
+		if ev, ok := ev.(IceGatheringStateChangeEvent); ok && ev.State == IceGatheringStateComplete {
+			break
+		}
+	}
+}
+
+func TestLogging(t *testing.T) {
+	defer SetLogger(nil)
+	SetLoggingVerbosity(3) // go-webrtc's own logs; no effect on the handler.
+
+	h := newLogRecorder(slog.LevelDebug)
+	SetLogger(h)
+	logActivity(t, "a")
+	logActivity(t, "b")
+	for _, tc := range []struct {
+		msg   string
+		level slog.Level
+		attrs map[string]string
+	}{
+		{"negotiationneeded", slog.LevelDebug, nil},
+		{"signaling state", slog.LevelDebug, map[string]string{"state": "have-local-offer"}},
+		{"ice gathering state", slog.LevelDebug, map[string]string{"state": "complete"}},
+		{"data channel send", slog.LevelWarn, map[string]string{"label": "chat", "error": ErrDataChannelNotOpen.Error()}},
+	} {
+		records := h.find(tc.msg)
+		peers := map[string]string{}
+	match:
+		for _, r := range records {
+			for k, v := range tc.attrs {
+				if r.attrs[k] != v {
+					continue match
+				}
+			}
+			if r.level != tc.level {
+				t.Errorf("%s: level %s, want %s", tc.msg, r.level, tc.level)
+			}
+			peers[r.attrs["peer"]] = r.attrs["pc"]
+		}
+		if peers["a"] == "" || peers["b"] == "" || peers["a"] == peers["b"] {
+			t.Errorf("%s %v: logged for peers %v", tc.msg, tc.attrs, peers)
+		}
+	}
+
+	// below the level of the handler, only warnings are kept.
+	h = newLogRecorder(slog.LevelWarn)
+	SetLogger(h)
+	logActivity(t, "c")
+	warned := false
+	for _, r := range h.all() {
+		if r.attrs["peer"] != "c" {
+			continue
+		}
+		if r.level < slog.LevelWarn {
+			t.Errorf("%s logged at %s", r.msg, r.level)
+		}
+		warned = warned || r.msg == "data channel send"
+	}
+	if !warned {
+		t.Fatal("warning not logged")
+	}
+
+	SetLogger(nil)
+	logActivity(t, "d")
+	for _, r := range h.all() {
+		if r.attrs["peer"] == "d" {
+			t.Fatalf("%s logged after SetLogger(nil)", r.msg)
+		}
+	}
+}
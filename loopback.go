@@ -0,0 +1,148 @@
+package webrtc
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrSignalerClosed ...
+var ErrSignalerClosed = errors.New("signaler closed")
+
+var pairTimeout = 10 * time.Second
+
+type loopback struct {
+	mu     sync.Mutex
+	peer   *loopback
+	queue  []*Signal
+	closed bool
+	notify chan struct{}
+}
+
+// NewLoopback returns two in-memory Signalers connected to each other.
+func NewLoopback() (Signaler, Signaler) {
+	a := &loopback{notify: make(chan struct{}, 1)}
+	b := &loopback{notify: make(chan struct{}, 1)}
+	a.peer, b.peer = b, a
+	return a, b
+}
+
+func (l *loopback) wake() {
+	select {
+	case l.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Send ...
+func (l *loopback) Send(s *Signal) error {
+	p := l.peer
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrSignalerClosed
+	}
+	c := *s
+	p.queue = append(p.queue, &c)
+	p.mu.Unlock()
+	p.wake()
+	return nil
+}
+
+// Recv ...
+func (l *loopback) Recv() (*Signal, error) {
+	for {
+		l.mu.Lock()
+		if len(l.queue) > 0 {
+			s := l.queue[0]
+			l.queue = l.queue[1:]
+			l.mu.Unlock()
+			return s, nil
+		}
+		closed := l.closed
+		l.mu.Unlock()
+		if closed {
+			return nil, ErrSignalerClosed
+		}
+		<-l.notify
+	}
+}
+
+// Close closes both ends.
+func (l *loopback) Close() error {
+	for _, e := range []*loopback{l, l.peer} {
+		e.mu.Lock()
+		e.closed = true
+		e.mu.Unlock()
+		e.wake()
+	}
+	return nil
+}
+
+// NewPair returns two PeerConnections connected to each other through
+// an in-memory Signaler. The first one opens a data channel labeled "pair"
+// to bring the connection up; it is open on both sides before NewPair
+// returns, so callers that want a channel should create their own. The
+// signaling stops once either PeerConnection is closed.
+func NewPair(config *Configuration) (*PeerConnection, *PeerConnection, error) {
+	offerer, err := NewPeerConnection(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	answerer, err := NewPeerConnection(config)
+	if err != nil {
+		offerer.Close()
+		return nil, nil, err
+	}
+	fail := func(err error) (*PeerConnection, *PeerConnection, error) {
+		offerer.Close()
+		answerer.Close()
+		return nil, nil, fmt.Errorf("new pair: %w", err)
+	}
+	dc, err := offerer.CreateDataChannel("pair")
+	if err != nil {
+		return fail(err)
+	}
+	open := make(chan struct{}, 1)
+	dc.OnOpen(func() {
+		select {
+		case open <- struct{}{}:
+		default:
+		}
+	})
+	a, b := NewLoopback()
+	errc := make(chan error, 2)
+	go func() { errc <- Connect(offerer, a) }()
+	go func() { errc <- Connect(answerer, b) }()
+	if err := b.Send(&Signal{Type: SignalJoin}); err != nil {
+		a.Close()
+		return fail(err)
+	}
+	select {
+	case <-open:
+		go closeWith(a, offerer, answerer)
+		return offerer, answerer, nil
+	case err := <-errc:
+		a.Close()
+		return fail(err)
+	case <-time.After(pairTimeout):
+		a.Close()
+		return fail(errors.New("timeout"))
+	}
+}
+
+// closeWith closes s once one of pcs is closed, which ends the Connect
+// calls that use s. The watch on the others ends when they are closed.
+func closeWith(s Signaler, pcs ...*PeerConnection) {
+	closed := make(chan struct{}, len(pcs))
+	for _, pc := range pcs {
+		go func(events <-chan Event) {
+			for range events {
+			}
+			closed <- struct{}{}
+		}(pc.Events())
+	}
+	<-closed
+	s.Close()
+}
@@ -0,0 +1,72 @@
+// +build mock
+
+package webrtc
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestLoopback(t *testing.T) {
+	a, b := NewLoopback()
+	for i := 0; i < 3; i++ {
+		if err := a.Send(&Signal{Type: SignalCandidate, SdpMLineIndex: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		s, err := b.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.SdpMLineIndex != i {
+			t.Fatalf("signal %d arrived as %d", i, s.SdpMLineIndex)
+		}
+	}
+	b.Close()
+	if _, err := a.Recv(); err != ErrSignalerClosed {
+		t.Fatalf("Recv after Close: %v", err)
+	}
+	if err := a.Send(&Signal{}); err != ErrSignalerClosed {
+		t.Fatalf("Send after Close: %v", err)
+	}
+}
+
+func TestNewPair(t *testing.T) {
+	before := runtime.NumGoroutine()
+	offerer, answerer, err := NewPair(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pc := range []*PeerConnection{offerer, answerer} {
+		if s := pc.ConnectionState(); s != PeerConnectionStateConnected {
+			t.Fatalf("connection state %s", s)
+		}
+	}
+	offerer.Close()
+	answerer.Close()
+	// the Connect goroutines and the watchers end with the pair.
+	for i := 0; ; i++ {
+		if runtime.NumGoroutine() <= before {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("%d goroutines left running", runtime.NumGoroutine()-before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNewPairTimeout(t *testing.T) {
+	defer func(d time.Duration) { pairTimeout = d }(pairTimeout)
+	pairTimeout = 0
+	_, _, err := NewPair(nil)
+	if err == nil {
+		return // connected before the timer fired.
+	}
+	if errors.Unwrap(err) == nil {
+		t.Fatalf("error %v does not wrap its cause", err)
+	}
+}
@@ -0,0 +1,106 @@
+// +build !js
+
+package webrtc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrMediaNotSupported is returned by the media functions a backend
+	// lacks.
+	ErrMediaNotSupported = errors.New("media is not supported by this backend")
+	// ErrDirectionNotSupported is returned by SetDirection for the changes
+	// a backend cannot make.
+	ErrDirectionNotSupported = errors.New("direction change is not supported by this backend")
+	// ErrTrackStopped is returned by WriteSample and ReadRTP after Stop.
+	ErrTrackStopped = errors.New("track stopped")
+)
+
+// neverCalled logs that the callback set with the method name will not be
+// called by the backend.
+func (pc *PeerConnection) neverCalled(name string) {
+	pc.log.logger().Warn(name+" is never called", "error", ErrMediaNotSupported)
+}
+
+// noStream is the msid of a track added without a stream.
+const noStream = "-"
+
+// MediaStream groups tracks, as the msid of their SDP does.
+type MediaStream struct {
+	id     string
+	mu     sync.Mutex
+	tracks []*MediaStreamTrack
+}
+
+// NewMediaStream returns an empty stream. Tracks added to a PeerConnection
+// with it are grouped under id on the remote side.
+func NewMediaStream(id string) *MediaStream {
+	return &MediaStream{id: id}
+}
+
+// ID ...
+func (s *MediaStream) ID() string {
+	return s.id
+}
+
+// GetTracks ...
+func (s *MediaStream) GetTracks() []*MediaStreamTrack {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*MediaStreamTrack{}, s.tracks...)
+}
+
+// AddTrack ...
+func (s *MediaStream) AddTrack(t *MediaStreamTrack) {
+	s.mu.Lock()
+	s.tracks = append(s.tracks, t)
+	s.mu.Unlock()
+}
+
+// streamID returns the msid for a track added with streams. Only the first
+// stream is signaled.
+func streamID(streams []*MediaStream) string {
+	if len(streams) == 0 || streams[0] == nil {
+		return noStream
+	}
+	return streams[0].ID()
+}
+
+// trackKind returns "audio" or "video" for a codec MIME type such as
+// "video/VP8".
+func trackKind(mimeType string) (string, error) {
+	kind := strings.ToLower(strings.SplitN(mimeType, "/", 2)[0])
+	if kind != "audio" && kind != "video" {
+		return "", fmt.Errorf("unknown track kind of %q", mimeType)
+	}
+	return kind, nil
+}
+
+// streamSet holds the remote streams of a PeerConnection by ID, so that
+// tracks of one stream share its MediaStream.
+type streamSet struct {
+	mu      sync.Mutex
+	streams map[string]*MediaStream
+}
+
+func (s *streamSet) add(id string, t *MediaStreamTrack) []*MediaStream {
+	if id == noStream || id == "" {
+		return nil
+	}
+	s.mu.Lock()
+	if s.streams == nil {
+		s.streams = map[string]*MediaStream{}
+	}
+	stream := s.streams[id]
+	if stream == nil {
+		stream = NewMediaStream(id)
+		s.streams[id] = stream
+	}
+	s.mu.Unlock()
+	stream.AddTrack(t)
+	return []*MediaStream{stream}
+}
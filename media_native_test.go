@@ -0,0 +1,66 @@
+//go:build !js && !mock && !pion
+// +build !js,!mock,!pion
+
+package webrtc
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestMediaNotSupported(t *testing.T) {
+	var buf bytes.Buffer
+	SetLogger(slog.NewTextHandler(&buf, nil))
+	defer SetLogger(nil)
+	// the media methods don't reach go-webrtc, so an unconnected
+	// PeerConnection will do.
+	pc := &PeerConnection{}
+	track := &MediaStreamTrack{}
+	tr := &RTPTransceiver{}
+	for name, call := range map[string]func() error{
+		"GetUserMedia": func() error {
+			_, err := GetUserMedia(&Constraints{Audio: true})
+			return err
+		},
+		"GetUserMediaContext": func() error {
+			_, err := GetUserMediaContext(context.Background(), &Constraints{Audio: true})
+			return err
+		},
+		"NewSampleTrack": func() error {
+			_, err := NewSampleTrack("cam", "video/VP8")
+			return err
+		},
+		"AddStream":   func() error { return pc.AddStream(NewMediaStream("s")) },
+		"AddTrack":    func() error { return pc.AddTrack(track) },
+		"RemoveTrack": func() error { return pc.RemoveTrack(track) },
+		"AddTransceiver": func() error {
+			_, err := pc.AddTransceiver("audio", RTPTransceiverDirectionSendrecv)
+			return err
+		},
+		"WriteSample": func() error { return track.WriteSample(nil, 0) },
+		"ReadRTP": func() error {
+			_, err := track.ReadRTP(make([]byte, 1500))
+			return err
+		},
+		"SetDirection":     func() error { return tr.SetDirection(RTPTransceiverDirectionInactive) },
+		"Transceiver.Stop": func() error { return tr.Stop() },
+	} {
+		if err := call(); err != ErrMediaNotSupported {
+			t.Errorf("%s: got %v, want %v", name, err, ErrMediaNotSupported)
+		}
+	}
+	if pc.GetTransceivers() != nil || pc.GetSenders() != nil || pc.GetReceivers() != nil {
+		t.Error("transceivers reported")
+	}
+	pc.OnTrack(func(*MediaStreamTrack, []*MediaStream) {})
+	pc.OnAddStream(func(*MediaStream) {})
+	pc.OnRemoveStream(func(*MediaStream) {})
+	for _, name := range []string{"OnTrack", "OnAddStream", "OnRemoveStream"} {
+		if !strings.Contains(buf.String(), name+" is never called") {
+			t.Errorf("%s not logged: %q", name, buf.String())
+		}
+	}
+}
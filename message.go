@@ -0,0 +1,23 @@
+package webrtc
+
+import (
+	"context"
+)
+
+// Message is a DataChannel message with its frame type.
+type Message struct {
+	Data     []byte
+	IsString bool
+}
+
+// String ...
+func (m Message) String() string {
+	return string(m.Data)
+}
+
+// SendTextContext is SendContext for text messages.
+func (c *DataChannel) SendTextContext(ctx context.Context, text string) error {
+	return c.sendContext(ctx, len(text), func() error {
+		return c.sendMessage([]byte(text), true)
+	})
+}
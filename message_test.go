@@ -0,0 +1,115 @@
+// +build mock
+
+package webrtc
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// channelPair returns both ends of a DataChannel label between a connected
+// pair, once the local end is open.
+func channelPair(t *testing.T, label string) (*DataChannel, *DataChannel) {
+	t.Helper()
+	offerer, answerer, err := NewPair(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		offerer.Close()
+		answerer.Close()
+	})
+	accepted := make(chan *DataChannel, 1)
+	answerer.OnDataChannel(func(dc *DataChannel) {
+		if dc.Label() == label {
+			accepted <- dc
+		}
+	})
+	dc, err := offerer.CreateDataChannel(label)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := dc.Events()
+	defer dc.Unsubscribe(events)
+	for {
+		ev, ok := next(t, events)
+		if !ok {
+			t.Fatal("events closed")
+		}
+		if _, ok := ev.(OpenEvent); ok {
+			break
+		}
+	}
+	select {
+	case rc := <-accepted:
+		return dc, rc
+	case <-time.After(5 * time.Second):
+		t.Fatal("data channel not announced")
+		return nil, nil
+	}
+}
+
+// nextMessage returns the next message event on events.
+func nextMessage(t *testing.T, events <-chan Event) MessageEvent {
+	t.Helper()
+	for {
+		ev, ok := next(t, events)
+		if !ok {
+			t.Fatal("events closed")
+		}
+		if m, ok := ev.(MessageEvent); ok {
+			return m
+		}
+	}
+}
+
+func TestMessageType(t *testing.T) {
+	dc, rc := channelPair(t, "types")
+	events := rc.Events()
+	defer rc.Unsubscribe(events)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, tc := range []struct {
+		name     string
+		send     func(string) error
+		isString bool
+	}{
+		{"Send", func(s string) error { dc.Send([]byte(s)); return nil }, false},
+		{"SendText", func(s string) error { dc.SendText(s); return nil }, true},
+		{"SendContext", func(s string) error { return dc.SendContext(ctx, []byte(s)) }, false},
+		{"SendTextContext", func(s string) error { return dc.SendTextContext(ctx, s) }, true},
+	} {
+		if err := tc.send(tc.name); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		m := nextMessage(t, events)
+		if string(m.Data) != tc.name || m.IsString != tc.isString {
+			t.Fatalf("%s: got %q (string %v), want string %v", tc.name, m.Data, m.IsString, tc.isString)
+		}
+	}
+}
+
+func TestSendTextContext(t *testing.T) {
+	dc, rc := channelPair(t, "text")
+	got := make(chan Message, 1)
+	rc.OnMessageData(func(m Message) { got <- m })
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// the channel has room, so a done ctx does not stop the send.
+	if err := dc.SendTextContext(ctx, "hi"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case m := <-got:
+		if m.String() != "hi" || !m.IsString {
+			t.Fatalf("got %q (string %v)", m.Data, m.IsString)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message not delivered")
+	}
+	dc.Close()
+	if err := dc.SendTextContext(context.Background(), "late"); err != ErrDataChannelNotOpen {
+		t.Fatalf("after close: got %v, want %v", err, ErrDataChannelNotOpen)
+	}
+}
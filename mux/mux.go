@@ -0,0 +1,95 @@
+// Package mux multiplexes streams over one connection, typically the
+// net.Conn of a single webrtc.DataChannel.
+//
+// One side runs Client and the other Server on the two ends of the
+// connection. Either side may then OpenStream, and the peer receives the
+// stream from AcceptStream. Every stream has its own receive window, so a
+// slow reader only stalls its own stream.
+//
+//	sess := mux.Client(dc.Conn(), nil)
+//	st, err := sess.OpenStream()
+package mux
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrSessionClosed ...
+	ErrSessionClosed = errors.New("mux: session closed")
+	// ErrStreamClosed ...
+	ErrStreamClosed = errors.New("mux: stream closed")
+	// ErrStreamReset ...
+	ErrStreamReset = errors.New("mux: stream reset by peer")
+)
+
+// initialWindow is the receive window every stream starts with. A larger
+// Config.MaxStreamWindow is announced with a window update on open.
+const initialWindow = 256 * 1024
+
+// Config ...
+type Config struct {
+	// MaxStreamWindow is the receive window of each stream, at least
+	// 256KiB.
+	MaxStreamWindow uint32
+	// AcceptBacklog is the number of streams waiting for AcceptStream
+	// before new ones are reset.
+	AcceptBacklog int
+}
+
+// DefaultConfig ...
+func DefaultConfig() *Config {
+	return &Config{
+		MaxStreamWindow: initialWindow,
+		AcceptBacklog:   256,
+	}
+}
+
+func (c *Config) validate() error {
+	if c.MaxStreamWindow < initialWindow {
+		return fmt.Errorf("mux: MaxStreamWindow must be at least %d", initialWindow)
+	}
+	if c.AcceptBacklog <= 0 {
+		return fmt.Errorf("mux: AcceptBacklog must be positive")
+	}
+	return nil
+}
+
+// frame types
+const (
+	typeData byte = iota
+	typeWindowUpdate
+	typeGoAway
+)
+
+// frame flags
+const (
+	flagSYN byte = 1 << iota
+	flagFIN
+	flagRST
+)
+
+const headerSize = 10
+
+// header is type, flags, stream id and length. length is the payload size
+// of data frames and the window delta of window updates.
+type header [headerSize]byte
+
+// maxPayload keeps each frame within one 16KiB DataChannel message.
+const maxPayload = 16*1024 - headerSize
+
+func newHeader(typ, flags byte, id, length uint32) header {
+	var h header
+	h[0] = typ
+	h[1] = flags
+	binary.BigEndian.PutUint32(h[2:], id)
+	binary.BigEndian.PutUint32(h[6:], length)
+	return h
+}
+
+func (h header) typ() byte      { return h[0] }
+func (h header) flags() byte    { return h[1] }
+func (h header) id() uint32     { return binary.BigEndian.Uint32(h[2:]) }
+func (h header) length() uint32 { return binary.BigEndian.Uint32(h[6:]) }
@@ -0,0 +1,141 @@
+// +build mock
+
+package mux
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nobonobo/webrtc/webrtctest"
+)
+
+// conns returns the two ends of a DataChannel between a connected pair.
+func conns(t *testing.T) (net.Conn, net.Conn) {
+	t.Helper()
+	dc, rc := webrtctest.Channels(t, "mux", nil)
+	return dc.Conn(), rc.Conn()
+}
+
+func sessions(t *testing.T, config *Config) (*Session, *Session) {
+	t.Helper()
+	a, b := conns(t)
+	client, err := Client(a, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := Server(b, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return client, server
+}
+
+func TestEcho(t *testing.T) {
+	client, server := sessions(t, nil)
+	go func() {
+		for {
+			st, err := server.AcceptStream()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(st, st)
+				st.Close()
+			}()
+		}
+	}()
+
+	const size = 2 << 20
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			st, err := client.OpenStream()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			st.SetDeadline(time.Now().Add(30 * time.Second))
+			data := make([]byte, size)
+			rand.Read(data)
+			go func() {
+				st.Write(data)
+				st.Close()
+			}()
+			got, err := io.ReadAll(st)
+			if err != nil {
+				t.Errorf("stream %d: %v", st.ID(), err)
+				return
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("stream %d: echoed %d bytes, differ from the %d sent", st.ID(), len(got), len(data))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestReset(t *testing.T) {
+	client, server := sessions(t, nil)
+	st, err := client.OpenStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.Write([]byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	peer, err := server.AcceptStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	peer.SetReadDeadline(time.Now().Add(5 * time.Second))
+	b := make([]byte, 1)
+	if _, err := io.ReadFull(peer, b); err != nil {
+		t.Fatal(err)
+	}
+	st.Reset()
+	if _, err := peer.Read(b); err != ErrStreamReset {
+		t.Fatalf("read after reset: %v", err)
+	}
+	if _, err := st.Write(b); err != ErrStreamReset {
+		t.Fatalf("write after reset: %v", err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	client, server := sessions(t, nil)
+	client.Close()
+	select {
+	case <-server.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("server session still running")
+	}
+	if _, err := server.AcceptStream(); err != ErrSessionClosed {
+		t.Fatalf("accept after close: %v", err)
+	}
+	if _, err := client.OpenStream(); err != ErrSessionClosed {
+		t.Fatalf("open after close: %v", err)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	if err := (&Config{MaxStreamWindow: initialWindow - 1, AcceptBacklog: 1}).validate(); err == nil {
+		t.Fatal("small window accepted")
+	}
+	if err := (&Config{MaxStreamWindow: initialWindow}).validate(); err == nil {
+		t.Fatal("zero backlog accepted")
+	}
+	if err := DefaultConfig().validate(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,265 @@
+package mux
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+// Session multiplexes streams over one connection.
+type Session struct {
+	conn   io.ReadWriteCloser
+	config *Config
+	nextID uint32
+
+	mu      sync.Mutex
+	streams map[uint32]*Stream
+	accept  chan *Stream
+
+	wmu sync.Mutex
+
+	closed    chan struct{}
+	closeOnce sync.Once
+	err       error
+}
+
+var _ net.Listener = (*Session)(nil)
+
+// Client starts the session of the side with odd stream IDs. config may be
+// nil for DefaultConfig.
+func Client(conn io.ReadWriteCloser, config *Config) (*Session, error) {
+	return newSession(conn, config, 1)
+}
+
+// Server starts the session of the side with even stream IDs. config may be
+// nil for DefaultConfig.
+func Server(conn io.ReadWriteCloser, config *Config) (*Session, error) {
+	return newSession(conn, config, 2)
+}
+
+func newSession(conn io.ReadWriteCloser, config *Config, firstID uint32) (*Session, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	s := &Session{
+		conn:    conn,
+		config:  config,
+		nextID:  firstID,
+		streams: map[uint32]*Stream{},
+		accept:  make(chan *Stream, config.AcceptBacklog),
+		closed:  make(chan struct{}),
+	}
+	go s.recvLoop()
+	return s, nil
+}
+
+// OpenStream opens a stream to the peer.
+func (s *Session) OpenStream() (*Stream, error) {
+	s.mu.Lock()
+	select {
+	case <-s.closed:
+		s.mu.Unlock()
+		return nil, ErrSessionClosed
+	default:
+	}
+	id := s.nextID
+	s.nextID += 2
+	st := newStream(s, id)
+	s.streams[id] = st
+	s.mu.Unlock()
+	if err := s.writeFrame(newHeader(typeWindowUpdate, flagSYN, id, s.config.MaxStreamWindow-initialWindow), nil); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// AcceptStream waits for a stream opened by the peer.
+func (s *Session) AcceptStream() (*Stream, error) {
+	select {
+	case st := <-s.accept:
+		if delta := s.config.MaxStreamWindow - initialWindow; delta > 0 {
+			if err := s.writeFrame(newHeader(typeWindowUpdate, 0, st.id, delta), nil); err != nil {
+				return nil, err
+			}
+		}
+		return st, nil
+	case <-s.closed:
+		return nil, ErrSessionClosed
+	}
+}
+
+// Accept is AcceptStream for net.Listener.
+func (s *Session) Accept() (net.Conn, error) {
+	return s.AcceptStream()
+}
+
+// Addr ...
+func (s *Session) Addr() net.Addr {
+	return s.LocalAddr()
+}
+
+// LocalAddr returns the local address of the connection, if it has one.
+func (s *Session) LocalAddr() net.Addr {
+	if c, ok := s.conn.(net.Conn); ok {
+		return c.LocalAddr()
+	}
+	return addr("mux")
+}
+
+// RemoteAddr returns the remote address of the connection, if it has one.
+func (s *Session) RemoteAddr() net.Addr {
+	if c, ok := s.conn.(net.Conn); ok {
+		return c.RemoteAddr()
+	}
+	return addr("mux")
+}
+
+// NumStreams ...
+func (s *Session) NumStreams() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.streams)
+}
+
+// Done is closed when the session ends.
+func (s *Session) Done() <-chan struct{} {
+	return s.closed
+}
+
+// Err returns why the session ended, nil while it runs.
+func (s *Session) Err() error {
+	select {
+	case <-s.closed:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+// Close tells the peer to go away, then closes the connection and every
+// stream.
+func (s *Session) Close() error {
+	s.writeFrame(newHeader(typeGoAway, 0, 0, 0), nil)
+	return s.shutdown(ErrSessionClosed)
+}
+
+func (s *Session) shutdown(err error) error {
+	result := ErrSessionClosed
+	s.closeOnce.Do(func() {
+		s.err = err
+		close(s.closed)
+		result = s.conn.Close()
+		s.mu.Lock()
+		streams := s.streams
+		s.streams = map[uint32]*Stream{}
+		s.mu.Unlock()
+		for _, st := range streams {
+			st.wake()
+		}
+	})
+	return result
+}
+
+// writeFrame writes a frame with a single Write, so that it stays one
+// DataChannel message.
+func (s *Session) writeFrame(h header, payload []byte) error {
+	buf := make([]byte, headerSize+len(payload))
+	copy(buf, h[:])
+	copy(buf[headerSize:], payload)
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	select {
+	case <-s.closed:
+		return ErrSessionClosed
+	default:
+	}
+	if _, err := s.conn.Write(buf); err != nil {
+		s.shutdown(err)
+		return err
+	}
+	return nil
+}
+
+func (s *Session) remove(id uint32) {
+	s.mu.Lock()
+	delete(s.streams, id)
+	s.mu.Unlock()
+}
+
+func (s *Session) recvLoop() {
+	r := bufio.NewReader(s.conn)
+	for {
+		var h header
+		if _, err := io.ReadFull(r, h[:]); err != nil {
+			s.shutdown(err)
+			return
+		}
+		if err := s.handle(r, h); err != nil {
+			s.shutdown(err)
+			return
+		}
+	}
+}
+
+func (s *Session) handle(r io.Reader, h header) error {
+	var payload []byte
+	switch h.typ() {
+	case typeData:
+		if h.length() > maxPayload {
+			return fmt.Errorf("mux: frame of %d bytes too large", h.length())
+		}
+		payload = make([]byte, h.length())
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return err
+		}
+	case typeWindowUpdate:
+	case typeGoAway:
+		return io.EOF
+	default:
+		return fmt.Errorf("mux: unknown frame type %d", h.typ())
+	}
+	st, err := s.stream(h)
+	if err != nil || st == nil {
+		return err
+	}
+	return st.handle(h, payload)
+}
+
+// stream returns the stream of a frame, creating streams opened by the
+// peer. It returns nil for frames of streams already gone.
+func (s *Session) stream(h header) (*Stream, error) {
+	id := h.id()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if st, ok := s.streams[id]; ok {
+		if h.flags()&flagSYN != 0 {
+			return nil, fmt.Errorf("mux: duplicate stream %d", id)
+		}
+		return st, nil
+	}
+	if h.flags()&flagSYN == 0 {
+		return nil, nil
+	}
+	if id%2 == s.nextID%2 {
+		return nil, fmt.Errorf("mux: peer opened stream %d with our parity", id)
+	}
+	st := newStream(s, id)
+	select {
+	case s.accept <- st:
+	default:
+		go s.writeFrame(newHeader(typeWindowUpdate, flagRST, id, 0), nil)
+		return nil, nil
+	}
+	s.streams[id] = st
+	return st, nil
+}
+
+type addr string
+
+func (a addr) Network() string { return "mux" }
+func (a addr) String() string  { return string(a) }
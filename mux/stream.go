@@ -0,0 +1,259 @@
+package mux
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// Stream is one logical connection of a Session.
+type Stream struct {
+	s  *Session
+	id uint32
+
+	mu         sync.Mutex
+	recvBuf    bytes.Buffer
+	recvWindow uint32
+	consumed   uint32
+	sendWindow uint32
+
+	localClosed  bool
+	remoteClosed bool
+	reset        bool
+
+	readDeadline  time.Time
+	writeDeadline time.Time
+
+	readNotify chan struct{}
+	sendNotify chan struct{}
+}
+
+var _ net.Conn = (*Stream)(nil)
+
+func newStream(s *Session, id uint32) *Stream {
+	return &Stream{
+		s:          s,
+		id:         id,
+		recvWindow: s.config.MaxStreamWindow,
+		sendWindow: initialWindow,
+		readNotify: make(chan struct{}, 1),
+		sendNotify: make(chan struct{}, 1),
+	}
+}
+
+// ID ...
+func (st *Stream) ID() uint32 {
+	return st.id
+}
+
+func notify(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+func (st *Stream) wake() {
+	notify(st.readNotify)
+	notify(st.sendNotify)
+}
+
+// wait blocks until ch is notified, the deadline passes or the session
+// ends.
+func (st *Stream) wait(ch chan struct{}, deadline time.Time) error {
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		d := time.Until(deadline)
+		if d <= 0 {
+			return os.ErrDeadlineExceeded
+		}
+		t := time.NewTimer(d)
+		defer t.Stop()
+		timeout = t.C
+	}
+	select {
+	case <-ch:
+		return nil
+	case <-timeout:
+		return os.ErrDeadlineExceeded
+	case <-st.s.closed:
+		return ErrSessionClosed
+	}
+}
+
+// Read ...
+func (st *Stream) Read(b []byte) (int, error) {
+	for {
+		st.mu.Lock()
+		if st.recvBuf.Len() > 0 {
+			n, _ := st.recvBuf.Read(b)
+			var delta uint32
+			st.consumed += uint32(n)
+			if st.consumed >= st.s.config.MaxStreamWindow/2 && !st.remoteClosed {
+				delta = st.consumed
+				st.consumed = 0
+				st.recvWindow += delta
+			}
+			st.mu.Unlock()
+			if delta > 0 {
+				st.s.writeFrame(newHeader(typeWindowUpdate, 0, st.id, delta), nil)
+			}
+			return n, nil
+		}
+		switch {
+		case st.reset:
+			st.mu.Unlock()
+			return 0, ErrStreamReset
+		case st.remoteClosed:
+			st.mu.Unlock()
+			return 0, io.EOF
+		}
+		deadline := st.readDeadline
+		st.mu.Unlock()
+		if err := st.wait(st.readNotify, deadline); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// Write blocks while the peer's receive window for the stream is full.
+func (st *Stream) Write(b []byte) (int, error) {
+	n := 0
+	for len(b) > 0 {
+		st.mu.Lock()
+		switch {
+		case st.reset:
+			st.mu.Unlock()
+			return n, ErrStreamReset
+		case st.localClosed:
+			st.mu.Unlock()
+			return n, ErrStreamClosed
+		}
+		if st.sendWindow == 0 {
+			deadline := st.writeDeadline
+			st.mu.Unlock()
+			if err := st.wait(st.sendNotify, deadline); err != nil {
+				return n, err
+			}
+			continue
+		}
+		chunk := len(b)
+		if chunk > maxPayload {
+			chunk = maxPayload
+		}
+		if uint32(chunk) > st.sendWindow {
+			chunk = int(st.sendWindow)
+		}
+		st.sendWindow -= uint32(chunk)
+		st.mu.Unlock()
+		if err := st.s.writeFrame(newHeader(typeData, 0, st.id, uint32(chunk)), b[:chunk]); err != nil {
+			return n, err
+		}
+		n += chunk
+		b = b[chunk:]
+	}
+	return n, nil
+}
+
+// Close closes the write side. Reads continue until the peer closes too.
+func (st *Stream) Close() error {
+	st.mu.Lock()
+	if st.localClosed || st.reset {
+		st.mu.Unlock()
+		return nil
+	}
+	st.localClosed = true
+	done := st.remoteClosed
+	st.mu.Unlock()
+	if done {
+		st.s.remove(st.id)
+	}
+	st.wake()
+	return st.s.writeFrame(newHeader(typeData, flagFIN, st.id, 0), nil)
+}
+
+// Reset aborts the stream in both directions.
+func (st *Stream) Reset() error {
+	st.mu.Lock()
+	if st.reset {
+		st.mu.Unlock()
+		return nil
+	}
+	st.reset = true
+	st.mu.Unlock()
+	st.s.remove(st.id)
+	st.wake()
+	return st.s.writeFrame(newHeader(typeWindowUpdate, flagRST, st.id, 0), nil)
+}
+
+// handle applies a frame from the peer.
+func (st *Stream) handle(h header, payload []byte) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	defer st.wake()
+	switch h.typ() {
+	case typeData:
+		if uint32(len(payload)) > st.recvWindow {
+			return fmt.Errorf("mux: stream %d exceeded its window", st.id)
+		}
+		if !st.reset {
+			st.recvWindow -= uint32(len(payload))
+			st.recvBuf.Write(payload)
+		}
+	case typeWindowUpdate:
+		st.sendWindow += h.length()
+	}
+	if h.flags()&flagFIN != 0 {
+		st.remoteClosed = true
+		if st.localClosed {
+			st.s.remove(st.id)
+		}
+	}
+	if h.flags()&flagRST != 0 {
+		st.reset = true
+		st.s.remove(st.id)
+	}
+	return nil
+}
+
+// LocalAddr ...
+func (st *Stream) LocalAddr() net.Addr {
+	return st.s.LocalAddr()
+}
+
+// RemoteAddr ...
+func (st *Stream) RemoteAddr() net.Addr {
+	return st.s.RemoteAddr()
+}
+
+// SetDeadline ...
+func (st *Stream) SetDeadline(t time.Time) error {
+	st.mu.Lock()
+	st.readDeadline = t
+	st.writeDeadline = t
+	st.mu.Unlock()
+	st.wake()
+	return nil
+}
+
+// SetReadDeadline ...
+func (st *Stream) SetReadDeadline(t time.Time) error {
+	st.mu.Lock()
+	st.readDeadline = t
+	st.mu.Unlock()
+	notify(st.readNotify)
+	return nil
+}
+
+// SetWriteDeadline ...
+func (st *Stream) SetWriteDeadline(t time.Time) error {
+	st.mu.Lock()
+	st.writeDeadline = t
+	st.mu.Unlock()
+	notify(st.sendNotify)
+	return nil
+}
@@ -0,0 +1,223 @@
+package webrtc
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Negotiator runs the "perfect negotiation" pattern for a PeerConnection:
+// either side may renegotiate at any time, and when both offer at once the
+// polite peer rolls its offer back while the impolite peer ignores the
+// colliding offer. Exactly one of the two peers must be polite.
+//
+// On backends that cannot roll back (go-webrtc), the polite peer never
+// offers: it sends a join signal to have the impolite peer offer instead.
+type Negotiator struct {
+	pc     *PeerConnection
+	s      Signaler
+	ss     *signalSender
+	polite bool
+	events <-chan Event
+
+	// op serializes the offer and answer steps, so that Negotiate and
+	// incoming descriptions never interleave on the PeerConnection.
+	op sync.Mutex
+
+	mu                           sync.Mutex
+	makingOffer                  bool
+	ignoreOffer                  bool
+	isSettingRemoteAnswerPending bool
+	retry                        bool
+	errc                         chan error
+}
+
+// NewNegotiator subscribes to the events of pc at once, so that the
+// negotiationneeded and icecandidate events fired before Run are not lost.
+// Run must be called to release the subscription.
+func NewNegotiator(pc *PeerConnection, s Signaler, polite bool) *Negotiator {
+	return &Negotiator{
+		pc:     pc,
+		s:      s,
+		ss:     newSignalSender(s),
+		polite: polite,
+		events: pc.Events(),
+		errc:   make(chan error, 1),
+	}
+}
+
+// Polite ...
+func (n *Negotiator) Polite() bool {
+	return n.polite
+}
+
+// Run negotiates whenever the PeerConnection needs it, sends local
+// candidates and handles incoming signals. A join signal starts a
+// negotiation. It blocks until the Signaler or a negotiation fails.
+func (n *Negotiator) Run() error {
+	go n.ss.run()
+	defer n.ss.stop()
+	defer n.pc.Unsubscribe(n.events)
+	go func() {
+		for ev := range n.events {
+			switch ev := ev.(type) {
+			case NegotiationNeededEvent:
+				go n.Negotiate()
+			case IceCandidateEvent:
+				n.ss.candidate(ev.Candidate)
+			}
+		}
+	}()
+	sigc := make(chan *Signal)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			sig, err := n.s.Recv()
+			if err != nil {
+				if serr := n.ss.error(); serr != nil {
+					err = fmt.Errorf("send signal: %w", serr)
+				}
+				n.fail(err)
+				close(sigc)
+				return
+			}
+			select {
+			case sigc <- sig:
+			case <-done:
+				return
+			}
+		}
+	}()
+	for {
+		select {
+		case err := <-n.errc:
+			return err
+		case sig, ok := <-sigc:
+			if !ok {
+				return <-n.errc
+			}
+			if err := n.handle(sig); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (n *Negotiator) fail(err error) {
+	select {
+	case n.errc <- err:
+	default:
+	}
+}
+
+// Negotiate creates and sends an offer. It is called on negotiationneeded.
+// An offer wanted while another exchange is in progress is made once the
+// signaling state is stable again.
+func (n *Negotiator) Negotiate() {
+	if n.polite && !canRollback {
+		n.ss.signal(&Signal{Type: SignalJoin})
+		return
+	}
+	n.op.Lock()
+	defer n.op.Unlock()
+	if !n.stable() {
+		n.mu.Lock()
+		n.retry = true
+		n.mu.Unlock()
+		return
+	}
+	n.mu.Lock()
+	n.makingOffer = true
+	n.mu.Unlock()
+	defer func() {
+		n.mu.Lock()
+		n.makingOffer = false
+		n.mu.Unlock()
+	}()
+	offer, err := n.pc.CreateOffer()
+	if err != nil {
+		n.fail(fmt.Errorf("negotiate: %w", err))
+		return
+	}
+	if err := n.pc.SetLocalDescription(offer); err != nil {
+		n.fail(fmt.Errorf("negotiate: %w", err))
+		return
+	}
+	n.ss.description(offer)
+}
+
+func (n *Negotiator) stable() bool {
+	return n.pc.SignalingState() == SignalingStateStable
+}
+
+func (n *Negotiator) handle(sig *Signal) error {
+	switch sig.Type {
+	case SignalJoin:
+		go n.Negotiate()
+	case SignalOffer, SignalAnswer:
+		if err := n.describe(sig); err != nil {
+			return err
+		}
+		n.mu.Lock()
+		retry := n.retry && n.stable()
+		if retry {
+			n.retry = false
+		}
+		n.mu.Unlock()
+		if retry {
+			go n.Negotiate()
+		}
+	case SignalCandidate:
+		if err := n.pc.AddIceCandidate(sig.IceCandidate()); err != nil {
+			n.mu.Lock()
+			ignore := n.ignoreOffer
+			n.mu.Unlock()
+			if !ignore {
+				return fmt.Errorf("add ice candidate: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// describe applies a remote offer or answer, answering offers.
+func (n *Negotiator) describe(sig *Signal) error {
+	n.op.Lock()
+	defer n.op.Unlock()
+	n.mu.Lock()
+	readyForOffer := !n.makingOffer &&
+		(n.stable() || n.isSettingRemoteAnswerPending)
+	offerCollision := sig.Type == SignalOffer && !readyForOffer
+	n.ignoreOffer = !n.polite && offerCollision
+	ignore := n.ignoreOffer
+	n.isSettingRemoteAnswerPending = sig.Type == SignalAnswer
+	n.mu.Unlock()
+	if ignore {
+		return nil
+	}
+	if offerCollision {
+		rollback := NewSessionDescription("rollback", "")
+		if err := n.pc.SetLocalDescription(rollback); err != nil {
+			return fmt.Errorf("rollback: %w", err)
+		}
+	}
+	err := n.pc.SetRemoteDescription(sig.SessionDescription())
+	n.mu.Lock()
+	n.isSettingRemoteAnswerPending = false
+	n.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if sig.Type != SignalOffer {
+		return nil
+	}
+	answer, err := n.pc.CreateAnswer()
+	if err != nil {
+		return err
+	}
+	if err := n.pc.SetLocalDescription(answer); err != nil {
+		return err
+	}
+	n.ss.description(answer)
+	return nil
+}
@@ -0,0 +1,219 @@
+// +build mock
+
+package webrtc
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// negotiatedPair runs a polite and an impolite Negotiator over a loopback,
+// after start was called on both of them.
+func negotiatedPair(t *testing.T, start func(polite, impolite *Negotiator)) (*PeerConnection, *PeerConnection, chan error) {
+	t.Helper()
+	a, err := NewPeerConnection(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewPeerConnection(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sa, sb := NewLoopback()
+	polite, impolite := NewNegotiator(a, sa, true), NewNegotiator(b, sb, false)
+	start(polite, impolite)
+	errc := make(chan error, 2)
+	go func() { errc <- polite.Run() }()
+	go func() { errc <- impolite.Run() }()
+	t.Cleanup(func() {
+		sa.Close()
+		a.Close()
+		b.Close()
+	})
+	return a, b, errc
+}
+
+func waitConnected(t *testing.T, errc chan error, pcs ...*PeerConnection) {
+	t.Helper()
+	deadline := time.After(5 * time.Second)
+	for {
+		done := true
+		for _, pc := range pcs {
+			if pc.ConnectionState() != PeerConnectionStateConnected || pc.SignalingState() != SignalingStateStable {
+				done = false
+			}
+		}
+		if done {
+			return
+		}
+		select {
+		case err := <-errc:
+			t.Fatalf("negotiation failed: %v", err)
+		case <-deadline:
+			for _, pc := range pcs {
+				t.Logf("%s %s", pc.ConnectionState(), pc.SignalingState())
+			}
+			t.Fatal("not connected")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestNegotiatorGlare(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		a, b, errc := negotiatedPair(t, func(p, ip *Negotiator) {
+			// both offers are made before either side handles signals.
+			p.Negotiate()
+			ip.Negotiate()
+			for _, pc := range []*PeerConnection{p.pc, ip.pc} {
+				if s := pc.SignalingState(); s != SignalingStateHaveLocalOffer {
+					t.Fatalf("signaling state %s before the collision", s)
+				}
+			}
+		})
+		waitConnected(t, errc, a, b)
+		// the polite peer rolled its offer back and answered.
+		if a.LocalDescription().Type != "answer" || b.LocalDescription().Type != "offer" {
+			t.Fatalf("polite %s, impolite %s", a.LocalDescription().Type, b.LocalDescription().Type)
+		}
+	}
+}
+
+func TestNegotiatorConcurrent(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		a, b, errc := negotiatedPair(t, func(p, ip *Negotiator) {})
+		got := make(chan *DataChannel, 2)
+		a.OnDataChannel(func(c *DataChannel) { got <- c })
+		b.OnDataChannel(func(c *DataChannel) { got <- c })
+		// both sides need a negotiation at the same time.
+		if _, err := a.CreateDataChannel("a"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := b.CreateDataChannel("b"); err != nil {
+			t.Fatal(err)
+		}
+		waitConnected(t, errc, a, b)
+		labels := map[string]bool{}
+		for len(labels) < 2 {
+			select {
+			case c := <-got:
+				labels[c.Label()] = true
+			case <-time.After(5 * time.Second):
+				t.Fatalf("channels announced: %v", labels)
+			}
+		}
+	}
+}
+
+func TestNegotiatorWithoutRollback(t *testing.T) {
+	defer func(v bool) { canRollback = v }(canRollback)
+	canRollback = false
+	a, b, errc := negotiatedPair(t, func(p, ip *Negotiator) {
+		p.Negotiate()
+		ip.Negotiate()
+	})
+	waitConnected(t, errc, a, b)
+	if a.LocalDescription().Type != "answer" {
+		t.Fatalf("polite peer made an %s without rollback", a.LocalDescription().Type)
+	}
+}
+
+// offers counts the offers pc sets locally from now on.
+func offers(pc *PeerConnection) func() int {
+	events := pc.Events()
+	var mu sync.Mutex
+	n := 0
+	go func() {
+		for ev := range events {
+			if ev, ok := ev.(SignalingStateChangeEvent); ok && ev.State == SignalingStateHaveLocalOffer {
+				mu.Lock()
+				n++
+				mu.Unlock()
+			}
+		}
+	}()
+	return func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return n
+	}
+}
+
+func TestNegotiatorRenegotiate(t *testing.T) {
+	a, b, errc := negotiatedPair(t, func(p, ip *Negotiator) {})
+	if _, err := a.CreateDataChannel("up"); err != nil {
+		t.Fatal(err)
+	}
+	waitConnected(t, errc, a, b)
+	offersA, offersB := offers(a), offers(b)
+	tracks := make(chan *MediaStreamTrack, 1)
+	b.OnTrack(func(track *MediaStreamTrack, _ []*MediaStream) { tracks <- track })
+	track, err := NewSampleTrack("cam", "video/VP8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AddTrack(track); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-tracks:
+	case err := <-errc:
+		t.Fatalf("negotiation failed: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("track not negotiated")
+	}
+	waitConnected(t, errc, a, b)
+	if n, m := offersA(), offersB(); n != 1 || m != 0 {
+		t.Fatalf("%d offers from the peer adding the track, %d from the other", n, m)
+	}
+}
+
+func TestNegotiatorRenegotiateBoth(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		a, b, errc := negotiatedPair(t, func(p, ip *Negotiator) {})
+		tr, err := a.AddTransceiver("audio", RTPTransceiverDirectionRecvonly)
+		if err != nil {
+			t.Fatal(err)
+		}
+		waitConnected(t, errc, a, b)
+		if tr.Mid() == "" {
+			t.Fatal("transceiver without mid")
+		}
+		// both sides change at once in the call.
+		got := make(chan *DataChannel, 1)
+		a.OnDataChannel(func(c *DataChannel) { got <- c })
+		tracks := make(chan *MediaStreamTrack, 1)
+		a.OnTrack(func(track *MediaStreamTrack, _ []*MediaStream) { tracks <- track })
+		track, err := NewSampleTrack("mic", "audio/opus")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := b.AddTrack(track); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := b.CreateDataChannel("late"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := a.AddTransceiver("video", RTPTransceiverDirectionSendrecv); err != nil {
+			t.Fatal(err)
+		}
+		for got != nil || tracks != nil {
+			select {
+			case <-got:
+				got = nil
+			case <-tracks:
+				tracks = nil
+			case err := <-errc:
+				t.Fatalf("negotiation failed: %v", err)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("channel %v, track %v not negotiated", got == nil, tracks == nil)
+			}
+		}
+		waitConnected(t, errc, a, b)
+		// the track arrives on the recvonly transceiver of its kind.
+		if rt := tr.Receiver().Track(); rt == nil || rt.ID() != "mic" {
+			t.Fatalf("receiver track %v", rt)
+		}
+	}
+}
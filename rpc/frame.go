@@ -0,0 +1,56 @@
+package rpc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// frame kinds
+const (
+	kindRequest byte = iota + 1
+	kindStream
+	kindResponse
+	kindError
+	kindCancel
+)
+
+// frame is one message: kind, call id, method and body. The body of
+// kindError is the error text; the others carry codec data.
+type frame struct {
+	kind   byte
+	id     uint64
+	method string
+	body   []byte
+}
+
+const frameHeaderSize = 1 + 8 + 2
+
+func (f *frame) marshal() ([]byte, error) {
+	if len(f.method) > math.MaxUint16 {
+		return nil, ErrMethodTooLong
+	}
+	b := make([]byte, frameHeaderSize+len(f.method)+len(f.body))
+	b[0] = f.kind
+	binary.BigEndian.PutUint64(b[1:], f.id)
+	binary.BigEndian.PutUint16(b[9:], uint16(len(f.method)))
+	n := copy(b[frameHeaderSize:], f.method)
+	copy(b[frameHeaderSize+n:], f.body)
+	return b, nil
+}
+
+func parseFrame(b []byte) (*frame, error) {
+	if len(b) < frameHeaderSize {
+		return nil, fmt.Errorf("rpc: short frame of %d bytes", len(b))
+	}
+	n := int(binary.BigEndian.Uint16(b[9:]))
+	if len(b) < frameHeaderSize+n {
+		return nil, fmt.Errorf("rpc: truncated method name")
+	}
+	return &frame{
+		kind:   b[0],
+		id:     binary.BigEndian.Uint64(b[1:]),
+		method: string(b[frameHeaderSize : frameHeaderSize+n]),
+		body:   b[frameHeaderSize+n:],
+	}, nil
+}
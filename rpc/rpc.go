@@ -0,0 +1,421 @@
+// Package rpc provides request/response calls between two peers over a
+// webrtc.DataChannel.
+//
+// Each side wraps its end of the channel in a Conn, registers handlers
+// with Handle or HandleStream and calls the other side with Call or
+// Stream. Calls are multiplexed by ID, so any number may run concurrently,
+// and cancelling the context of a call cancels the handler on the peer.
+// Messages go through the framed mode of the DataChannel, so arguments and
+// results are not limited by the SCTP message size.
+//
+//	conn := rpc.NewConn(dc, rpc.JSON)
+//	conn.Handle("add", func(ctx context.Context, req *rpc.Request) (interface{}, error) {
+//		var args [2]int
+//		if err := req.Decode(&args); err != nil {
+//			return nil, err
+//		}
+//		return args[0] + args[1], nil
+//	})
+//
+//	var sum int
+//	err := conn.Call(ctx, "add", [2]int{1, 2}, &sum)
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/nobonobo/webrtc"
+)
+
+// ErrClosed ...
+var ErrClosed = errors.New("rpc: connection closed")
+
+// ErrMethodTooLong is returned for a method name longer than the 65535
+// bytes a frame can hold.
+var ErrMethodTooLong = errors.New("rpc: method name too long")
+
+// ServerError is an error returned by the handler on the peer.
+type ServerError string
+
+func (e ServerError) Error() string {
+	return string(e)
+}
+
+// Request is an incoming call.
+type Request struct {
+	Method string
+	body   []byte
+	codec  Codec
+}
+
+// Decode decodes the call arguments into v.
+func (r *Request) Decode(v interface{}) error {
+	if len(r.body) == 0 {
+		return nil
+	}
+	return r.codec.Unmarshal(r.body, v)
+}
+
+// Handler answers a call with one result.
+type Handler func(ctx context.Context, req *Request) (interface{}, error)
+
+// StreamHandler answers a call with any number of results passed to send.
+type StreamHandler func(ctx context.Context, req *Request, send func(v interface{}) error) error
+
+// transport is implemented by *webrtc.FramedChannel.
+type transport interface {
+	Send(ctx context.Context, data []byte) error
+	Recv(ctx context.Context) (webrtc.Message, error)
+	Close() error
+}
+
+// Conn is one end of an RPC connection. Both ends can serve and call.
+type Conn struct {
+	t     transport
+	codec Codec
+
+	mu       sync.Mutex
+	handlers map[string]StreamHandler
+	nextID   uint64
+	calls    map[uint64]*call
+	running  map[uint64]context.CancelFunc
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewConn starts RPC over dc, switching it to framed mode.
+func NewConn(dc *webrtc.DataChannel, codec Codec) *Conn {
+	return newConn(dc.Framed(0), codec)
+}
+
+func newConn(t transport, codec Codec) *Conn {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Conn{
+		t:        t,
+		codec:    codec,
+		handlers: map[string]StreamHandler{},
+		calls:    map[uint64]*call{},
+		running:  map[uint64]context.CancelFunc{},
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	go c.loop()
+	return c
+}
+
+// Handle registers the handler for method. It returns ErrMethodTooLong if
+// method can't be sent in a frame.
+func (c *Conn) Handle(method string, h Handler) error {
+	return c.HandleStream(method, func(ctx context.Context, req *Request, send func(interface{}) error) error {
+		v, err := h(ctx, req)
+		if err != nil {
+			return err
+		}
+		if v == nil {
+			return nil
+		}
+		return send(v)
+	})
+}
+
+// HandleStream registers the streaming handler for method. It returns
+// ErrMethodTooLong if method can't be sent in a frame.
+func (c *Conn) HandleStream(method string, h StreamHandler) error {
+	if len(method) > math.MaxUint16 {
+		return ErrMethodTooLong
+	}
+	c.mu.Lock()
+	c.handlers[method] = h
+	c.mu.Unlock()
+	return nil
+}
+
+// Done is closed when the connection ends.
+func (c *Conn) Done() <-chan struct{} {
+	return c.ctx.Done()
+}
+
+// Close ends the connection, cancelling running handlers and pending calls.
+// The DataChannel stays open.
+func (c *Conn) Close() error {
+	c.shutdown()
+	return c.t.Close()
+}
+
+func (c *Conn) shutdown() {
+	c.cancel()
+	c.mu.Lock()
+	calls := c.calls
+	c.calls = map[uint64]*call{}
+	c.mu.Unlock()
+	for _, cl := range calls {
+		cl.finish(ErrClosed)
+	}
+}
+
+func (c *Conn) send(f *frame) error {
+	b, err := f.marshal()
+	if err != nil {
+		return err
+	}
+	return c.t.Send(c.ctx, b)
+}
+
+func (c *Conn) loop() {
+	defer c.shutdown()
+	for {
+		m, err := c.t.Recv(c.ctx)
+		if err != nil {
+			return
+		}
+		f, err := parseFrame(m.Data)
+		if err != nil {
+			continue
+		}
+		switch f.kind {
+		case kindRequest:
+			c.serve(f)
+		case kindCancel:
+			c.mu.Lock()
+			cancel := c.running[f.id]
+			c.mu.Unlock()
+			if cancel != nil {
+				cancel()
+			}
+		case kindStream, kindResponse, kindError:
+			c.mu.Lock()
+			cl := c.calls[f.id]
+			if f.kind != kindStream {
+				delete(c.calls, f.id)
+			}
+			c.mu.Unlock()
+			if cl == nil {
+				continue
+			}
+			switch f.kind {
+			case kindStream:
+				cl.push(f.body)
+			case kindResponse:
+				cl.finish(io.EOF)
+			case kindError:
+				cl.finish(ServerError(f.body))
+			}
+		}
+	}
+}
+
+func (c *Conn) serve(f *frame) {
+	c.mu.Lock()
+	h, ok := c.handlers[f.method]
+	ctx, cancel := context.WithCancel(c.ctx)
+	c.running[f.id] = cancel
+	c.mu.Unlock()
+	go func() {
+		defer func() {
+			c.mu.Lock()
+			delete(c.running, f.id)
+			c.mu.Unlock()
+			cancel()
+		}()
+		var err error
+		if !ok {
+			err = errors.New("rpc: can't find method " + f.method)
+		} else {
+			req := &Request{Method: f.method, body: f.body, codec: c.codec}
+			err = runHandler(ctx, h, req, func(v interface{}) error {
+				b, err := c.codec.Marshal(v)
+				if err != nil {
+					return err
+				}
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				return c.send(&frame{kind: kindStream, id: f.id, body: b})
+			})
+		}
+		if err != nil {
+			c.send(&frame{kind: kindError, id: f.id, body: []byte(err.Error())})
+			return
+		}
+		c.send(&frame{kind: kindResponse, id: f.id})
+	}()
+}
+
+// runHandler runs h and returns a panic in it as an error, so that the
+// caller gets an error response instead of the process crashing.
+func runHandler(ctx context.Context, h StreamHandler, req *Request, send func(interface{}) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("rpc: %s: panic: %v", req.Method, r)
+		}
+	}()
+	return h(ctx, req, send)
+}
+
+// Call calls method on the peer and decodes the result into reply, which
+// may be nil to discard it.
+func (c *Conn) Call(ctx context.Context, method string, args, reply interface{}) error {
+	st, err := c.Stream(ctx, method, args)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	var target interface{} = reply
+	for {
+		err := st.Recv(target)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// a unary handler sends at most one result; keep only the first.
+		target = nil
+	}
+}
+
+// Stream calls method on the peer and returns the stream of its results.
+func (c *Conn) Stream(ctx context.Context, method string, args interface{}) (*Stream, error) {
+	if len(method) > math.MaxUint16 {
+		return nil, ErrMethodTooLong
+	}
+	var body []byte
+	if args != nil {
+		b, err := c.codec.Marshal(args)
+		if err != nil {
+			return nil, err
+		}
+		body = b
+	}
+	c.mu.Lock()
+	if c.ctx.Err() != nil {
+		c.mu.Unlock()
+		return nil, ErrClosed
+	}
+	c.nextID++
+	id := c.nextID
+	cl := newCall()
+	c.calls[id] = cl
+	c.mu.Unlock()
+	st := &Stream{c: c, id: id, call: cl, ctx: ctx}
+	b, err := (&frame{kind: kindRequest, id: id, method: method, body: body}).marshal()
+	if err == nil {
+		err = c.t.Send(ctx, b)
+	}
+	if err != nil {
+		st.abort()
+		return nil, err
+	}
+	return st, nil
+}
+
+// Stream is the result stream of a call.
+type Stream struct {
+	c    *Conn
+	id   uint64
+	call *call
+	ctx  context.Context
+	once sync.Once
+}
+
+// Recv decodes the next result into v, which may be nil to skip it. It
+// returns io.EOF after the last result.
+func (st *Stream) Recv(v interface{}) error {
+	body, err := st.call.next(st.ctx)
+	if err != nil {
+		if err == st.ctx.Err() {
+			st.abort()
+		}
+		return err
+	}
+	if v == nil {
+		return nil
+	}
+	return st.c.codec.Unmarshal(body, v)
+}
+
+// Close cancels the call on the peer unless it has finished.
+func (st *Stream) Close() error {
+	st.abort()
+	return nil
+}
+
+func (st *Stream) abort() {
+	st.once.Do(func() {
+		st.c.mu.Lock()
+		_, pending := st.c.calls[st.id]
+		delete(st.c.calls, st.id)
+		st.c.mu.Unlock()
+		if pending {
+			st.call.finish(context.Canceled)
+			st.c.send(&frame{kind: kindCancel, id: st.id})
+		}
+	})
+}
+
+// call queues the results of a call without bound, so that a slow reader
+// does not block the connection.
+type call struct {
+	mu     sync.Mutex
+	items  [][]byte
+	err    error
+	notify chan struct{}
+}
+
+func newCall() *call {
+	return &call{notify: make(chan struct{}, 1)}
+}
+
+func (cl *call) wake() {
+	select {
+	case cl.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (cl *call) push(body []byte) {
+	cl.mu.Lock()
+	if cl.err == nil {
+		cl.items = append(cl.items, body)
+	}
+	cl.mu.Unlock()
+	cl.wake()
+}
+
+// finish ends the results with err, io.EOF for success.
+func (cl *call) finish(err error) {
+	cl.mu.Lock()
+	if cl.err == nil {
+		cl.err = err
+	}
+	cl.mu.Unlock()
+	cl.wake()
+}
+
+func (cl *call) next(ctx context.Context) ([]byte, error) {
+	for {
+		cl.mu.Lock()
+		if len(cl.items) > 0 {
+			body := cl.items[0]
+			cl.items = cl.items[1:]
+			cl.mu.Unlock()
+			return body, nil
+		}
+		err := cl.err
+		cl.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		select {
+		case <-cl.notify:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nobonobo/webrtc"
+)
+
+// pipe is an in-memory transport.
+type pipe struct {
+	in   <-chan []byte
+	out  chan<- []byte
+	once sync.Once
+	done chan struct{}
+	peer *pipe
+}
+
+func pipes() (*pipe, *pipe) {
+	ab := make(chan []byte, 64)
+	ba := make(chan []byte, 64)
+	a := &pipe{in: ba, out: ab, done: make(chan struct{})}
+	b := &pipe{in: ab, out: ba, done: make(chan struct{})}
+	a.peer, b.peer = b, a
+	return a, b
+}
+
+func (p *pipe) Send(ctx context.Context, data []byte) error {
+	select {
+	case p.out <- append([]byte(nil), data...):
+		return nil
+	case <-p.done:
+		return io.ErrClosedPipe
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *pipe) Recv(ctx context.Context) (webrtc.Message, error) {
+	select {
+	case b := <-p.in:
+		return webrtc.Message{Data: b}, nil
+	case <-p.done:
+		return webrtc.Message{}, io.EOF
+	case <-p.peer.done:
+		return webrtc.Message{}, io.EOF
+	case <-ctx.Done():
+		return webrtc.Message{}, ctx.Err()
+	}
+}
+
+func (p *pipe) Close() error {
+	p.once.Do(func() { close(p.done) })
+	return nil
+}
+
+func conns(t *testing.T) (*Conn, *Conn) {
+	a, b := pipes()
+	client, server := newConn(a, JSON), newConn(b, JSON)
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return client, server
+}
+
+func TestCall(t *testing.T) {
+	client, server := conns(t)
+	server.Handle("add", func(ctx context.Context, req *Request) (interface{}, error) {
+		var args [2]int
+		if err := req.Decode(&args); err != nil {
+			return nil, err
+		}
+		return args[0] + args[1], nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var sum int
+	if err := client.Call(ctx, "add", [2]int{1, 2}, &sum); err != nil || sum != 3 {
+		t.Fatalf("got %d, %v", sum, err)
+	}
+	err := client.Call(ctx, "sub", nil, nil)
+	if _, ok := err.(ServerError); !ok {
+		t.Fatalf("unknown method: %v", err)
+	}
+}
+
+func TestStream(t *testing.T) {
+	client, server := conns(t)
+	server.HandleStream("count", func(ctx context.Context, req *Request, send func(interface{}) error) error {
+		var n int
+		if err := req.Decode(&n); err != nil {
+			return err
+		}
+		for i := 0; i < n; i++ {
+			if err := send(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	st, err := client.Stream(ctx, "count", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	for i := 0; ; i++ {
+		var v int
+		err := st.Recv(&v)
+		if err == io.EOF {
+			if i != 100 {
+				t.Fatalf("%d results, want 100", i)
+			}
+			break
+		}
+		if err != nil || v != i {
+			t.Fatalf("result %d: %d, %v", i, v, err)
+		}
+	}
+}
+
+func TestPanic(t *testing.T) {
+	client, server := conns(t)
+	server.Handle("boom", func(ctx context.Context, req *Request) (interface{}, error) {
+		panic("boom")
+	})
+	server.HandleStream("boom stream", func(ctx context.Context, req *Request, send func(interface{}) error) error {
+		send(1)
+		var m map[string]int
+		m["x"] = 1
+		return nil
+	})
+	server.Handle("ok", func(ctx context.Context, req *Request) (interface{}, error) {
+		return "ok", nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, method := range []string{"boom", "boom stream"} {
+		err := client.Call(ctx, method, nil, nil)
+		if _, ok := err.(ServerError); !ok || !strings.Contains(err.Error(), "panic") {
+			t.Fatalf("%s: %v", method, err)
+		}
+	}
+	// the connection survives.
+	var s string
+	if err := client.Call(ctx, "ok", nil, &s); err != nil || s != "ok" {
+		t.Fatalf("after panic: %q, %v", s, err)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	client, server := conns(t)
+	cancelled := make(chan struct{})
+	server.Handle("wait", func(ctx context.Context, req *Request) (interface{}, error) {
+		<-ctx.Done()
+		close(cancelled)
+		return nil, ctx.Err()
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := client.Call(ctx, "wait", nil, nil); err != context.DeadlineExceeded {
+		t.Fatalf("got %v", err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler not cancelled")
+	}
+}
+
+func TestClose(t *testing.T) {
+	client, server := conns(t)
+	started := make(chan struct{})
+	server.Handle("wait", func(ctx context.Context, req *Request) (interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	errc := make(chan error, 1)
+	go func() { errc <- client.Call(context.Background(), "wait", nil, nil) }()
+	<-started
+	client.Close()
+	if err := <-errc; err != ErrClosed {
+		t.Fatalf("pending call: %v", err)
+	}
+	if err := client.Call(context.Background(), "wait", nil, nil); err != ErrClosed {
+		t.Fatalf("call after close: %v", err)
+	}
+}
+
+func TestMethodTooLong(t *testing.T) {
+	client, server := conns(t)
+	long := strings.Repeat("m", math.MaxUint16+1)
+	h := func(ctx context.Context, req *Request) (interface{}, error) { return "ok", nil }
+	if err := server.Handle(long, h); err != ErrMethodTooLong {
+		t.Fatalf("Handle: %v", err)
+	}
+	if _, err := (&frame{kind: kindRequest, method: long}).marshal(); err != ErrMethodTooLong {
+		t.Fatalf("marshal: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Call(ctx, long, nil, nil); err != ErrMethodTooLong {
+		t.Fatalf("Call: %v", err)
+	}
+
+	// the longest name that fits still works.
+	max := long[:math.MaxUint16]
+	if err := server.Handle(max, h); err != nil {
+		t.Fatal(err)
+	}
+	var s string
+	if err := client.Call(ctx, max, nil, &s); err != nil || s != "ok" {
+		t.Fatalf("longest name: %q, %v", s, err)
+	}
+}
@@ -0,0 +1,100 @@
+package sdp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Candidate is an ICE candidate (RFC 5245 section 15.1).
+type Candidate struct {
+	Foundation     string
+	Component      int
+	Protocol       string
+	Priority       uint32
+	Address        string
+	Port           int
+	Type           string
+	RelatedAddress string
+	RelatedPort    int
+	TCPType        string
+	Extensions     []Attribute
+}
+
+// ParseCandidate parses a candidate in either "candidate:..." form, as
+// carried by IceCandidate, or the bare a=candidate attribute value.
+func ParseCandidate(v string) (*Candidate, error) {
+	v = strings.TrimPrefix(v, "a=")
+	v = strings.TrimPrefix(v, "candidate:")
+	f := strings.Fields(v)
+	if len(f) < 8 || f[6] != "typ" {
+		return nil, fmt.Errorf("sdp: malformed candidate %q", v)
+	}
+	c := &Candidate{
+		Foundation: f[0],
+		Protocol:   strings.ToLower(f[2]),
+		Address:    f[4],
+		Type:       f[7],
+	}
+	var err error
+	if c.Component, err = strconv.Atoi(f[1]); err != nil {
+		return nil, fmt.Errorf("sdp: malformed candidate component %q", f[1])
+	}
+	prio, err := strconv.ParseUint(f[3], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("sdp: malformed candidate priority %q", f[3])
+	}
+	c.Priority = uint32(prio)
+	if c.Port, err = strconv.Atoi(f[5]); err != nil {
+		return nil, fmt.Errorf("sdp: malformed candidate port %q", f[5])
+	}
+	rest := f[8:]
+	for len(rest) >= 2 {
+		k, val := rest[0], rest[1]
+		rest = rest[2:]
+		switch k {
+		case "raddr":
+			c.RelatedAddress = val
+		case "rport":
+			if c.RelatedPort, err = strconv.Atoi(val); err != nil {
+				return nil, fmt.Errorf("sdp: malformed candidate rport %q", val)
+			}
+		case "tcptype":
+			c.TCPType = val
+		default:
+			c.Extensions = append(c.Extensions, Attribute{Key: k, Value: val})
+		}
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("sdp: malformed candidate %q", v)
+	}
+	return c, nil
+}
+
+// Value returns the candidate as an a=candidate attribute value.
+func (c *Candidate) Value() string {
+	f := []string{
+		c.Foundation,
+		strconv.Itoa(c.Component),
+		c.Protocol,
+		strconv.FormatUint(uint64(c.Priority), 10),
+		c.Address,
+		strconv.Itoa(c.Port),
+		"typ", c.Type,
+	}
+	if c.RelatedAddress != "" {
+		f = append(f, "raddr", c.RelatedAddress, "rport", strconv.Itoa(c.RelatedPort))
+	}
+	if c.TCPType != "" {
+		f = append(f, "tcptype", c.TCPType)
+	}
+	for _, e := range c.Extensions {
+		f = append(f, e.Key, e.Value)
+	}
+	return strings.Join(f, " ")
+}
+
+// String returns the candidate in "candidate:..." form.
+func (c *Candidate) String() string {
+	return "candidate:" + c.Value()
+}
@@ -0,0 +1,329 @@
+// Package sdp parses and writes Session Description Protocol (RFC 4566)
+// messages as used by WebRTC.
+//
+// A Session keeps every line in its original order and form, so a parsed
+// description is written back byte-for-byte unless it is edited. Typed
+// values such as Origin, MediaName, Codec or Candidate are parsed on demand
+// by accessors, and setters rewrite only the lines they touch.
+package sdp
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Line is one "<type>=<value>" line.
+type Line struct {
+	Type  byte
+	Value string
+	eol   string
+}
+
+// String ...
+func (l *Line) String() string {
+	return string(l.Type) + "=" + l.Value
+}
+
+// Section is a list of lines sharing a scope: the session level or one media
+// description.
+type Section struct {
+	Lines []*Line
+}
+
+// Media is a media description, starting with its "m=" line.
+type Media struct {
+	Section
+}
+
+// Session is a parsed session description.
+type Session struct {
+	Section
+	Media []*Media
+
+	eol     string
+	noFinal bool
+}
+
+// Parse ...
+func Parse(b []byte) (*Session, error) {
+	s := &Session{eol: "\r\n"}
+	var cur *Section = &s.Section
+	lineno := 0
+	first := true
+	for len(b) > 0 {
+		lineno++
+		var raw []byte
+		eol := ""
+		if i := bytes.IndexByte(b, '\n'); i >= 0 {
+			raw, b = b[:i], b[i+1:]
+			eol = "\n"
+			if len(raw) > 0 && raw[len(raw)-1] == '\r' {
+				raw = raw[:len(raw)-1]
+				eol = "\r\n"
+			}
+		} else {
+			raw, b = b, nil
+			s.noFinal = true
+		}
+		if len(raw) < 2 || raw[1] != '=' || raw[0] < 'a' || raw[0] > 'z' {
+			return nil, fmt.Errorf("sdp: line %d: malformed line %q", lineno, raw)
+		}
+		if first {
+			if raw[0] != 'v' {
+				return nil, fmt.Errorf("sdp: line %d: description must start with v=", lineno)
+			}
+			if eol != "" {
+				s.eol = eol
+			}
+			first = false
+		}
+		l := &Line{Type: raw[0], Value: string(raw[2:]), eol: eol}
+		if l.Type == 'm' {
+			m := &Media{}
+			s.Media = append(s.Media, m)
+			cur = &m.Section
+		}
+		cur.Lines = append(cur.Lines, l)
+	}
+	if first {
+		return nil, fmt.Errorf("sdp: empty description")
+	}
+	return s, nil
+}
+
+// ParseString ...
+func ParseString(s string) (*Session, error) {
+	return Parse([]byte(s))
+}
+
+// Marshal writes the session back to its text form.
+func (s *Session) Marshal() []byte {
+	var buf bytes.Buffer
+	lines := s.Lines
+	for _, m := range s.Media {
+		lines = append(lines[:len(lines):len(lines)], m.Lines...)
+	}
+	for i, l := range lines {
+		buf.WriteByte(l.Type)
+		buf.WriteByte('=')
+		buf.WriteString(l.Value)
+		switch {
+		case i == len(lines)-1 && s.noFinal:
+		case l.eol != "":
+			buf.WriteString(l.eol)
+		default:
+			buf.WriteString(s.eol)
+		}
+	}
+	return buf.Bytes()
+}
+
+// String ...
+func (s *Session) String() string {
+	return string(s.Marshal())
+}
+
+// AddMedia appends a media description built from name.
+func (s *Session) AddMedia(name *MediaName) *Media {
+	m := &Media{}
+	m.Lines = []*Line{{Type: 'm', Value: name.String()}}
+	s.Media = append(s.Media, m)
+	return m
+}
+
+// RemoveMedia ...
+func (s *Session) RemoveMedia(m *Media) {
+	for i, v := range s.Media {
+		if v == m {
+			s.Media = append(s.Media[:i], s.Media[i+1:]...)
+			return
+		}
+	}
+}
+
+// MediaByMid returns the media description with the given a=mid, or nil.
+func (s *Session) MediaByMid(mid string) *Media {
+	for _, m := range s.Media {
+		if v, ok := m.Attribute("mid"); ok && v == mid {
+			return m
+		}
+	}
+	return nil
+}
+
+// Version ...
+func (s *Session) Version() string {
+	v, _ := s.Value('v')
+	return v
+}
+
+// Origin ...
+func (s *Session) Origin() (*Origin, error) {
+	v, ok := s.Value('o')
+	if !ok {
+		return nil, fmt.Errorf("sdp: missing o= line")
+	}
+	return ParseOrigin(v)
+}
+
+// SetOrigin ...
+func (s *Session) SetOrigin(o *Origin) {
+	s.SetValue('o', o.String())
+}
+
+// Name returns the s= line.
+func (s *Session) Name() string {
+	v, _ := s.Value('s')
+	return v
+}
+
+// Fingerprint returns the DTLS fingerprint of m, falling back to the session
+// level one.
+func (s *Session) Fingerprint(m *Media) (*Fingerprint, bool) {
+	if m != nil {
+		if fp, ok := m.Fingerprint(); ok {
+			return fp, ok
+		}
+	}
+	return s.Section.Fingerprint()
+}
+
+// IceCredentials returns the ICE ufrag and pwd of m, falling back to the
+// session level ones.
+func (s *Session) IceCredentials(m *Media) (ufrag, pwd string) {
+	ufrag, _ = s.Attribute("ice-ufrag")
+	pwd, _ = s.Attribute("ice-pwd")
+	if m != nil {
+		if v, ok := m.Attribute("ice-ufrag"); ok {
+			ufrag = v
+		}
+		if v, ok := m.Attribute("ice-pwd"); ok {
+			pwd = v
+		}
+	}
+	return
+}
+
+// MediaName parses the m= line.
+func (m *Media) MediaName() (*MediaName, error) {
+	v, _ := m.Value('m')
+	return ParseMediaName(v)
+}
+
+// SetMediaName ...
+func (m *Media) SetMediaName(name *MediaName) {
+	m.SetValue('m', name.String())
+}
+
+// Kind returns the media type: "audio", "video", "application", ...
+func (m *Media) Kind() string {
+	v, _ := m.Value('m')
+	for i := 0; i < len(v); i++ {
+		if v[i] == ' ' {
+			return v[:i]
+		}
+	}
+	return v
+}
+
+// Mid ...
+func (m *Media) Mid() string {
+	v, _ := m.Attribute("mid")
+	return v
+}
+
+// Directions
+const (
+	SendRecv = "sendrecv"
+	SendOnly = "sendonly"
+	RecvOnly = "recvonly"
+	Inactive = "inactive"
+)
+
+// Direction returns the direction attribute, or "" if there is none.
+func (m *Media) Direction() string {
+	for _, l := range m.Lines {
+		if l.Type != 'a' {
+			continue
+		}
+		switch l.Value {
+		case SendRecv, SendOnly, RecvOnly, Inactive:
+			return l.Value
+		}
+	}
+	return ""
+}
+
+// SetDirection replaces the direction attribute.
+func (m *Media) SetDirection(dir string) {
+	for _, l := range m.Lines {
+		if l.Type != 'a' {
+			continue
+		}
+		switch l.Value {
+		case SendRecv, SendOnly, RecvOnly, Inactive:
+			l.Value = dir
+			return
+		}
+	}
+	m.AddAttribute(dir, "")
+}
+
+// Codecs returns the payload formats described by a=rtpmap and a=fmtp,
+// in m= line order.
+func (m *Media) Codecs() ([]*Codec, error) {
+	name, err := m.MediaName()
+	if err != nil {
+		return nil, err
+	}
+	byPT := map[string]*Codec{}
+	res := []*Codec{}
+	for _, f := range name.Formats {
+		c := &Codec{}
+		if _, err := fmt.Sscanf(f, "%d", &c.PayloadType); err != nil {
+			continue
+		}
+		byPT[f] = c
+		res = append(res, c)
+	}
+	for _, l := range m.Lines {
+		if l.Type != 'a' {
+			continue
+		}
+		a := ParseAttribute(l.Value)
+		pt, rest := splitFirst(a.Value, ' ')
+		c, ok := byPT[pt]
+		if !ok {
+			continue
+		}
+		switch a.Key {
+		case "rtpmap":
+			if err := c.parseRtpmap(rest); err != nil {
+				return nil, err
+			}
+		case "fmtp":
+			c.Fmtp = rest
+		case "rtcp-fb":
+			c.Feedback = append(c.Feedback, rest)
+		}
+	}
+	return res, nil
+}
+
+// Candidates parses the a=candidate attributes.
+func (m *Media) Candidates() ([]*Candidate, error) {
+	res := []*Candidate{}
+	for _, v := range m.Attributes("candidate") {
+		c, err := ParseCandidate(v)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, c)
+	}
+	return res, nil
+}
+
+// AddCandidate ...
+func (m *Media) AddCandidate(c *Candidate) {
+	m.AddAttribute("candidate", c.Value())
+}
@@ -0,0 +1,145 @@
+package sdp
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+const chromeOffer = "v=0\r\n" +
+	"o=- 4611731400430051336 2 IN IP4 127.0.0.1\r\n" +
+	"s=-\r\n" +
+	"t=0 0\r\n" +
+	"a=group:BUNDLE 0 1\r\n" +
+	"a=msid-semantic: WMS stream\r\n" +
+	"m=audio 9 UDP/TLS/RTP/SAVPF 111 0\r\n" +
+	"c=IN IP4 0.0.0.0\r\n" +
+	"a=ice-ufrag:abcd\r\n" +
+	"a=ice-pwd:0123456789abcdefghijkl\r\n" +
+	"a=fingerprint:sha-256 AA:BB:CC:DD\r\n" +
+	"a=setup:actpass\r\n" +
+	"a=mid:0\r\n" +
+	"a=sendrecv\r\n" +
+	"a=rtpmap:111 opus/48000/2\r\n" +
+	"a=fmtp:111 minptime=10;useinbandfec=1\r\n" +
+	"a=rtpmap:0 PCMU/8000\r\n" +
+	"a=candidate:1 1 udp 2122260223 192.168.1.2 54321 typ host generation 0\r\n" +
+	"m=application 9 UDP/DTLS/SCTP webrtc-datachannel\r\n" +
+	"c=IN IP4 0.0.0.0\r\n" +
+	"a=mid:1\r\n" +
+	"a=sctp-port:5000\r\n"
+
+var seeds = []string{
+	chromeOffer,
+	"v=0\no=- 1 1 IN IP4 0.0.0.0\ns=-\nt=0 0\nm=video 9 RTP/AVP 96\na=rtpmap:96 VP8/90000\na=recvonly\n",
+	"v=0\r\ns=-\r\nt=0 0",
+	"v=0\r\na=candidate:2 1 tcp 1518280447 10.0.0.1 9 typ host tcptype active\r\n",
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, in := range seeds {
+		s, err := ParseString(in)
+		if err != nil {
+			t.Fatalf("%q: %v", in, err)
+		}
+		if out := s.String(); out != in {
+			t.Errorf("round trip changed the description:\n%q\n%q", in, out)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, in := range []string{"", "s=-\r\n", "v=0\r\nbad line\r\n", "v=0\r\n\r\n"} {
+		if _, err := ParseString(in); err == nil {
+			t.Errorf("%q parsed", in)
+		}
+	}
+}
+
+func TestAccessors(t *testing.T) {
+	s, err := ParseString(chromeOffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := s.Origin()
+	if err != nil || o.SessionID != 4611731400430051336 {
+		t.Fatalf("origin %+v, %v", o, err)
+	}
+	if len(s.Media) != 2 {
+		t.Fatalf("%d media", len(s.Media))
+	}
+	audio := s.MediaByMid("0")
+	if audio == nil || audio.Kind() != "audio" || audio.Direction() != "sendrecv" {
+		t.Fatalf("audio media %v", audio)
+	}
+	codecs, err := audio.Codecs()
+	if err != nil || len(codecs) != 2 || codecs[0].Name != "opus" || codecs[0].ClockRate != 48000 {
+		t.Fatalf("codecs %+v, %v", codecs, err)
+	}
+	if ufrag, pwd := s.IceCredentials(audio); ufrag != "abcd" || pwd == "" {
+		t.Fatalf("ice credentials %q %q", ufrag, pwd)
+	}
+	audio.SetDirection("recvonly")
+	if audio.Direction() != "recvonly" {
+		t.Fatalf("direction %q", audio.Direction())
+	}
+	cands, err := audio.Candidates()
+	if err != nil || len(cands) != 1 || cands[0].Port != 54321 {
+		t.Fatalf("candidates %+v, %v", cands, err)
+	}
+	if s.MediaByMid("1").Kind() != "application" {
+		t.Fatal("data channel media not found")
+	}
+}
+
+func TestCandidate(t *testing.T) {
+	for _, in := range []string{
+		"candidate:1 1 udp 2122260223 192.168.1.2 54321 typ host generation 0",
+		"candidate:3 1 udp 1686052607 203.0.113.7 61000 typ srflx raddr 192.168.1.2 rport 54321",
+		"candidate:2 1 tcp 1518280447 10.0.0.1 9 typ host tcptype active",
+	} {
+		c, err := ParseCandidate(in)
+		if err != nil {
+			t.Fatalf("%q: %v", in, err)
+		}
+		if c.String() != in {
+			t.Errorf("candidate %q written as %q", in, c.String())
+		}
+	}
+	for _, in := range []string{"candidate:1 1 udp", "candidate:1 x udp 1 a 1 typ host", "candidate:1 1 udp 1 a 1 typ host raddr"} {
+		if _, err := ParseCandidate(in); err == nil {
+			t.Errorf("%q parsed", in)
+		}
+	}
+}
+
+// FuzzParse checks that a parsed description is written back unchanged and
+// parses again to the same Session.
+func FuzzParse(f *testing.F) {
+	for _, s := range seeds {
+		f.Add([]byte(s))
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		s, err := Parse(b)
+		if err != nil {
+			return
+		}
+		out := s.Marshal()
+		if !bytes.Equal(out, b) {
+			t.Fatalf("round trip changed the description:\n%q\n%q", b, out)
+		}
+		s2, err := Parse(out)
+		if err != nil {
+			t.Fatalf("marshaled description does not parse: %v", err)
+		}
+		if !reflect.DeepEqual(s, s2) {
+			t.Fatalf("sessions differ after round trip:\n%q", out)
+		}
+		for _, m := range s.Media {
+			m.Codecs()
+			m.Candidates()
+			m.MediaName()
+		}
+		s.Origin()
+	})
+}
@@ -0,0 +1,161 @@
+package sdp
+
+import (
+	"strings"
+)
+
+// lineOrder is the order of line types required by RFC 4566. Media
+// descriptions only use m, i, c, b, k and a, in the same relative order.
+const lineOrder = "vmosiuepcbtrzka"
+
+// Value returns the value of the first line of type t.
+func (s *Section) Value(t byte) (string, bool) {
+	for _, l := range s.Lines {
+		if l.Type == t {
+			return l.Value, true
+		}
+	}
+	return "", false
+}
+
+// Values returns the values of every line of type t.
+func (s *Section) Values(t byte) []string {
+	res := []string{}
+	for _, l := range s.Lines {
+		if l.Type == t {
+			res = append(res, l.Value)
+		}
+	}
+	return res
+}
+
+// SetValue replaces the first line of type t, or inserts one where RFC 4566
+// expects it.
+func (s *Section) SetValue(t byte, value string) {
+	for _, l := range s.Lines {
+		if l.Type == t {
+			l.Value = value
+			return
+		}
+	}
+	s.insert(&Line{Type: t, Value: value})
+}
+
+func (s *Section) insert(l *Line) {
+	order := strings.IndexByte(lineOrder, l.Type)
+	for i, v := range s.Lines {
+		if strings.IndexByte(lineOrder, v.Type) > order {
+			s.Lines = append(s.Lines[:i], append([]*Line{l}, s.Lines[i:]...)...)
+			return
+		}
+	}
+	s.Lines = append(s.Lines, l)
+}
+
+// Attribute returns the value of the first a=key attribute.
+// Flag attributes such as a=rtcp-mux have an empty value.
+func (s *Section) Attribute(key string) (string, bool) {
+	for _, l := range s.Lines {
+		if l.Type != 'a' {
+			continue
+		}
+		if a := ParseAttribute(l.Value); a.Key == key {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// Attributes returns the values of every a=key attribute.
+func (s *Section) Attributes(key string) []string {
+	res := []string{}
+	for _, l := range s.Lines {
+		if l.Type != 'a' {
+			continue
+		}
+		if a := ParseAttribute(l.Value); a.Key == key {
+			res = append(res, a.Value)
+		}
+	}
+	return res
+}
+
+// AllAttributes returns every attribute in order.
+func (s *Section) AllAttributes() []Attribute {
+	res := []Attribute{}
+	for _, l := range s.Lines {
+		if l.Type == 'a' {
+			res = append(res, ParseAttribute(l.Value))
+		}
+	}
+	return res
+}
+
+// AddAttribute appends a=key:value, or a=key if value is empty.
+func (s *Section) AddAttribute(key, value string) {
+	s.insert(&Line{Type: 'a', Value: Attribute{Key: key, Value: value}.String()})
+}
+
+// SetAttribute replaces the first a=key attribute or adds one.
+func (s *Section) SetAttribute(key, value string) {
+	for _, l := range s.Lines {
+		if l.Type != 'a' {
+			continue
+		}
+		if a := ParseAttribute(l.Value); a.Key == key {
+			a.Value = value
+			l.Value = a.String()
+			return
+		}
+	}
+	s.AddAttribute(key, value)
+}
+
+// RemoveAttribute removes every a=key attribute and reports how many were
+// removed.
+func (s *Section) RemoveAttribute(key string) int {
+	n := 0
+	lines := s.Lines[:0]
+	for _, l := range s.Lines {
+		if l.Type == 'a' && ParseAttribute(l.Value).Key == key {
+			n++
+			continue
+		}
+		lines = append(lines, l)
+	}
+	s.Lines = lines
+	return n
+}
+
+// Connection parses the c= line.
+func (s *Section) Connection() (*Connection, bool) {
+	v, ok := s.Value('c')
+	if !ok {
+		return nil, false
+	}
+	c, err := ParseConnection(v)
+	if err != nil {
+		return nil, false
+	}
+	return c, true
+}
+
+// Fingerprint parses the a=fingerprint attribute.
+func (s *Section) Fingerprint() (*Fingerprint, bool) {
+	v, ok := s.Attribute("fingerprint")
+	if !ok {
+		return nil, false
+	}
+	fp, err := ParseFingerprint(v)
+	if err != nil {
+		return nil, false
+	}
+	return fp, true
+}
+
+func splitFirst(s string, sep byte) (string, string) {
+	if i := strings.IndexByte(s, sep); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return s, ""
+}
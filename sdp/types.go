@@ -0,0 +1,191 @@
+package sdp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Attribute is an a= line split at the first colon.
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// ParseAttribute ...
+func ParseAttribute(v string) Attribute {
+	k, val := splitFirst(v, ':')
+	return Attribute{Key: k, Value: val}
+}
+
+// String ...
+func (a Attribute) String() string {
+	if a.Value == "" {
+		return a.Key
+	}
+	return a.Key + ":" + a.Value
+}
+
+// Origin is the o= line.
+type Origin struct {
+	Username       string
+	SessionID      uint64
+	SessionVersion uint64
+	NetworkType    string
+	AddressType    string
+	Address        string
+}
+
+// ParseOrigin ...
+func ParseOrigin(v string) (*Origin, error) {
+	f := strings.Split(v, " ")
+	if len(f) != 6 {
+		return nil, fmt.Errorf("sdp: malformed origin %q", v)
+	}
+	id, err := strconv.ParseUint(f[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("sdp: malformed origin session id %q", f[1])
+	}
+	ver, err := strconv.ParseUint(f[2], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("sdp: malformed origin session version %q", f[2])
+	}
+	return &Origin{
+		Username:       f[0],
+		SessionID:      id,
+		SessionVersion: ver,
+		NetworkType:    f[3],
+		AddressType:    f[4],
+		Address:        f[5],
+	}, nil
+}
+
+// String ...
+func (o *Origin) String() string {
+	return fmt.Sprintf("%s %d %d %s %s %s",
+		o.Username, o.SessionID, o.SessionVersion,
+		o.NetworkType, o.AddressType, o.Address,
+	)
+}
+
+// MediaName is the m= line.
+type MediaName struct {
+	Media    string
+	Port     int
+	NumPorts int
+	Protos   []string
+	Formats  []string
+}
+
+// ParseMediaName ...
+func ParseMediaName(v string) (*MediaName, error) {
+	f := strings.Split(v, " ")
+	if len(f) < 3 {
+		return nil, fmt.Errorf("sdp: malformed media %q", v)
+	}
+	m := &MediaName{
+		Media:   f[0],
+		Protos:  strings.Split(f[2], "/"),
+		Formats: f[3:],
+	}
+	port, num := splitFirst(f[1], '/')
+	var err error
+	if m.Port, err = strconv.Atoi(port); err != nil {
+		return nil, fmt.Errorf("sdp: malformed media port %q", f[1])
+	}
+	if num != "" {
+		if m.NumPorts, err = strconv.Atoi(num); err != nil {
+			return nil, fmt.Errorf("sdp: malformed media port %q", f[1])
+		}
+	}
+	return m, nil
+}
+
+// String ...
+func (m *MediaName) String() string {
+	port := strconv.Itoa(m.Port)
+	if m.NumPorts > 0 {
+		port += "/" + strconv.Itoa(m.NumPorts)
+	}
+	f := append([]string{m.Media, port, strings.Join(m.Protos, "/")}, m.Formats...)
+	return strings.Join(f, " ")
+}
+
+// Connection is the c= line.
+type Connection struct {
+	NetworkType string
+	AddressType string
+	Address     string
+}
+
+// ParseConnection ...
+func ParseConnection(v string) (*Connection, error) {
+	f := strings.Split(v, " ")
+	if len(f) != 3 {
+		return nil, fmt.Errorf("sdp: malformed connection %q", v)
+	}
+	return &Connection{NetworkType: f[0], AddressType: f[1], Address: f[2]}, nil
+}
+
+// String ...
+func (c *Connection) String() string {
+	return c.NetworkType + " " + c.AddressType + " " + c.Address
+}
+
+// Fingerprint is the a=fingerprint attribute value.
+type Fingerprint struct {
+	Hash  string
+	Value string
+}
+
+// ParseFingerprint ...
+func ParseFingerprint(v string) (*Fingerprint, error) {
+	h, val := splitFirst(v, ' ')
+	if h == "" || val == "" {
+		return nil, fmt.Errorf("sdp: malformed fingerprint %q", v)
+	}
+	return &Fingerprint{Hash: h, Value: val}, nil
+}
+
+// String ...
+func (fp *Fingerprint) String() string {
+	return fp.Hash + " " + fp.Value
+}
+
+// Codec is an RTP payload format of a media description.
+type Codec struct {
+	PayloadType uint8
+	Name        string
+	ClockRate   uint32
+	Channels    int
+	Fmtp        string
+	Feedback    []string
+}
+
+func (c *Codec) parseRtpmap(v string) error {
+	f := strings.Split(v, "/")
+	if len(f) < 2 {
+		return fmt.Errorf("sdp: malformed rtpmap %q", v)
+	}
+	c.Name = f[0]
+	rate, err := strconv.ParseUint(f[1], 10, 32)
+	if err != nil {
+		return fmt.Errorf("sdp: malformed rtpmap clock rate %q", v)
+	}
+	c.ClockRate = uint32(rate)
+	if len(f) > 2 {
+		if c.Channels, err = strconv.Atoi(f[2]); err != nil {
+			return fmt.Errorf("sdp: malformed rtpmap channels %q", v)
+		}
+	}
+	return nil
+}
+
+// String returns the codec in "name/rate[/channels]" form.
+func (c *Codec) String() string {
+	s := fmt.Sprintf("%s/%d", c.Name, c.ClockRate)
+	if c.Channels > 0 {
+		s += "/" + strconv.Itoa(c.Channels)
+	}
+	return s
+}
@@ -0,0 +1,214 @@
+package webrtc
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Signal types
+const (
+	SignalJoin      = "join"
+	SignalLeave     = "leave"
+	SignalOffer     = "offer"
+	SignalAnswer    = "answer"
+	SignalCandidate = "candidate"
+)
+
+// Signal is a message exchanged between peers by a Signaler.
+type Signal struct {
+	Type          string `json:"type"`
+	Sdp           string `json:"sdp,omitempty"`
+	Candidate     string `json:"candidate,omitempty"`
+	SdpMid        string `json:"sdpMid,omitempty"`
+	SdpMLineIndex int    `json:"sdpMLineIndex,omitempty"`
+}
+
+// NewDescriptionSignal ...
+func NewDescriptionSignal(sd *SessionDescription) *Signal {
+	return &Signal{Type: sd.Type, Sdp: sd.Sdp}
+}
+
+// NewCandidateSignal ...
+func NewCandidateSignal(ic *IceCandidate) *Signal {
+	return &Signal{
+		Type:          SignalCandidate,
+		Candidate:     ic.Candidate,
+		SdpMid:        ic.SdpMid,
+		SdpMLineIndex: ic.SdpMLineIndex,
+	}
+}
+
+// SessionDescription ...
+func (s *Signal) SessionDescription() *SessionDescription {
+	return NewSessionDescription(s.Type, s.Sdp)
+}
+
+// IceCandidate ...
+func (s *Signal) IceCandidate() *IceCandidate {
+	return NewIceCandidate(s.Candidate, s.SdpMid, s.SdpMLineIndex)
+}
+
+// Signaler carries Signals between two peers.
+// Send must be safe to call from any goroutine; Recv is called from one.
+type Signaler interface {
+	Send(s *Signal) error
+	Recv() (*Signal, error)
+	Close() error
+}
+
+// signalSender sends signals in order from one goroutine. It holds back
+// local candidates until the first description has been sent, so the
+// remote peer never sees a candidate before a description.
+type signalSender struct {
+	s      Signaler
+	notify chan struct{}
+	done   chan struct{}
+
+	mu      sync.Mutex
+	ready   bool
+	pending []*Signal
+	queue   []*Signal
+	err     error
+}
+
+func newSignalSender(s Signaler) *signalSender {
+	return &signalSender{
+		s:      s,
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+}
+
+// push queues sigs; it is called with ss.mu held.
+func (ss *signalSender) push(sigs ...*Signal) {
+	ss.queue = append(ss.queue, sigs...)
+	select {
+	case ss.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (ss *signalSender) candidate(ic *IceCandidate) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if !ss.ready {
+		ss.pending = append(ss.pending, NewCandidateSignal(ic))
+		return
+	}
+	ss.push(NewCandidateSignal(ic))
+}
+
+// signal queues a signal that is not held back.
+func (ss *signalSender) signal(sig *Signal) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.push(sig)
+}
+
+func (ss *signalSender) description(sd *SessionDescription) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.push(NewDescriptionSignal(sd))
+	if !ss.ready {
+		ss.ready = true
+		ss.push(ss.pending...)
+		ss.pending = nil
+	}
+}
+
+// run sends the queued signals until stop. A failed Send closes the
+// Signaler, so that Connect returns from Recv and reports the error.
+func (ss *signalSender) run() {
+	for {
+		select {
+		case <-ss.notify:
+		case <-ss.done:
+			return
+		}
+		for {
+			ss.mu.Lock()
+			if len(ss.queue) == 0 || ss.err != nil {
+				ss.mu.Unlock()
+				break
+			}
+			sig := ss.queue[0]
+			ss.queue = ss.queue[1:]
+			ss.mu.Unlock()
+			if err := ss.s.Send(sig); err != nil {
+				ss.mu.Lock()
+				ss.err = err
+				ss.mu.Unlock()
+				ss.s.Close()
+			}
+		}
+	}
+}
+
+func (ss *signalSender) stop() {
+	close(ss.done)
+}
+
+// error returns the error of the first failed Send.
+func (ss *signalSender) error() error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	return ss.err
+}
+
+// Connect runs the offer/answer and candidate exchange for pc over s.
+// The peer that receives a join signal creates the offer.
+// It blocks until s fails or is closed and returns that error.
+func Connect(pc *PeerConnection, s Signaler) error {
+	ss := newSignalSender(s)
+	go ss.run()
+	defer ss.stop()
+	events := pc.Events()
+	defer pc.Unsubscribe(events)
+	go func() {
+		for ev := range events {
+			if ev, ok := ev.(IceCandidateEvent); ok {
+				ss.candidate(ev.Candidate)
+			}
+		}
+	}()
+	for {
+		sig, err := s.Recv()
+		if err != nil {
+			if serr := ss.error(); serr != nil {
+				return fmt.Errorf("send signal: %w", serr)
+			}
+			return err
+		}
+		switch sig.Type {
+		case SignalJoin:
+			offer, err := pc.CreateOffer()
+			if err != nil {
+				return err
+			}
+			if err := pc.SetLocalDescription(offer); err != nil {
+				return err
+			}
+			ss.description(offer)
+		case SignalOffer:
+			if err := pc.SetRemoteDescription(sig.SessionDescription()); err != nil {
+				return err
+			}
+			answer, err := pc.CreateAnswer()
+			if err != nil {
+				return err
+			}
+			if err := pc.SetLocalDescription(answer); err != nil {
+				return err
+			}
+			ss.description(answer)
+		case SignalAnswer:
+			if err := pc.SetRemoteDescription(sig.SessionDescription()); err != nil {
+				return err
+			}
+		case SignalCandidate:
+			if err := pc.AddIceCandidate(sig.IceCandidate()); err != nil {
+				return fmt.Errorf("add ice candidate: %w", err)
+			}
+		}
+	}
+}
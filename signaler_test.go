@@ -0,0 +1,100 @@
+package webrtc
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder is a Signaler that records what is sent and fails on demand.
+type recorder struct {
+	mu     sync.Mutex
+	sent   []*Signal
+	fail   error
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newRecorder() *recorder {
+	return &recorder{closed: make(chan struct{})}
+}
+
+func (r *recorder) Send(s *Signal) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fail != nil {
+		return r.fail
+	}
+	r.sent = append(r.sent, s)
+	return nil
+}
+
+func (r *recorder) Recv() (*Signal, error) {
+	<-r.closed
+	return nil, ErrSignalerClosed
+}
+
+func (r *recorder) Close() error {
+	r.once.Do(func() { close(r.closed) })
+	return nil
+}
+
+func (r *recorder) signals(n int) []*Signal {
+	for i := 0; i < 100; i++ {
+		r.mu.Lock()
+		sent := append([]*Signal{}, r.sent...)
+		r.mu.Unlock()
+		if len(sent) >= n {
+			return sent
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return nil
+}
+
+func TestSignalSenderOrder(t *testing.T) {
+	r := newRecorder()
+	ss := newSignalSender(r)
+	go ss.run()
+	defer ss.stop()
+	ss.candidate(NewIceCandidate("candidate:1", "0", 0))
+	ss.candidate(NewIceCandidate("candidate:2", "0", 0))
+	ss.description(NewSessionDescription("offer", "v=0"))
+	for i := 3; i <= 20; i++ {
+		ss.candidate(NewIceCandidate("candidate:"+strconv.Itoa(i), "0", 0))
+	}
+	sent := r.signals(21)
+	if sent == nil {
+		t.Fatal("signals not sent")
+	}
+	if sent[0].Type != SignalOffer {
+		t.Fatalf("first signal %q, want offer", sent[0].Type)
+	}
+	if sent[1].Candidate != "candidate:1" || sent[2].Candidate != "candidate:2" {
+		t.Fatalf("held back candidates out of order: %q %q", sent[1].Candidate, sent[2].Candidate)
+	}
+	for i := 3; i <= 20; i++ {
+		if want := "candidate:" + strconv.Itoa(i); sent[i].Candidate != want {
+			t.Fatalf("signal %d is %q, want %q", i, sent[i].Candidate, want)
+		}
+	}
+}
+
+func TestSignalSenderError(t *testing.T) {
+	r := newRecorder()
+	r.fail = errors.New("broken pipe")
+	ss := newSignalSender(r)
+	go ss.run()
+	defer ss.stop()
+	ss.description(NewSessionDescription("offer", "v=0"))
+	select {
+	case <-r.closed:
+	case <-time.After(time.Second):
+		t.Fatal("signaler not closed after a failed send")
+	}
+	if err := ss.error(); err != r.fail {
+		t.Fatalf("error %v, want %v", err, r.fail)
+	}
+}
@@ -0,0 +1,137 @@
+// +build js,!wasm
+
+package signaling
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/gopherjs/gopherjs/js"
+	"github.com/nobonobo/webrtc"
+)
+
+// ErrClosed ...
+var ErrClosed = errors.New("signaling: connection closed")
+
+// Client ...
+type Client struct {
+	ws     *js.Object
+	mu     sync.Mutex
+	queue  []*webrtc.Signal
+	err    error
+	notify chan struct{}
+}
+
+var _ webrtc.Signaler = (*Client)(nil)
+
+// Dial connects to the signaling server at url and joins room.
+func Dial(url, room string) (c *Client, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s", r)
+			c = nil
+		}
+	}()
+	u, err := roomURL(url, room)
+	if err != nil {
+		return nil, err
+	}
+	c = &Client{
+		ws:     js.Global.Get("WebSocket").New(u),
+		notify: make(chan struct{}, 1),
+	}
+	opened := make(chan error, 1)
+	c.ws.Call("addEventListener", "open", func(ev *js.Object) {
+		select {
+		case opened <- nil:
+		default:
+		}
+	}, false)
+	c.ws.Call("addEventListener", "error", func(ev *js.Object) {
+		select {
+		case opened <- fmt.Errorf("signaling: dial %s failed", url):
+		default:
+		}
+	}, false)
+	c.ws.Call("addEventListener", "message", func(ev *js.Object) {
+		s := &webrtc.Signal{}
+		if err := json.Unmarshal([]byte(ev.Get("data").String()), s); err != nil {
+			c.push(nil, err)
+			return
+		}
+		c.push(s, nil)
+	}, false)
+	c.ws.Call("addEventListener", "close", func(ev *js.Object) {
+		select {
+		case opened <- ErrClosed:
+		default:
+		}
+		c.push(nil, ErrClosed)
+	}, false)
+	if err := <-opened; err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Client) push(s *webrtc.Signal, err error) {
+	c.mu.Lock()
+	if s != nil {
+		c.queue = append(c.queue, s)
+	}
+	if err != nil && c.err == nil {
+		c.err = err
+	}
+	c.mu.Unlock()
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Send ...
+func (c *Client) Send(s *webrtc.Signal) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s", r)
+		}
+	}()
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	c.ws.Call("send", string(b))
+	return
+}
+
+// Recv ...
+func (c *Client) Recv() (*webrtc.Signal, error) {
+	for {
+		c.mu.Lock()
+		if len(c.queue) > 0 {
+			s := c.queue[0]
+			c.queue = c.queue[1:]
+			c.mu.Unlock()
+			return s, nil
+		}
+		err := c.err
+		c.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		<-c.notify
+	}
+}
+
+// Close ...
+func (c *Client) Close() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s", r)
+		}
+	}()
+	c.ws.Call("close")
+	return
+}
@@ -0,0 +1,52 @@
+// +build !js
+
+package signaling
+
+import (
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/nobonobo/webrtc"
+)
+
+// Client ...
+type Client struct {
+	mu   sync.Mutex
+	conn *websocket.Conn
+}
+
+var _ webrtc.Signaler = (*Client)(nil)
+
+// Dial connects to the signaling server at url and joins room.
+func Dial(url, room string) (*Client, error) {
+	u, err := roomURL(url, room)
+	if err != nil {
+		return nil, err
+	}
+	conn, _, err := websocket.DefaultDialer.Dial(u, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn}, nil
+}
+
+// Send ...
+func (c *Client) Send(s *webrtc.Signal) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn.WriteJSON(s)
+}
+
+// Recv ...
+func (c *Client) Recv() (*webrtc.Signal, error) {
+	s := &webrtc.Signal{}
+	if err := c.conn.ReadJSON(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close ...
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
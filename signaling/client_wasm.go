@@ -0,0 +1,162 @@
+// +build js,wasm
+
+package signaling
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"syscall/js"
+
+	"github.com/nobonobo/webrtc"
+)
+
+// ErrClosed ...
+var ErrClosed = errors.New("signaling: connection closed")
+
+// Client ...
+type Client struct {
+	ws     js.Value
+	mu     sync.Mutex
+	queue  []*webrtc.Signal
+	err    error
+	notify chan struct{}
+	funcs  []js.Func
+}
+
+var _ webrtc.Signaler = (*Client)(nil)
+
+// Dial connects to the signaling server at url and joins room.
+func Dial(url, room string) (c *Client, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s", r)
+			c = nil
+		}
+	}()
+	u, err := roomURL(url, room)
+	if err != nil {
+		return nil, err
+	}
+	c = &Client{
+		ws:     js.Global().Get("WebSocket").New(u),
+		notify: make(chan struct{}, 1),
+	}
+	opened := make(chan error, 1)
+	c.listen("open", func(ev js.Value) {
+		select {
+		case opened <- nil:
+		default:
+		}
+	})
+	c.listen("error", func(ev js.Value) {
+		select {
+		case opened <- fmt.Errorf("signaling: dial %s failed", url):
+		default:
+		}
+	})
+	c.listen("message", func(ev js.Value) {
+		s := &webrtc.Signal{}
+		if err := json.Unmarshal([]byte(ev.Get("data").String()), s); err != nil {
+			c.push(nil, err)
+			return
+		}
+		c.push(s, nil)
+	})
+	c.listen("close", func(ev js.Value) {
+		select {
+		case opened <- ErrClosed:
+		default:
+		}
+		c.push(nil, ErrClosed)
+		c.release()
+	})
+	if err := <-opened; err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Client) listen(name string, f func(ev js.Value)) {
+	fn := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		f(args[0])
+		return nil
+	})
+	c.mu.Lock()
+	c.funcs = append(c.funcs, fn)
+	c.mu.Unlock()
+	c.ws.Call("addEventListener", name, fn, false)
+}
+
+// release frees the listeners once the socket is closed. The close event
+// is the last one a WebSocket dispatches.
+func (c *Client) release() {
+	c.mu.Lock()
+	funcs := c.funcs
+	c.funcs = nil
+	c.mu.Unlock()
+	for _, fn := range funcs {
+		fn.Release()
+	}
+}
+
+func (c *Client) push(s *webrtc.Signal, err error) {
+	c.mu.Lock()
+	if s != nil {
+		c.queue = append(c.queue, s)
+	}
+	if err != nil && c.err == nil {
+		c.err = err
+	}
+	c.mu.Unlock()
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Send ...
+func (c *Client) Send(s *webrtc.Signal) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s", r)
+		}
+	}()
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	c.ws.Call("send", string(b))
+	return
+}
+
+// Recv ...
+func (c *Client) Recv() (*webrtc.Signal, error) {
+	for {
+		c.mu.Lock()
+		if len(c.queue) > 0 {
+			s := c.queue[0]
+			c.queue = c.queue[1:]
+			c.mu.Unlock()
+			return s, nil
+		}
+		err := c.err
+		c.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		<-c.notify
+	}
+}
+
+// Close ...
+func (c *Client) Close() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s", r)
+		}
+	}()
+	c.ws.Call("close")
+	return
+}
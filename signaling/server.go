@@ -0,0 +1,143 @@
+// +build !js
+
+package signaling
+
+import (
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/nobonobo/webrtc"
+)
+
+type member struct {
+	mu   sync.Mutex
+	conn *websocket.Conn
+}
+
+func (m *member) write(messageType int, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.conn.WriteMessage(messageType, data)
+}
+
+func (m *member) writeSignal(s *webrtc.Signal) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.conn.WriteJSON(s)
+}
+
+// Server ...
+type Server struct {
+	Upgrader websocket.Upgrader
+	mu       sync.Mutex
+	rooms    map[string][]*member
+}
+
+// NewServer ...
+func NewServer() *Server {
+	return &Server{
+		Upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+		rooms: map[string][]*member{},
+	}
+}
+
+// notify writes a signal to members without holding s.mu, so that a slow
+// member does not stall the other rooms.
+func notify(members []*member, typ string) {
+	for _, m := range members {
+		if err := m.writeSignal(&webrtc.Signal{Type: typ}); err != nil {
+			log.Println("signaling:", err)
+		}
+	}
+}
+
+func (s *Server) join(room string, m *member) bool {
+	s.mu.Lock()
+	members := s.rooms[room]
+	if len(members) >= RoomSize {
+		s.mu.Unlock()
+		return false
+	}
+	others := append([]*member{}, members...)
+	s.rooms[room] = append(members, m)
+	s.mu.Unlock()
+	notify(others, webrtc.SignalJoin)
+	return true
+}
+
+func (s *Server) leave(room string, m *member) {
+	s.mu.Lock()
+	members := []*member{}
+	for _, other := range s.rooms[room] {
+		if other != m {
+			members = append(members, other)
+		}
+	}
+	if len(members) == 0 {
+		delete(s.rooms, room)
+	} else {
+		s.rooms[room] = members
+	}
+	s.mu.Unlock()
+	notify(members, webrtc.SignalLeave)
+}
+
+func (s *Server) others(room string, m *member) []*member {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := []*member{}
+	for _, other := range s.rooms[room] {
+		if other != m {
+			res = append(res, other)
+		}
+	}
+	return res
+}
+
+func (s *Server) full(room string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.rooms[room]) >= RoomSize
+}
+
+// ServeHTTP ...
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	room := r.URL.Query().Get(RoomParam)
+	if room == "" {
+		http.Error(w, "room required", http.StatusBadRequest)
+		return
+	}
+	if s.full(room) {
+		http.Error(w, "room is full", http.StatusConflict)
+		return
+	}
+	conn, err := s.Upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Println("signaling:", err)
+		return
+	}
+	defer conn.Close()
+	m := &member{conn: conn}
+	if !s.join(room, m) {
+		conn.WriteMessage(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "room is full"),
+		)
+		return
+	}
+	defer s.leave(room, m)
+	for {
+		tp, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		for _, other := range s.others(room, m) {
+			if err := other.write(tp, data); err != nil {
+				log.Println("signaling:", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// +build !js
+
+package signaling
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nobonobo/webrtc"
+)
+
+func dial(t *testing.T, url, room string) *Client {
+	t.Helper()
+	c, err := Dial(url, room)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func recv(t *testing.T, c *Client) *webrtc.Signal {
+	t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	s, err := c.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestServerRelay(t *testing.T) {
+	srv := httptest.NewServer(NewServer())
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	a := dial(t, url, "r1")
+	defer a.Close()
+	b := dial(t, url, "r1")
+	defer b.Close()
+	if s := recv(t, a); s.Type != webrtc.SignalJoin {
+		t.Fatalf("first member got %q, want join", s.Type)
+	}
+	if err := a.Send(&webrtc.Signal{Type: webrtc.SignalOffer, Sdp: "v=0"}); err != nil {
+		t.Fatal(err)
+	}
+	if s := recv(t, b); s.Type != webrtc.SignalOffer || s.Sdp != "v=0" {
+		t.Fatalf("relayed %+v", s)
+	}
+	if _, err := Dial(url, "r1"); err == nil {
+		t.Fatal("third member joined a full room")
+	}
+	b.Close()
+	if s := recv(t, a); s.Type != webrtc.SignalLeave {
+		t.Fatalf("got %q, want leave", s.Type)
+	}
+}
+
+func TestServerSlowMember(t *testing.T) {
+	s := NewServer()
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	slow := dial(t, url, "slow")
+	defer slow.Close()
+	// block writes to the slow member.
+	s.mu.Lock()
+	m := s.rooms["slow"][0]
+	s.mu.Unlock()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	go func() {
+		c, err := Dial(url, "slow")
+		if err == nil {
+			defer c.Close()
+		}
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a := dial(t, url, "other")
+		defer a.Close()
+		b := dial(t, url, "other")
+		defer b.Close()
+		recv(t, a)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a slow member stalled another room")
+	}
+}
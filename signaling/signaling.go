@@ -0,0 +1,28 @@
+// Package signaling provides a WebSocket Signaler for webrtc.PeerConnection.
+//
+// A Server relays signals between the two peers of a room, and Dial
+// connects a Client to a room on such a server. Peers that share a room ID
+// can then be connected with webrtc.Connect.
+package signaling
+
+import (
+	"net/url"
+)
+
+const (
+	// RoomParam is the query parameter that selects the room.
+	RoomParam = "room"
+	// RoomSize is the number of peers a room accepts.
+	RoomSize = 2
+)
+
+func roomURL(rawurl, room string) (string, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set(RoomParam, room)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
@@ -0,0 +1,203 @@
+package webrtc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// normalizeState folds the W3C form ("have-local-offer") and the go-webrtc
+// form ("HaveLocalOffer") of a state name to one key.
+func normalizeState(s string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", "_", "").Replace(s))
+}
+
+func parseState(kind, s string, names []string) (int, error) {
+	key := normalizeState(s)
+	for i, name := range names {
+		if i > 0 && normalizeState(name) == key {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown %s: %q", kind, s)
+}
+
+func stateString(i int, names []string) string {
+	if i <= 0 || i >= len(names) {
+		return names[0]
+	}
+	return names[i]
+}
+
+// SignalingState ...
+type SignalingState int
+
+// SignalingState values
+const (
+	SignalingStateUnknown SignalingState = iota
+	SignalingStateStable
+	SignalingStateHaveLocalOffer
+	SignalingStateHaveLocalPrAnswer
+	SignalingStateHaveRemoteOffer
+	SignalingStateHaveRemotePrAnswer
+	SignalingStateClosed
+)
+
+var signalingStateNames = []string{
+	"unknown",
+	"stable",
+	"have-local-offer",
+	"have-local-pranswer",
+	"have-remote-offer",
+	"have-remote-pranswer",
+	"closed",
+}
+
+// ParseSignalingState ...
+func ParseSignalingState(s string) (SignalingState, error) {
+	i, err := parseState("signaling state", s, signalingStateNames)
+	return SignalingState(i), err
+}
+
+// String returns the W3C name of the state.
+func (s SignalingState) String() string {
+	return stateString(int(s), signalingStateNames)
+}
+
+// IceConnectionState ...
+type IceConnectionState int
+
+// IceConnectionState values
+const (
+	IceConnectionStateUnknown IceConnectionState = iota
+	IceConnectionStateNew
+	IceConnectionStateChecking
+	IceConnectionStateConnected
+	IceConnectionStateCompleted
+	IceConnectionStateFailed
+	IceConnectionStateDisconnected
+	IceConnectionStateClosed
+)
+
+var iceConnectionStateNames = []string{
+	"unknown",
+	"new",
+	"checking",
+	"connected",
+	"completed",
+	"failed",
+	"disconnected",
+	"closed",
+}
+
+// ParseIceConnectionState ...
+func ParseIceConnectionState(s string) (IceConnectionState, error) {
+	i, err := parseState("ice connection state", s, iceConnectionStateNames)
+	return IceConnectionState(i), err
+}
+
+// String returns the W3C name of the state.
+func (s IceConnectionState) String() string {
+	return stateString(int(s), iceConnectionStateNames)
+}
+
+// IceGatheringState ...
+type IceGatheringState int
+
+// IceGatheringState values
+const (
+	IceGatheringStateUnknown IceGatheringState = iota
+	IceGatheringStateNew
+	IceGatheringStateGathering
+	IceGatheringStateComplete
+)
+
+var iceGatheringStateNames = []string{
+	"unknown",
+	"new",
+	"gathering",
+	"complete",
+}
+
+// ParseIceGatheringState ...
+func ParseIceGatheringState(s string) (IceGatheringState, error) {
+	i, err := parseState("ice gathering state", s, iceGatheringStateNames)
+	return IceGatheringState(i), err
+}
+
+// String returns the W3C name of the state.
+func (s IceGatheringState) String() string {
+	return stateString(int(s), iceGatheringStateNames)
+}
+
+// PeerConnectionState ...
+type PeerConnectionState int
+
+// PeerConnectionState values
+const (
+	PeerConnectionStateUnknown PeerConnectionState = iota
+	PeerConnectionStateNew
+	PeerConnectionStateConnecting
+	PeerConnectionStateConnected
+	PeerConnectionStateDisconnected
+	PeerConnectionStateFailed
+	PeerConnectionStateClosed
+)
+
+var peerConnectionStateNames = []string{
+	"unknown",
+	"new",
+	"connecting",
+	"connected",
+	"disconnected",
+	"failed",
+	"closed",
+}
+
+// ParsePeerConnectionState ...
+func ParsePeerConnectionState(s string) (PeerConnectionState, error) {
+	i, err := parseState("peer connection state", s, peerConnectionStateNames)
+	return PeerConnectionState(i), err
+}
+
+// String returns the W3C name of the state.
+func (s PeerConnectionState) String() string {
+	return stateString(int(s), peerConnectionStateNames)
+}
+
+// RTPTransceiverDirection ...
+type RTPTransceiverDirection int
+
+// RTPTransceiverDirection values
+const (
+	RTPTransceiverDirectionUnknown RTPTransceiverDirection = iota
+	RTPTransceiverDirectionSendrecv
+	RTPTransceiverDirectionSendonly
+	RTPTransceiverDirectionRecvonly
+	RTPTransceiverDirectionInactive
+	RTPTransceiverDirectionStopped
+)
+
+var rtpTransceiverDirectionNames = []string{
+	"unknown",
+	"sendrecv",
+	"sendonly",
+	"recvonly",
+	"inactive",
+	"stopped",
+}
+
+// ParseRTPTransceiverDirection ...
+func ParseRTPTransceiverDirection(s string) (RTPTransceiverDirection, error) {
+	i, err := parseState("transceiver direction", s, rtpTransceiverDirectionNames)
+	return RTPTransceiverDirection(i), err
+}
+
+// String returns the W3C name of the direction.
+func (d RTPTransceiverDirection) String() string {
+	return stateString(int(d), rtpTransceiverDirectionNames)
+}
+
+// sends reports whether media is sent in the direction.
+func (d RTPTransceiverDirection) sends() bool {
+	return d == RTPTransceiverDirectionSendrecv || d == RTPTransceiverDirectionSendonly
+}
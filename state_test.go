@@ -0,0 +1,93 @@
+package webrtc
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestStateNames(t *testing.T) {
+	for _, tc := range []struct {
+		value    fmt.Stringer
+		w3c      string
+		gowebrtc string
+		parse    func(string) (fmt.Stringer, error)
+	}{
+		{SignalingStateStable, "stable", "Stable", parseSignaling},
+		{SignalingStateHaveLocalOffer, "have-local-offer", "HaveLocalOffer", parseSignaling},
+		{SignalingStateHaveLocalPrAnswer, "have-local-pranswer", "HaveLocalPrAnswer", parseSignaling},
+		{SignalingStateHaveRemoteOffer, "have-remote-offer", "HaveRemoteOffer", parseSignaling},
+		{SignalingStateHaveRemotePrAnswer, "have-remote-pranswer", "HaveRemotePrAnswer", parseSignaling},
+		{SignalingStateClosed, "closed", "Closed", parseSignaling},
+		{IceConnectionStateNew, "new", "New", parseIceConnection},
+		{IceConnectionStateChecking, "checking", "Checking", parseIceConnection},
+		{IceConnectionStateConnected, "connected", "Connected", parseIceConnection},
+		{IceConnectionStateCompleted, "completed", "Completed", parseIceConnection},
+		{IceConnectionStateFailed, "failed", "Failed", parseIceConnection},
+		{IceConnectionStateDisconnected, "disconnected", "Disconnected", parseIceConnection},
+		{IceConnectionStateClosed, "closed", "Closed", parseIceConnection},
+		{IceGatheringStateNew, "new", "New", parseIceGathering},
+		{IceGatheringStateGathering, "gathering", "Gathering", parseIceGathering},
+		{IceGatheringStateComplete, "complete", "Complete", parseIceGathering},
+		{PeerConnectionStateNew, "new", "New", parsePeerConnection},
+		{PeerConnectionStateConnecting, "connecting", "Connecting", parsePeerConnection},
+		{PeerConnectionStateConnected, "connected", "Connected", parsePeerConnection},
+		{PeerConnectionStateDisconnected, "disconnected", "Disconnected", parsePeerConnection},
+		{PeerConnectionStateFailed, "failed", "Failed", parsePeerConnection},
+		{PeerConnectionStateClosed, "closed", "Closed", parsePeerConnection},
+		{RTPTransceiverDirectionSendrecv, "sendrecv", "SendRecv", parseDirection},
+		{RTPTransceiverDirectionSendonly, "sendonly", "SendOnly", parseDirection},
+		{RTPTransceiverDirectionRecvonly, "recvonly", "RecvOnly", parseDirection},
+		{RTPTransceiverDirectionInactive, "inactive", "Inactive", parseDirection},
+		{RTPTransceiverDirectionStopped, "stopped", "Stopped", parseDirection},
+	} {
+		if got := tc.value.String(); got != tc.w3c {
+			t.Errorf("%#v: String() = %q, want %q", tc.value, got, tc.w3c)
+		}
+		for _, s := range []string{tc.w3c, tc.gowebrtc, strings.ToUpper(tc.w3c)} {
+			v, err := tc.parse(s)
+			if err != nil || v != tc.value {
+				t.Errorf("parse %q: got %v, %v, want %v", s, v, err, tc.value)
+			}
+		}
+	}
+}
+
+func TestStateUnknown(t *testing.T) {
+	for _, s := range []string{"", "unknown", "bogus", "have-local"} {
+		for name, parse := range map[string]func(string) (fmt.Stringer, error){
+			"signaling":      parseSignaling,
+			"ice connection": parseIceConnection,
+			"ice gathering":  parseIceGathering,
+			"peer":           parsePeerConnection,
+			"direction":      parseDirection,
+		} {
+			v, err := parse(s)
+			if err == nil {
+				t.Errorf("%s: %q parsed as %v", name, s, v)
+			}
+			if v.String() != "unknown" {
+				t.Errorf("%s: %q parsed as %v, want unknown", name, s, v)
+			}
+		}
+	}
+	for _, v := range []fmt.Stringer{
+		SignalingStateUnknown,
+		SignalingState(-1),
+		SignalingState(100),
+		IceConnectionState(100),
+		IceGatheringState(100),
+		PeerConnectionState(100),
+		RTPTransceiverDirection(100),
+	} {
+		if got := v.String(); got != "unknown" {
+			t.Errorf("%#v: String() = %q, want unknown", v, got)
+		}
+	}
+}
+
+func parseSignaling(s string) (fmt.Stringer, error)      { return ParseSignalingState(s) }
+func parseIceConnection(s string) (fmt.Stringer, error)  { return ParseIceConnectionState(s) }
+func parseIceGathering(s string) (fmt.Stringer, error)   { return ParseIceGatheringState(s) }
+func parsePeerConnection(s string) (fmt.Stringer, error) { return ParsePeerConnectionState(s) }
+func parseDirection(s string) (fmt.Stringer, error)      { return ParseRTPTransceiverDirection(s) }
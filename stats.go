@@ -0,0 +1,223 @@
+package webrtc
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Stats holds the members every W3C stats object has.
+type Stats struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Timestamp time.Time `json:"-"`
+}
+
+// CandidatePairStats ...
+type CandidatePairStats struct {
+	Stats
+	TransportID              string  `json:"transportId"`
+	LocalCandidateID         string  `json:"localCandidateId"`
+	RemoteCandidateID        string  `json:"remoteCandidateId"`
+	State                    string  `json:"state"`
+	Nominated                bool    `json:"nominated"`
+	BytesSent                uint64  `json:"bytesSent"`
+	BytesReceived            uint64  `json:"bytesReceived"`
+	CurrentRoundTripTime     float64 `json:"currentRoundTripTime"`
+	TotalRoundTripTime       float64 `json:"totalRoundTripTime"`
+	AvailableOutgoingBitrate float64 `json:"availableOutgoingBitrate"`
+	RequestsSent             uint64  `json:"requestsSent"`
+	ResponsesReceived        uint64  `json:"responsesReceived"`
+}
+
+// CandidateStats is a local-candidate or remote-candidate.
+type CandidateStats struct {
+	Stats
+	TransportID   string `json:"transportId"`
+	Address       string `json:"address"`
+	Port          int    `json:"port"`
+	Protocol      string `json:"protocol"`
+	CandidateType string `json:"candidateType"`
+	Priority      uint32 `json:"priority"`
+	URL           string `json:"url"`
+}
+
+// TransportStats ...
+type TransportStats struct {
+	Stats
+	BytesSent               uint64 `json:"bytesSent"`
+	BytesReceived           uint64 `json:"bytesReceived"`
+	DtlsState               string `json:"dtlsState"`
+	SelectedCandidatePairID string `json:"selectedCandidatePairId"`
+	LocalCertificateID      string `json:"localCertificateId"`
+	RemoteCertificateID     string `json:"remoteCertificateId"`
+	DtlsCipher              string `json:"dtlsCipher"`
+}
+
+// DataChannelStats ...
+type DataChannelStats struct {
+	Stats
+	Label                 string `json:"label"`
+	Protocol              string `json:"protocol"`
+	DataChannelIdentifier int    `json:"dataChannelIdentifier"`
+	State                 string `json:"state"`
+	MessagesSent          uint64 `json:"messagesSent"`
+	BytesSent             uint64 `json:"bytesSent"`
+	MessagesReceived      uint64 `json:"messagesReceived"`
+	BytesReceived         uint64 `json:"bytesReceived"`
+}
+
+// RTPStreamStats holds the members shared by inbound-rtp and outbound-rtp.
+type RTPStreamStats struct {
+	SSRC        uint32 `json:"ssrc"`
+	Kind        string `json:"kind"`
+	TransportID string `json:"transportId"`
+	CodecID     string `json:"codecId"`
+}
+
+// InboundRTPStats ...
+type InboundRTPStats struct {
+	Stats
+	RTPStreamStats
+	PacketsReceived uint64  `json:"packetsReceived"`
+	PacketsLost     int64   `json:"packetsLost"`
+	Jitter          float64 `json:"jitter"`
+	BytesReceived   uint64  `json:"bytesReceived"`
+}
+
+// OutboundRTPStats ...
+type OutboundRTPStats struct {
+	Stats
+	RTPStreamStats
+	PacketsSent uint64 `json:"packetsSent"`
+	BytesSent   uint64 `json:"bytesSent"`
+}
+
+// CertificateStats ...
+type CertificateStats struct {
+	Stats
+	Fingerprint          string `json:"fingerprint"`
+	FingerprintAlgorithm string `json:"fingerprintAlgorithm"`
+	Base64Certificate    string `json:"base64Certificate"`
+}
+
+// StatsReport is the result of GetStats, grouped by stats type. Types not
+// listed here are skipped.
+type StatsReport struct {
+	CandidatePairs   []*CandidatePairStats
+	LocalCandidates  []*CandidateStats
+	RemoteCandidates []*CandidateStats
+	Transports       []*TransportStats
+	DataChannels     []*DataChannelStats
+	InboundRTP       []*InboundRTPStats
+	OutboundRTP      []*OutboundRTPStats
+	Certificates     []*CertificateStats
+}
+
+// SelectedCandidatePair returns the candidate pair in use, or nil.
+func (r *StatsReport) SelectedCandidatePair() *CandidatePairStats {
+	for _, t := range r.Transports {
+		for _, p := range r.CandidatePairs {
+			if p.ID == t.SelectedCandidatePairID {
+				return p
+			}
+		}
+	}
+	// browsers without selectedCandidatePairId
+	for _, p := range r.CandidatePairs {
+		if p.Nominated && p.State == "succeeded" {
+			return p
+		}
+	}
+	return nil
+}
+
+// Candidate returns the local or remote candidate with the id, or nil.
+func (r *StatsReport) Candidate(id string) *CandidateStats {
+	for _, cands := range [][]*CandidateStats{r.LocalCandidates, r.RemoteCandidates} {
+		for _, c := range cands {
+			if c.ID == id {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// normalizeStatsType maps legacy stats type names to the W3C ones, like
+// fixStatsType in adaptor.inc.js.
+func normalizeStatsType(t string) string {
+	switch t {
+	case "inboundrtp":
+		return "inbound-rtp"
+	case "outboundrtp":
+		return "outbound-rtp"
+	case "candidatepair":
+		return "candidate-pair"
+	case "localcandidate":
+		return "local-candidate"
+	case "remotecandidate":
+		return "remote-candidate"
+	}
+	return t
+}
+
+// addJSON adds one stats object in its W3C JSON form.
+func (r *StatsReport) addJSON(data []byte) error {
+	var head struct {
+		Type      string  `json:"type"`
+		Timestamp float64 `json:"timestamp"`
+		IP        string  `json:"ip"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return err
+	}
+	typ := normalizeStatsType(head.Type)
+	var (
+		v    interface{}
+		base *Stats
+	)
+	switch typ {
+	case "candidate-pair":
+		s := &CandidatePairStats{}
+		r.CandidatePairs = append(r.CandidatePairs, s)
+		v, base = s, &s.Stats
+	case "local-candidate", "remote-candidate":
+		s := &CandidateStats{}
+		if typ == "local-candidate" {
+			r.LocalCandidates = append(r.LocalCandidates, s)
+		} else {
+			r.RemoteCandidates = append(r.RemoteCandidates, s)
+		}
+		// older browsers name the address "ip".
+		s.Address = head.IP
+		v, base = s, &s.Stats
+	case "transport":
+		s := &TransportStats{}
+		r.Transports = append(r.Transports, s)
+		v, base = s, &s.Stats
+	case "data-channel":
+		s := &DataChannelStats{}
+		r.DataChannels = append(r.DataChannels, s)
+		v, base = s, &s.Stats
+	case "inbound-rtp":
+		s := &InboundRTPStats{}
+		r.InboundRTP = append(r.InboundRTP, s)
+		v, base = s, &s.Stats
+	case "outbound-rtp":
+		s := &OutboundRTPStats{}
+		r.OutboundRTP = append(r.OutboundRTP, s)
+		v, base = s, &s.Stats
+	case "certificate":
+		s := &CertificateStats{}
+		r.Certificates = append(r.Certificates, s)
+		v, base = s, &s.Stats
+	default:
+		return nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+	base.Type = typ
+	base.Timestamp = time.Unix(0, int64(head.Timestamp*float64(time.Millisecond)))
+	return nil
+}
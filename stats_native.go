@@ -0,0 +1,172 @@
+// +build !js,!pion !js,mock
+
+package webrtc
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/nobonobo/webrtc/sdp"
+)
+
+// dataChannelCounters are the message counts go-webrtc and the mock do not
+// otherwise keep.
+type dataChannelCounters struct {
+	messagesSent     uint64
+	bytesSent        uint64
+	messagesReceived uint64
+	bytesReceived    uint64
+}
+
+func (c *dataChannelCounters) sent(n int) {
+	atomic.AddUint64(&c.messagesSent, 1)
+	atomic.AddUint64(&c.bytesSent, uint64(n))
+}
+
+func (c *dataChannelCounters) received(n int) {
+	atomic.AddUint64(&c.messagesReceived, 1)
+	atomic.AddUint64(&c.bytesReceived, uint64(n))
+}
+
+// GetStats builds the report from what the wrapper itself observes: the
+// candidates seen in signaling, the DTLS fingerprints of the descriptions
+// and the messages of each DataChannel. go-webrtc reports neither the
+// selected candidate pair nor transport, round trip or RTP counters, so
+// the report has no candidate pairs and those members stay zero. The mock
+// backend reports its single candidate pair.
+func (pc *PeerConnection) GetStats(ctx context.Context) (*StatsReport, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	stats := func(id, typ string) Stats {
+		return Stats{ID: id, Type: typ, Timestamp: now}
+	}
+	r := &StatsReport{}
+	transport := &TransportStats{
+		Stats:     stats("RTCTransport_0", "transport"),
+		DtlsState: dtlsState(pc.ConnectionState()),
+	}
+	r.Transports = append(r.Transports, transport)
+
+	localSD, remoteSD := pc.LocalDescription(), pc.RemoteDescription()
+	local, remote := pc.seen.all()
+	ids := map[string]string{}
+	addCandidates := func(typ string, cands []*IceCandidate) []*CandidateStats {
+		var list []*CandidateStats
+		for _, ic := range cands {
+			if _, dup := ids[typ+ic.Candidate]; dup {
+				continue
+			}
+			c, err := sdp.ParseCandidate(ic.Candidate)
+			if err != nil {
+				continue
+			}
+			id := fmt.Sprintf("RTCIceCandidate_%s_%d", typ, len(list))
+			ids[typ+ic.Candidate] = id
+			list = append(list, &CandidateStats{
+				Stats:         stats(id, typ),
+				TransportID:   transport.ID,
+				Address:       c.Address,
+				Port:          c.Port,
+				Protocol:      c.Protocol,
+				CandidateType: c.Type,
+				Priority:      c.Priority,
+			})
+		}
+		return list
+	}
+	r.LocalCandidates = addCandidates("local-candidate", append(local, sdpCandidates(localSD)...))
+	r.RemoteCandidates = addCandidates("remote-candidate", append(remote, sdpCandidates(remoteSD)...))
+
+	if l, rm := pc.candidatePair(); l != nil && rm != nil {
+		pair := &CandidatePairStats{
+			Stats:             stats("RTCIceCandidatePair_0", "candidate-pair"),
+			TransportID:       transport.ID,
+			LocalCandidateID:  ids["local-candidate"+l.Candidate],
+			RemoteCandidateID: ids["remote-candidate"+rm.Candidate],
+			State:             "in-progress",
+		}
+		switch pc.IceConnectionState() {
+		case IceConnectionStateConnected, IceConnectionStateCompleted:
+			pair.State = "succeeded"
+			pair.Nominated = true
+			transport.SelectedCandidatePairID = pair.ID
+		case IceConnectionStateFailed:
+			pair.State = "failed"
+		}
+		r.CandidatePairs = append(r.CandidatePairs, pair)
+	}
+
+	for i, sd := range []*SessionDescription{localSD, remoteSD} {
+		fp := fingerprint(sd)
+		if fp == nil {
+			continue
+		}
+		cert := &CertificateStats{
+			Stats:                stats(fmt.Sprintf("RTCCertificate_%d", i), "certificate"),
+			Fingerprint:          fp.Value,
+			FingerprintAlgorithm: fp.Hash,
+		}
+		if i == 0 {
+			transport.LocalCertificateID = cert.ID
+		} else {
+			transport.RemoteCertificateID = cert.ID
+		}
+		r.Certificates = append(r.Certificates, cert)
+	}
+
+	pc.mu.Lock()
+	channels := append([]*DataChannel{}, pc.channels...)
+	pc.mu.Unlock()
+	for i, c := range channels {
+		s := &DataChannelStats{
+			Stats:                 stats(fmt.Sprintf("RTCDataChannel_%d", i), "data-channel"),
+			Label:                 c.Label(),
+			Protocol:              c.Protocol(),
+			DataChannelIdentifier: c.ID(),
+			State:                 c.ReadyState(),
+			MessagesSent:          atomic.LoadUint64(&c.counters.messagesSent),
+			BytesSent:             atomic.LoadUint64(&c.counters.bytesSent),
+			MessagesReceived:      atomic.LoadUint64(&c.counters.messagesReceived),
+			BytesReceived:         atomic.LoadUint64(&c.counters.bytesReceived),
+		}
+		r.DataChannels = append(r.DataChannels, s)
+	}
+	return r, nil
+}
+
+func dtlsState(s PeerConnectionState) string {
+	switch s {
+	case PeerConnectionStateNew, PeerConnectionStateUnknown:
+		return "new"
+	case PeerConnectionStateConnected, PeerConnectionStateDisconnected:
+		return "connected"
+	case PeerConnectionStateFailed:
+		return "failed"
+	case PeerConnectionStateClosed:
+		return "closed"
+	}
+	return "connecting"
+}
+
+func fingerprint(sd *SessionDescription) *sdp.Fingerprint {
+	if sd == nil {
+		return nil
+	}
+	s, err := sd.Session()
+	if err != nil {
+		return nil
+	}
+	var m *sdp.Media
+	if len(s.Media) > 0 {
+		m = s.Media[0]
+	}
+	fp, ok := s.Fingerprint(m)
+	if !ok {
+		return nil
+	}
+	return fp
+}
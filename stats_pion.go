@@ -0,0 +1,123 @@
+// +build !js,pion,!mock
+
+package webrtc
+
+import (
+	"context"
+	"time"
+
+	pion "github.com/pion/webrtc/v3"
+)
+
+// GetStats converts the report pion collects itself. Members pion does not
+// fill stay zero.
+func (pc *PeerConnection) GetStats(ctx context.Context) (*StatsReport, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r := &StatsReport{}
+	for _, st := range pc.pc.GetStats() {
+		switch s := st.(type) {
+		case pion.ICECandidatePairStats:
+			r.CandidatePairs = append(r.CandidatePairs, &CandidatePairStats{
+				Stats:                    pionStats(s.ID, "candidate-pair", float64(s.Timestamp)),
+				TransportID:              s.TransportID,
+				LocalCandidateID:         s.LocalCandidateID,
+				RemoteCandidateID:        s.RemoteCandidateID,
+				State:                    string(s.State),
+				Nominated:                s.Nominated,
+				BytesSent:                s.BytesSent,
+				BytesReceived:            s.BytesReceived,
+				CurrentRoundTripTime:     s.CurrentRoundTripTime,
+				TotalRoundTripTime:       s.TotalRoundTripTime,
+				AvailableOutgoingBitrate: s.AvailableOutgoingBitrate,
+				RequestsSent:             s.RequestsSent,
+				ResponsesReceived:        s.ResponsesReceived,
+			})
+		case pion.ICECandidateStats:
+			typ := normalizeStatsType(string(s.Type))
+			c := &CandidateStats{
+				Stats:         pionStats(s.ID, typ, float64(s.Timestamp)),
+				TransportID:   s.TransportID,
+				Address:       s.IP,
+				Port:          int(s.Port),
+				Protocol:      s.Protocol,
+				CandidateType: s.CandidateType.String(),
+				Priority:      uint32(s.Priority),
+				URL:           s.URL,
+			}
+			if typ == "local-candidate" {
+				r.LocalCandidates = append(r.LocalCandidates, c)
+			} else {
+				r.RemoteCandidates = append(r.RemoteCandidates, c)
+			}
+		case pion.TransportStats:
+			r.Transports = append(r.Transports, &TransportStats{
+				Stats:                   pionStats(s.ID, "transport", float64(s.Timestamp)),
+				BytesSent:               s.BytesSent,
+				BytesReceived:           s.BytesReceived,
+				DtlsState:               s.DTLSState.String(),
+				SelectedCandidatePairID: s.SelectedCandidatePairID,
+				LocalCertificateID:      s.LocalCertificateID,
+				RemoteCertificateID:     s.RemoteCertificateID,
+				DtlsCipher:              s.DTLSCipher,
+			})
+		case pion.DataChannelStats:
+			r.DataChannels = append(r.DataChannels, &DataChannelStats{
+				Stats:                 pionStats(s.ID, "data-channel", float64(s.Timestamp)),
+				Label:                 s.Label,
+				Protocol:              s.Protocol,
+				DataChannelIdentifier: int(s.DataChannelIdentifier),
+				State:                 s.State.String(),
+				MessagesSent:          uint64(s.MessagesSent),
+				BytesSent:             s.BytesSent,
+				MessagesReceived:      uint64(s.MessagesReceived),
+				BytesReceived:         s.BytesReceived,
+			})
+		case pion.InboundRTPStreamStats:
+			r.InboundRTP = append(r.InboundRTP, &InboundRTPStats{
+				Stats: pionStats(s.ID, "inbound-rtp", float64(s.Timestamp)),
+				RTPStreamStats: RTPStreamStats{
+					SSRC:        uint32(s.SSRC),
+					Kind:        s.Kind,
+					TransportID: s.TransportID,
+					CodecID:     s.CodecID,
+				},
+				PacketsReceived: uint64(s.PacketsReceived),
+				PacketsLost:     int64(s.PacketsLost),
+				Jitter:          s.Jitter,
+				BytesReceived:   s.BytesReceived,
+			})
+		case pion.OutboundRTPStreamStats:
+			r.OutboundRTP = append(r.OutboundRTP, &OutboundRTPStats{
+				Stats: pionStats(s.ID, "outbound-rtp", float64(s.Timestamp)),
+				RTPStreamStats: RTPStreamStats{
+					SSRC:        uint32(s.SSRC),
+					Kind:        s.Kind,
+					TransportID: s.TransportID,
+					CodecID:     s.CodecID,
+				},
+				PacketsSent: uint64(s.PacketsSent),
+				BytesSent:   s.BytesSent,
+			})
+		case pion.CertificateStats:
+			r.Certificates = append(r.Certificates, &CertificateStats{
+				Stats:                pionStats(s.ID, "certificate", float64(s.Timestamp)),
+				Fingerprint:          s.Fingerprint,
+				FingerprintAlgorithm: s.FingerprintAlgorithm,
+				Base64Certificate:    s.Base64Certificate,
+			})
+		}
+	}
+	return r, nil
+}
+
+// pionStats fills Stats from pion's members; pion timestamps are
+// milliseconds since the epoch like the W3C ones.
+func pionStats(id, typ string, ms float64) Stats {
+	return Stats{
+		ID:        id,
+		Type:      typ,
+		Timestamp: time.Unix(0, int64(ms*float64(time.Millisecond))),
+	}
+}
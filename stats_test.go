@@ -0,0 +1,76 @@
+// +build mock
+
+package webrtc
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// waitStats polls GetStats until ok accepts the report.
+func waitStats(t *testing.T, pc *PeerConnection, ok func(*StatsReport) bool) *StatsReport {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		r, err := pc.GetStats(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok(r) {
+			return r
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected report %+v", r)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func channelStats(r *StatsReport, label string) *DataChannelStats {
+	for _, s := range r.DataChannels {
+		if s.Label == label {
+			return s
+		}
+	}
+	return nil
+}
+
+func TestGetStats(t *testing.T) {
+	offerer, answerer, err := NewPair(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer offerer.Close()
+	defer answerer.Close()
+
+	dc, err := offerer.CreateDataChannel("stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := dc.Events()
+	for dc.ReadyState() != "open" {
+		next(t, events)
+	}
+	dc.Send([]byte("hello"))
+	// trickled candidates may still be on their way.
+	r := waitStats(t, offerer, func(r *StatsReport) bool {
+		return r.SelectedCandidatePair() != nil
+	})
+	p := r.SelectedCandidatePair()
+	if r.Candidate(p.LocalCandidateID) == nil || r.Candidate(p.RemoteCandidateID) == nil {
+		t.Fatalf("selected pair %+v without candidates", p)
+	}
+	if s := channelStats(r, "stats"); s == nil || s.MessagesSent != 1 || s.BytesSent != 5 {
+		t.Fatalf("data channel stats %+v", s)
+	}
+	if len(r.Certificates) != 2 {
+		t.Fatalf("%d certificates", len(r.Certificates))
+	}
+
+	// closed channels leave the report and the PeerConnection.
+	dc.Close()
+	waitStats(t, offerer, func(r *StatsReport) bool {
+		return channelStats(r, "stats") == nil
+	})
+}
@@ -0,0 +1,109 @@
+package transfer
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/nobonobo/webrtc"
+)
+
+// message tags
+const (
+	tagHeader   = 'H'
+	tagResume   = 'R'
+	tagData     = 'D'
+	tagManifest = 'E'
+	tagAck      = 'A'
+)
+
+type header struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+type resume struct {
+	Offset int64 `json:"offset"`
+	// SHA256 is the hash of the first Offset bytes.
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+type ack struct {
+	Error string `json:"error,omitempty"`
+}
+
+// channel exchanges tagged messages over a DataChannel.
+type channel struct {
+	dc     *webrtc.DataChannel
+	events <-chan webrtc.Event
+}
+
+func newChannel(dc *webrtc.DataChannel) (*channel, error) {
+	if !dc.Ordered() || dc.MaxRetransmits() >= 0 || dc.MaxPacketLifeTime() >= 0 {
+		return nil, ErrUnreliable
+	}
+	return &channel{dc: dc, events: dc.Events()}, nil
+}
+
+func (c *channel) close() {
+	c.dc.Unsubscribe(c.events)
+}
+
+// waitOpen waits until the DataChannel is open.
+func (c *channel) waitOpen(ctx context.Context) error {
+	for c.dc.ReadyState() != "open" {
+		select {
+		case ev, ok := <-c.events:
+			if !ok {
+				return ErrClosed
+			}
+			if _, ok := ev.(webrtc.CloseEvent); ok {
+				return ErrClosed
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (c *channel) send(ctx context.Context, tag byte, body []byte) error {
+	msg := make([]byte, 1+len(body))
+	msg[0] = tag
+	copy(msg[1:], body)
+	return c.dc.SendContext(ctx, msg)
+}
+
+func (c *channel) sendJSON(ctx context.Context, tag byte, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.send(ctx, tag, b)
+}
+
+// recv returns the next tagged message. retry, if not nil, interrupts the
+// wait with errRetry.
+func (c *channel) recv(ctx context.Context, retry <-chan time.Time) (byte, []byte, error) {
+	for {
+		select {
+		case ev, ok := <-c.events:
+			if !ok {
+				return 0, nil, ErrClosed
+			}
+			switch ev := ev.(type) {
+			case webrtc.MessageEvent:
+				if len(ev.Data) == 0 {
+					continue
+				}
+				return ev.Data[0], ev.Data[1:], nil
+			case webrtc.CloseEvent:
+				return 0, nil, ErrClosed
+			}
+		case <-retry:
+			return 0, nil, errRetry
+		case <-ctx.Done():
+			return 0, nil, ctx.Err()
+		}
+	}
+}
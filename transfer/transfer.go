@@ -0,0 +1,305 @@
+// Package transfer sends files over a webrtc.DataChannel.
+//
+// SendFile streams an io.Reader in chunks, waiting for the channel buffer
+// to drain, and finishes with a manifest carrying the SHA-256 of the whole
+// file. ReceiveFile writes the chunks out and checks the manifest. When
+// the destination already holds part of the file, the transfer resumes
+// after it, so an interrupted transfer can continue on a new DataChannel
+// by calling both functions again. The sender compares the SHA-256 of
+// that part with the start of its own file first and refuses to resume
+// onto different content with ErrResumeMismatch.
+//
+// Both ends use one ordered, reliable DataChannel per file.
+package transfer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+
+	"github.com/nobonobo/webrtc"
+)
+
+var (
+	// ErrClosed ...
+	ErrClosed = errors.New("transfer: data channel closed")
+	// ErrUnreliable is returned for unordered or partially reliable
+	// channels.
+	ErrUnreliable = errors.New("transfer: data channel must be ordered and reliable")
+	// ErrChecksumMismatch ...
+	ErrChecksumMismatch = errors.New("transfer: checksum mismatch")
+	// ErrResumeMismatch is returned by both ends when the data the
+	// receiver already has is not the start of the file. Truncate the
+	// destination and transfer again.
+	ErrResumeMismatch = errors.New("transfer: existing data differs from the file")
+
+	errRetry = errors.New("transfer: retry")
+)
+
+const (
+	// DefaultChunkSize keeps each chunk within one 16KiB message.
+	DefaultChunkSize = 16*1024 - 1
+
+	// MaxChunkSize keeps each chunk within the 64KiB message every peer
+	// accepts when its SDP has no a=max-message-size (RFC 8841).
+	MaxChunkSize = 64*1024 - 1
+
+	// helloInterval is how often SendFile repeats the header until the
+	// receiver answers, in case the receiver subscribed late.
+	helloInterval = 500 * time.Millisecond
+)
+
+// Manifest describes a transferred file.
+type Manifest struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Options ...
+type Options struct {
+	// ChunkSize is the payload of each data message, DefaultChunkSize if 0
+	// and at most MaxChunkSize.
+	ChunkSize int
+	// Progress is called after each chunk with the bytes done so far,
+	// including a resumed prefix, and the total or -1 if unknown.
+	Progress func(done, total int64)
+}
+
+func (o *Options) chunkSize() int {
+	switch {
+	case o == nil || o.ChunkSize <= 0:
+		return DefaultChunkSize
+	case o.ChunkSize > MaxChunkSize:
+		return MaxChunkSize
+	}
+	return o.ChunkSize
+}
+
+func (o *Options) progress(done, total int64) {
+	if o != nil && o.Progress != nil {
+		o.Progress(done, total)
+	}
+}
+
+// SendFile sends the content of r as name. size is the length of r, or -1
+// if unknown. When the receiver resumes, the part it already has is read
+// from r and hashed but not sent.
+func SendFile(ctx context.Context, dc *webrtc.DataChannel, name string, r io.Reader, size int64, opts *Options) (*Manifest, error) {
+	c, err := newChannel(dc)
+	if err != nil {
+		return nil, err
+	}
+	defer c.close()
+	if err := c.waitOpen(ctx); err != nil {
+		return nil, err
+	}
+	res, err := hello(ctx, c, &header{Name: name, Size: size})
+	if err != nil {
+		return nil, err
+	}
+	offset := res.Offset
+	if size >= 0 && offset > size {
+		c.sendJSON(ctx, tagAck, &ack{Error: ErrResumeMismatch.Error()})
+		return nil, fmt.Errorf("transfer: receiver has %d bytes of %d: %w", offset, size, ErrResumeMismatch)
+	}
+	h := sha256.New()
+	if n, err := io.CopyN(h, r, offset); err != nil {
+		if err == io.EOF {
+			c.sendJSON(ctx, tagAck, &ack{Error: ErrResumeMismatch.Error()})
+			return nil, fmt.Errorf("transfer: receiver has %d bytes of %d: %w", offset, n, ErrResumeMismatch)
+		}
+		return nil, fmt.Errorf("transfer: skip %d resumed bytes: read %d: %w", offset, n, err)
+	}
+	if offset > 0 && res.SHA256 != hex.EncodeToString(h.Sum(nil)) {
+		c.sendJSON(ctx, tagAck, &ack{Error: ErrResumeMismatch.Error()})
+		return nil, ErrResumeMismatch
+	}
+	done := offset
+	opts.progress(done, size)
+	buf := make([]byte, opts.chunkSize())
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			h.Write(buf[:n])
+			if err := c.send(ctx, tagData, buf[:n]); err != nil {
+				return nil, err
+			}
+			done += int64(n)
+			opts.progress(done, size)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	m := &Manifest{Name: name, Size: done, SHA256: hex.EncodeToString(h.Sum(nil))}
+	if err := c.sendJSON(ctx, tagManifest, m); err != nil {
+		return nil, err
+	}
+	for {
+		tag, body, err := c.recv(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		if tag != tagAck {
+			continue
+		}
+		var a ack
+		if err := json.Unmarshal(body, &a); err != nil {
+			return nil, err
+		}
+		if a.Error != "" {
+			return nil, fmt.Errorf("transfer: receiver: %s", a.Error)
+		}
+		return m, nil
+	}
+}
+
+// hello sends the header until the receiver tells where to resume.
+func hello(ctx context.Context, c *channel, hd *header) (*resume, error) {
+	for {
+		if err := c.sendJSON(ctx, tagHeader, hd); err != nil {
+			return nil, err
+		}
+		retry := time.After(helloInterval)
+		for {
+			tag, body, err := c.recv(ctx, retry)
+			if err == errRetry {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if tag != tagResume {
+				continue
+			}
+			var res resume
+			if err := json.Unmarshal(body, &res); err != nil {
+				return nil, err
+			}
+			return &res, nil
+		}
+	}
+}
+
+// ReceiveFile writes a file sent by SendFile to w. If w is an io.ReadSeeker,
+// such as an *os.File opened read-write, the data already in it is kept
+// and the transfer resumes after it, unless the sender reports
+// ErrResumeMismatch.
+func ReceiveFile(ctx context.Context, dc *webrtc.DataChannel, w io.Writer, opts *Options) (*Manifest, error) {
+	c, err := newChannel(dc)
+	if err != nil {
+		return nil, err
+	}
+	defer c.close()
+	h := sha256.New()
+	offset, err := existing(h, w)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.waitOpen(ctx); err != nil {
+		return nil, err
+	}
+	var hd header
+	for {
+		tag, body, err := c.recv(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		if tag != tagHeader {
+			continue
+		}
+		if err := json.Unmarshal(body, &hd); err != nil {
+			return nil, err
+		}
+		break
+	}
+	res := &resume{Offset: offset}
+	if offset > 0 {
+		res.SHA256 = hex.EncodeToString(h.Sum(nil))
+	}
+	if err := c.sendJSON(ctx, tagResume, res); err != nil {
+		return nil, err
+	}
+	done := offset
+	opts.progress(done, hd.Size)
+	for {
+		tag, body, err := c.recv(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		switch tag {
+		case tagData:
+			if hd.Size >= 0 && done+int64(len(body)) > hd.Size {
+				err := fmt.Errorf("transfer: %s exceeds its size of %d", hd.Name, hd.Size)
+				c.sendJSON(ctx, tagAck, &ack{Error: err.Error()})
+				return nil, err
+			}
+			if _, err := w.Write(body); err != nil {
+				c.sendJSON(ctx, tagAck, &ack{Error: err.Error()})
+				return nil, err
+			}
+			h.Write(body)
+			done += int64(len(body))
+			opts.progress(done, hd.Size)
+		case tagAck:
+			// the sender gave up before sending the data.
+			var a ack
+			if err := json.Unmarshal(body, &a); err != nil {
+				return nil, err
+			}
+			if a.Error == ErrResumeMismatch.Error() {
+				return nil, ErrResumeMismatch
+			}
+			return nil, fmt.Errorf("transfer: sender: %s", a.Error)
+		case tagManifest:
+			var m Manifest
+			if err := json.Unmarshal(body, &m); err != nil {
+				return nil, err
+			}
+			err := verify(&m, done, h)
+			a := &ack{}
+			if err != nil {
+				a.Error = err.Error()
+			}
+			if err := c.sendJSON(ctx, tagAck, a); err != nil {
+				return nil, err
+			}
+			if err != nil {
+				return nil, err
+			}
+			return &m, nil
+		}
+	}
+}
+
+// existing hashes the data already in w and returns its length.
+func existing(h hash.Hash, w io.Writer) (int64, error) {
+	rs, ok := w.(io.ReadSeeker)
+	if !ok {
+		return 0, nil
+	}
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return io.Copy(h, rs)
+}
+
+func verify(m *Manifest, size int64, h hash.Hash) error {
+	if m.Size != size {
+		return fmt.Errorf("transfer: received %d bytes, manifest says %d", size, m.Size)
+	}
+	if m.SHA256 != hex.EncodeToString(h.Sum(nil)) {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
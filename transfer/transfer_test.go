@@ -0,0 +1,161 @@
+// +build mock
+
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nobonobo/webrtc"
+	"github.com/nobonobo/webrtc/webrtctest"
+)
+
+func random(t *testing.T, n int) []byte {
+	t.Helper()
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// run transfers data into a file holding prefix and returns the file
+// content and both errors.
+func run(t *testing.T, data, prefix []byte, opts *Options) ([]byte, error, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "file")
+	if err := ioutil.WriteFile(path, prefix, 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	dc, rc := webrtctest.Channels(t, "file", nil)
+	sent := make(chan error, 1)
+	go func() {
+		_, err := SendFile(ctx, dc, "file", bytes.NewReader(data), int64(len(data)), nil)
+		sent <- err
+	}()
+	m, rerr := ReceiveFile(ctx, rc, f, opts)
+	serr := <-sent
+	if rerr == nil && m.Size != int64(len(data)) {
+		t.Errorf("manifest size %d, want %d", m.Size, len(data))
+	}
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got, serr, rerr
+}
+
+func TestTransfer(t *testing.T) {
+	data := random(t, 1<<20)
+	got, serr, rerr := run(t, data, nil, nil)
+	if serr != nil || rerr != nil {
+		t.Fatalf("send: %v, receive: %v", serr, rerr)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("received %d bytes, differ from the %d sent", len(got), len(data))
+	}
+}
+
+func TestResume(t *testing.T) {
+	data := random(t, 1<<20)
+	const have = 300 << 10
+	first := int64(-1)
+	opts := &Options{Progress: func(done, total int64) {
+		if first < 0 {
+			first = done
+		}
+	}}
+	got, serr, rerr := run(t, data, data[:have], opts)
+	if serr != nil || rerr != nil {
+		t.Fatalf("send: %v, receive: %v", serr, rerr)
+	}
+	if first != have {
+		t.Errorf("resumed at %d, want %d", first, have)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("received %d bytes, differ from the %d sent", len(got), len(data))
+	}
+}
+
+func TestResumeMismatch(t *testing.T) {
+	data := random(t, 1<<20)
+	prefix := append([]byte(nil), data[:300<<10]...)
+	prefix[len(prefix)/2] ^= 0xff
+	got, serr, rerr := run(t, data, prefix, nil)
+	if !errors.Is(serr, ErrResumeMismatch) || !errors.Is(rerr, ErrResumeMismatch) {
+		t.Fatalf("send: %v, receive: %v", serr, rerr)
+	}
+	if !bytes.Equal(got, prefix) {
+		t.Fatal("existing data changed")
+	}
+
+	// a prefix longer than the file cannot be its start either.
+	_, serr, rerr = run(t, data[:1000], data[:2000], nil)
+	if !errors.Is(serr, ErrResumeMismatch) || !errors.Is(rerr, ErrResumeMismatch) {
+		t.Fatalf("longer prefix: send: %v, receive: %v", serr, rerr)
+	}
+}
+
+func TestUnreliable(t *testing.T) {
+	zero := 0
+	dc, _ := webrtctest.Channels(t, "file", &webrtc.DataChannelInit{MaxRetransmits: &zero})
+	_, err := SendFile(context.Background(), dc, "file", bytes.NewReader(nil), 0, nil)
+	if err != ErrUnreliable {
+		t.Fatalf("got %v, want ErrUnreliable", err)
+	}
+}
+
+func TestOversize(t *testing.T) {
+	data := random(t, 100<<10)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	dc, rc := webrtctest.Channels(t, "file", nil)
+	sent := make(chan error, 1)
+	go func() {
+		// the file turns out longer than announced.
+		_, err := SendFile(ctx, dc, "file", bytes.NewReader(data), int64(len(data))/2, nil)
+		sent <- err
+	}()
+	if _, err := ReceiveFile(ctx, rc, ioutil.Discard, nil); err == nil {
+		t.Fatal("oversized file received")
+	}
+	select {
+	case err := <-sent:
+		if err == nil || errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("send: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("sender not told about the failure")
+	}
+}
+
+func TestChunkSize(t *testing.T) {
+	for _, c := range []struct {
+		opts *Options
+		want int
+	}{
+		{nil, DefaultChunkSize},
+		{&Options{}, DefaultChunkSize},
+		{&Options{ChunkSize: 1000}, 1000},
+		{&Options{ChunkSize: MaxChunkSize}, MaxChunkSize},
+		{&Options{ChunkSize: 1 << 20}, MaxChunkSize},
+	} {
+		if got := c.opts.chunkSize(); got != c.want {
+			t.Errorf("chunkSize of %+v: got %d, want %d", c.opts, got, c.want)
+		}
+	}
+}
@@ -1,4 +1,4 @@
-// +build js
+// +build js,!wasm
 
 package webrtc
 
@@ -143,6 +143,25 @@ func NewConstraints(video, audio interface{}) *Constraints {
 	return c
 }
 
+// dict returns the RTCDataChannelInit dictionary without the unset members.
+func (p dataChannelParams) dict() js.M {
+	m := js.M{
+		"ordered":    p.ordered,
+		"protocol":   p.protocol,
+		"negotiated": p.negotiated,
+	}
+	if p.lifetime >= 0 {
+		m["maxPacketLifeTime"] = p.lifetime
+	}
+	if p.retransmits >= 0 {
+		m["maxRetransmits"] = p.retransmits
+	}
+	if p.id >= 0 {
+		m["id"] = p.id
+	}
+	return m
+}
+
 // IceCandidate ...
 type IceCandidate struct {
 	o             *js.Object
@@ -1,4 +1,4 @@
-// +build !js
+// +build !js wasm
 
 package webrtc
 
@@ -106,6 +106,16 @@ type AudioConstraints struct {
 	Mandatory AudioMandatory `js:"mandatory"`
 }
 
+// NewVideoConstraints ...
+func NewVideoConstraints() *VideoConstraints {
+	return &VideoConstraints{}
+}
+
+// NewAudioConstraints ...
+func NewAudioConstraints() *AudioConstraints {
+	return &AudioConstraints{}
+}
+
 // Constraints ...
 type Constraints struct {
 	Video interface{} `js:"video"` // bool or *VideoConstraints
@@ -0,0 +1,145 @@
+// +build js,wasm
+
+package webrtc
+
+import (
+	"reflect"
+	"syscall/js"
+)
+
+// str returns v if it is a string, or "" for null and undefined.
+func str(v js.Value) string {
+	if v.Type() != js.TypeString {
+		return ""
+	}
+	return v.String()
+}
+
+// jsObject converts a struct with js tags, leaving out zero members.
+func jsObject(v interface{}) map[string]interface{} {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	rt := rv.Type()
+	m := map[string]interface{}{}
+	for i := 0; i < rt.NumField(); i++ {
+		name := rt.Field(i).Tag.Get("js")
+		f := rv.Field(i)
+		if name == "" || f.IsZero() {
+			continue
+		}
+		if f.Kind() == reflect.Struct {
+			m[name] = jsObject(f.Interface())
+		} else {
+			m[name] = f.Interface()
+		}
+	}
+	return m
+}
+
+// value returns the RTCConfiguration dictionary, or undefined for nil.
+func (config *Configuration) value() js.Value {
+	if config == nil {
+		return js.Undefined()
+	}
+	servers := []interface{}{}
+	for _, s := range config.IceServers {
+		urls := []interface{}{}
+		for _, u := range s.Urls {
+			urls = append(urls, u)
+		}
+		server := map[string]interface{}{"urls": urls}
+		if s.Username != "" {
+			server["username"] = s.Username
+		}
+		if s.Credential != "" {
+			server["credential"] = s.Credential
+		}
+		servers = append(servers, server)
+	}
+	m := map[string]interface{}{"iceServers": servers}
+	// browsers reject empty enum values.
+	for k, v := range map[string]string{
+		"iceTransportPolicy": config.IceTransportPolicy,
+		"bundlePolicy":       config.BundlePolicy,
+		"rtcpMuxPolicy":      config.RTCPMuxPolicy,
+		"peerIdentity":       config.PeerIdentity,
+	} {
+		if v != "" {
+			m[k] = v
+		}
+	}
+	return js.ValueOf(m)
+}
+
+// value returns the MediaStreamConstraints dictionary.
+func (c *Constraints) value() js.Value {
+	m := map[string]interface{}{}
+	for k, v := range map[string]interface{}{"video": c.Video, "audio": c.Audio} {
+		switch v := v.(type) {
+		case nil:
+		case bool:
+			m[k] = v
+		default:
+			m[k] = jsObject(v)
+		}
+	}
+	return js.ValueOf(m)
+}
+
+// dict returns the RTCDataChannelInit dictionary without the unset members.
+func (p dataChannelParams) dict() map[string]interface{} {
+	m := map[string]interface{}{
+		"ordered":    p.ordered,
+		"protocol":   p.protocol,
+		"negotiated": p.negotiated,
+	}
+	if p.lifetime >= 0 {
+		m["maxPacketLifeTime"] = p.lifetime
+	}
+	if p.retransmits >= 0 {
+		m["maxRetransmits"] = p.retransmits
+	}
+	if p.id >= 0 {
+		m["id"] = p.id
+	}
+	return m
+}
+
+// value returns the RTCIceCandidateInit dictionary.
+func (ic *IceCandidate) value() js.Value {
+	m := map[string]interface{}{
+		"candidate":     ic.Candidate,
+		"sdpMLineIndex": ic.SdpMLineIndex,
+	}
+	if ic.SdpMid != "" {
+		m["sdpMid"] = ic.SdpMid
+	}
+	return js.ValueOf(m)
+}
+
+// NewIceCandidateFromObj ...
+func NewIceCandidateFromObj(obj js.Value) *IceCandidate {
+	ic := &IceCandidate{
+		Candidate: str(obj.Get("candidate")),
+		SdpMid:    str(obj.Get("sdpMid")),
+	}
+	if v := obj.Get("sdpMLineIndex"); v.Type() == js.TypeNumber {
+		ic.SdpMLineIndex = v.Int()
+	}
+	return ic
+}
+
+// value returns the RTCSessionDescriptionInit dictionary.
+func (sd *SessionDescription) value() js.Value {
+	return js.ValueOf(map[string]interface{}{
+		"type": sd.Type,
+		"sdp":  sd.Sdp,
+	})
+}
+
+// NewSessionDescriptionFromObj ...
+func NewSessionDescriptionFromObj(obj js.Value) *SessionDescription {
+	return &SessionDescription{
+		Type: str(obj.Get("type")),
+		Sdp:  str(obj.Get("sdp")),
+	}
+}
@@ -1,10 +1,10 @@
-// +build js
+// +build js,!wasm
 
 package webrtc
 
 import (
+	"context"
 	"fmt"
-	"sync"
 
 	"github.com/gopherjs/gopherjs/js"
 )
@@ -19,9 +19,45 @@ func init() {
 	peerConnection = js.Global.Get("RTCPeerConnection")
 }
 
+// canRollback reports whether rollback descriptions are accepted.
+var canRollback = true
+
+// SetLoggingVerbosity is a no-op; browsers have no such setting.
+func SetLoggingVerbosity(level int) {}
+
+// await waits for the JS promise p to settle or ctx to be done.
+func await(ctx context.Context, p *js.Object) (*js.Object, error) {
+	type result struct {
+		v   *js.Object
+		err error
+	}
+	ch := make(chan result, 1)
+	p.Call("then",
+		func(v *js.Object) {
+			ch <- result{v: v}
+		},
+		func(e *js.Object) {
+			ch <- result{err: fmt.Errorf("%s", e)}
+		},
+	)
+	select {
+	case r := <-ch:
+		return r.v, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
 // PeerConnection ...
 type PeerConnection struct {
-	pc *js.Object
+	candidates    candidateQueue
+	gathering     gatheringState
+	events        broker
+	seen          candidateLog
+	log           pcLog
+	pc            *js.Object
+	onDataChannel []func(*DataChannel)
+	onTrack       []func(*MediaStreamTrack, []*MediaStream)
 }
 
 // NewPeerConnection ...
@@ -37,9 +73,89 @@ func NewPeerConnection(config *Configuration) (pc *PeerConnection, err error) {
 		return nil, fmt.Errorf("create peer connection: failed")
 	}
 	pc = &PeerConnection{pc: jpc}
+	pc.log.init()
+	pc.gathering.set(pc.IceGatheringState())
+	jpc.Call("addEventListener", "icegatheringstatechange",
+		func(ev *js.Object) {
+			pc.gathering.set(pc.IceGatheringState())
+		}, false,
+	)
+	jpc.Call("addEventListener", "icecandidate",
+		func(ev *js.Object) {
+			if ev.Get("candidate") == nil {
+				pc.gathering.set(IceGatheringStateComplete)
+			}
+		}, false,
+	)
+	pc.publishEvents()
 	return
 }
 
+func (pc *PeerConnection) publishEvents() {
+	listen := func(name string, f func(ev *js.Object) Event) {
+		pc.pc.Call("addEventListener", name,
+			func(ev *js.Object) {
+				if e := f(ev); e != nil {
+					pc.publish(e)
+				}
+			}, false,
+		)
+	}
+	listen("negotiationneeded", func(ev *js.Object) Event {
+		return NegotiationNeededEvent{}
+	})
+	listen("icecandidate", func(ev *js.Object) Event {
+		candidate := ev.Get("candidate")
+		if candidate == nil {
+			return nil
+		}
+		ic := NewIceCandidateFromObj(candidate)
+		pc.seen.addLocal(ic)
+		return IceCandidateEvent{Candidate: ic}
+	})
+	listen("icecandidateerror", func(ev *js.Object) Event {
+		return IceCandidateErrorEvent{}
+	})
+	listen("signalingstatechange", func(ev *js.Object) Event {
+		return SignalingStateChangeEvent{State: pc.SignalingState()}
+	})
+	listen("iceconnectionstatechange", func(ev *js.Object) Event {
+		return IceConnectionStateChangeEvent{State: pc.IceConnectionState()}
+	})
+	listen("icegatheringstatechange", func(ev *js.Object) Event {
+		return IceGatheringStateChangeEvent{State: pc.IceGatheringState()}
+	})
+	listen("connectionstatechange", func(ev *js.Object) Event {
+		return ConnectionStateChangeEvent{State: pc.ConnectionState()}
+	})
+	listen("addstream", func(ev *js.Object) Event {
+		return AddStreamEvent{Stream: &MediaStream{o: ev.Get("stream")}}
+	})
+	listen("removestream", func(ev *js.Object) Event {
+		return RemoveStreamEvent{Stream: &MediaStream{o: ev.Get("stream")}}
+	})
+	// one wrapper per channel, shared by Events and OnDataChannel.
+	listen("datachannel", func(ev *js.Object) Event {
+		c := newDataChannel(pc, ev.Get("channel"))
+		for _, cb := range pc.onDataChannel {
+			cb(c)
+		}
+		return DataChannelEvent{Channel: c}
+	})
+	listen("track", func(ev *js.Object) Event {
+		t := &MediaStreamTrack{o: ev.Get("track")}
+		streams := []*MediaStream{}
+		list := ev.Get("streams")
+		for i := 0; i < list.Length(); i++ {
+			streams = append(streams, &MediaStream{o: list.Index(i)})
+		}
+		for _, cb := range pc.onTrack {
+			cb(t, streams)
+		}
+		return TrackEvent{Track: t, Streams: streams}
+	})
+}
+
 // OnNegotiationNeeded ...
 func (pc *PeerConnection) OnNegotiationNeeded(cb func()) {
 	pc.pc.Call("addEventListener", "negotiationneeded",
@@ -56,9 +172,6 @@ func (pc *PeerConnection) OnIceCandidate(cb func(*IceCandidate)) {
 			candidate := ev.Get("candidate")
 			if candidate != nil {
 				cb(NewIceCandidateFromObj(candidate))
-			} else {
-				ev := js.Global.Get("CustomEvent").New("icegatheringstatechange", js.M{"eventPhase": 2})
-				pc.pc.Call("dispatchEvent", ev)
 			}
 		}, false,
 	)
@@ -74,60 +187,44 @@ func (pc *PeerConnection) OnIceCandidateError(cb func()) {
 }
 
 // OnSignalingStateChange ...
-func (pc *PeerConnection) OnSignalingStateChange(cb func(SignalingState string)) {
+func (pc *PeerConnection) OnSignalingStateChange(cb func(SignalingState)) {
 	pc.pc.Call("addEventListener", "signalingstatechange",
 		func(ev *js.Object) {
-			cb([]string{
-				"Stable",
-				"HaveLocalOffer", "HaveLocalPrAnswer",
-				"HaveRemoteOffer", "HaveRemotePrAnswer",
-				"Closed",
-			}[ev.Get("eventPhase").Int()])
+			cb(pc.SignalingState())
 		}, false,
 	)
 }
 
 // OnIceConnectionStateChange ...
-func (pc *PeerConnection) OnIceConnectionStateChange(cb func(IceConnectionState string)) {
+func (pc *PeerConnection) OnIceConnectionStateChange(cb func(IceConnectionState)) {
 	pc.pc.Call("addEventListener", "iceconnectionstatechange",
 		func(ev *js.Object) {
-			cb([]string{
-				"New", "Checking", "Connected", "Completed",
-				"Failed", "Disconnected", "Closed",
-			}[ev.Get("eventPhase").Int()])
+			cb(pc.IceConnectionState())
 		}, false,
 	)
 }
 
 // OnIceGatheringStateChange ...
-func (pc *PeerConnection) OnIceGatheringStateChange(cb func(IceGatheringState string)) {
+func (pc *PeerConnection) OnIceGatheringStateChange(cb func(IceGatheringState)) {
 	pc.pc.Call("addEventListener", "icegatheringstatechange",
 		func(ev *js.Object) {
-			cb([]string{
-				"New", "Gathering", "Complete",
-			}[ev.Get("eventPhase").Int()])
+			cb(pc.IceGatheringState())
 		}, false,
 	)
 }
 
 // OnConnectionStateChange ...
-func (pc *PeerConnection) OnConnectionStateChange(cb func(PeerConnectionState string)) {
+func (pc *PeerConnection) OnConnectionStateChange(cb func(PeerConnectionState)) {
 	pc.pc.Call("addEventListener", "connectionstatechange",
 		func(ev *js.Object) {
-			cb([]string{
-				"New", "Connecting", "Connected", "Disconnected", "Failed",
-			}[ev.Get("eventPhase").Int()])
+			cb(pc.ConnectionState())
 		}, false,
 	)
 }
 
 // OnDataChannel ...
 func (pc *PeerConnection) OnDataChannel(cb func(*DataChannel)) {
-	pc.pc.Call("addEventListener", "datachannel",
-		func(dc *js.Object) {
-			cb(&DataChannel{dc: dc})
-		}, false,
-	)
+	pc.onDataChannel = append(pc.onDataChannel, cb)
 }
 
 // OnAddStream ...
@@ -161,17 +258,137 @@ func (pc *PeerConnection) AddStream(stream *MediaStream) (err error) {
 	return
 }
 
-// AddIceCandidate ...
-func (pc *PeerConnection) AddIceCandidate(ic *IceCandidate) (err error) {
+// OnTrack is called with remote tracks and the streams they belong to.
+func (pc *PeerConnection) OnTrack(cb func(*MediaStreamTrack, []*MediaStream)) {
+	pc.onTrack = append(pc.onTrack, cb)
+}
+
+// AddTrack ...
+func (pc *PeerConnection) AddTrack(track *MediaStreamTrack, streams ...*MediaStream) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("%s", r)
 		}
 	}()
-	pc.pc.Call("addIceCandidate", ic.o)
+	args := []interface{}{track.o}
+	for _, s := range streams {
+		args = append(args, s.o)
+	}
+	pc.pc.Call("addTrack", args...)
 	return
 }
 
+// RemoveTrack removes the sender of track.
+func (pc *PeerConnection) RemoveTrack(track *MediaStreamTrack) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s", r)
+		}
+	}()
+	senders := pc.pc.Call("getSenders")
+	for i := 0; i < senders.Length(); i++ {
+		sender := senders.Index(i)
+		if sender.Get("track") == track.o {
+			pc.pc.Call("removeTrack", sender)
+			return nil
+		}
+	}
+	return fmt.Errorf("remove track: track not added")
+}
+
+// AddTransceiver adds a transceiver of kind "audio" or "video".
+func (pc *PeerConnection) AddTransceiver(kind string, direction RTPTransceiverDirection) (t *RTPTransceiver, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s", r)
+			t = nil
+		}
+	}()
+	o := pc.pc.Call("addTransceiver", kind, map[string]interface{}{
+		"direction": direction.String(),
+	})
+	return &RTPTransceiver{o: o}, nil
+}
+
+// GetTransceivers ...
+func (pc *PeerConnection) GetTransceivers() []*RTPTransceiver {
+	list := []*RTPTransceiver{}
+	a := pc.pc.Call("getTransceivers")
+	for i := 0; i < a.Length(); i++ {
+		list = append(list, &RTPTransceiver{o: a.Index(i)})
+	}
+	return list
+}
+
+// GetSenders ...
+func (pc *PeerConnection) GetSenders() []*RTPSender {
+	list := []*RTPSender{}
+	a := pc.pc.Call("getSenders")
+	for i := 0; i < a.Length(); i++ {
+		list = append(list, &RTPSender{o: a.Index(i)})
+	}
+	return list
+}
+
+// GetReceivers ...
+func (pc *PeerConnection) GetReceivers() []*RTPReceiver {
+	list := []*RTPReceiver{}
+	a := pc.pc.Call("getReceivers")
+	for i := 0; i < a.Length(); i++ {
+		list = append(list, &RTPReceiver{o: a.Index(i)})
+	}
+	return list
+}
+
+// OnRemoteCandidateError is called with remote candidates that failed to
+// apply once the remote description was set, or were dropped with
+// ErrCandidateDropped.
+func (pc *PeerConnection) OnRemoteCandidateError(cb func(*IceCandidate, error)) {
+	pc.candidates.setOnError(cb)
+}
+
+// AddIceCandidate adds a remote candidate. Candidates added before the remote
+// description is set are queued and applied by SetRemoteDescription.
+func (pc *PeerConnection) AddIceCandidate(ic *IceCandidate) error {
+	return pc.AddIceCandidateContext(context.Background(), ic)
+}
+
+// AddIceCandidateContext ...
+func (pc *PeerConnection) AddIceCandidateContext(ctx context.Context, ic *IceCandidate) error {
+	return pc.candidates.add(ic, func(ic *IceCandidate) error {
+		return pc.addIceCandidate(ctx, ic)
+	})
+}
+
+func (pc *PeerConnection) addIceCandidate(ctx context.Context, ic *IceCandidate) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s", r)
+		}
+	}()
+	if _, err = await(ctx, pc.pc.Call("addIceCandidate", ic.o)); err != nil {
+		return
+	}
+	pc.seen.addRemote(ic)
+	return
+}
+
+// candidatePair returns the selected candidate pair of the SCTP transport,
+// or an approximation where the browser does not expose it.
+func (pc *PeerConnection) candidatePair() (local, remote *IceCandidate) {
+	defer func() {
+		if r := recover(); r != nil {
+			local, remote = pc.seen.pair(pc.LocalDescription(), pc.RemoteDescription())
+		}
+	}()
+	ice := pc.pc.Get("sctp").Get("transport").Get("iceTransport")
+	p := ice.Call("getSelectedCandidatePair")
+	if p == nil {
+		panic("no selected candidate pair")
+	}
+	return NewIceCandidateFromObj(p.Get("local")), NewIceCandidateFromObj(p.Get("remote"))
+}
+
 // Close ...
 func (pc *PeerConnection) Close() (err error) {
 	defer func() {
@@ -179,127 +396,234 @@ func (pc *PeerConnection) Close() (err error) {
 			err = fmt.Errorf("%s", r)
 		}
 	}()
+	pc.candidates.drop()
+	defer pc.events.close()
 	pc.pc.Call("close")
 	return
 }
 
 // ConnectionState ...
-func (pc *PeerConnection) ConnectionState() string {
-	return pc.pc.Get("connectionState").String()
+func (pc *PeerConnection) ConnectionState() PeerConnectionState {
+	state, _ := ParsePeerConnectionState(pc.pc.Get("connectionState").String())
+	return state
 }
 
 // CreateAnswer ...
-func (pc *PeerConnection) CreateAnswer() (s *SessionDescription, err error) {
+func (pc *PeerConnection) CreateAnswer() (*SessionDescription, error) {
+	return pc.CreateAnswerContext(context.Background())
+}
+
+// CreateAnswerContext ...
+func (pc *PeerConnection) CreateAnswerContext(ctx context.Context) (s *SessionDescription, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("%s", r)
 		}
 	}()
-	wg := sync.WaitGroup{}
-	wg.Add(1)
-	pc.pc.Call(
-		"createAnswer",
-		func(desc *js.Object) {
-			s = NewSessionDescriptionFromObj(desc)
-			wg.Done()
-		},
-		func(e *js.Object) {
-			err = fmt.Errorf("create answer failed: %s", e)
-			wg.Done()
-		},
-		nil,
-	)
-	wg.Wait()
-	return
+	desc, err := await(ctx, pc.pc.Call("createAnswer"))
+	if err != nil {
+		if ctx.Err() == nil {
+			err = fmt.Errorf("create answer failed: %s", err)
+		}
+		return nil, err
+	}
+	return NewSessionDescriptionFromObj(desc), nil
 }
 
 // CreateDataChannel ...
-func (pc *PeerConnection) CreateDataChannel(label string) (dc *DataChannel, err error) {
+func (pc *PeerConnection) CreateDataChannel(label string, opts ...*DataChannelInit) (dc *DataChannel, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("%s", r)
 		}
 	}()
-	jdc := pc.pc.Call("createDataChannel", label)
+	p, err := dataChannelOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	jdc := pc.pc.Call("createDataChannel", label, p.dict())
 	if jdc == nil {
 		return nil, fmt.Errorf("create data channel: failed")
 	}
-	dc = &DataChannel{dc: jdc}
+	dc = newDataChannel(pc, jdc)
 	return
 }
 
 // CreateOffer ...
-func (pc *PeerConnection) CreateOffer() (s *SessionDescription, err error) {
+func (pc *PeerConnection) CreateOffer() (*SessionDescription, error) {
+	return pc.CreateOfferContext(context.Background())
+}
+
+// CreateOfferContext ...
+func (pc *PeerConnection) CreateOfferContext(ctx context.Context) (s *SessionDescription, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("%s", r)
 		}
 	}()
-	wg := sync.WaitGroup{}
-	wg.Add(1)
-	pc.pc.Call(
-		"createOffer",
-		func(desc *js.Object) {
-			s = NewSessionDescriptionFromObj(desc)
-			wg.Done()
-		},
-		func(e *js.Object) {
-			err = fmt.Errorf("create offer failed: %s", e)
-			wg.Done()
-		},
-		nil,
-	)
-	wg.Wait()
-	return
+	desc, err := await(ctx, pc.pc.Call("createOffer"))
+	if err != nil {
+		if ctx.Err() == nil {
+			err = fmt.Errorf("create offer failed: %s", err)
+		}
+		return nil, err
+	}
+	return NewSessionDescriptionFromObj(desc), nil
+}
+
+// GetStats ...
+func (pc *PeerConnection) GetStats(ctx context.Context) (r *StatsReport, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("%s", e)
+		}
+	}()
+	report, err := await(ctx, pc.pc.Call("getStats"))
+	if err != nil {
+		if ctx.Err() == nil {
+			err = fmt.Errorf("get stats failed: %s", err)
+		}
+		return nil, err
+	}
+	r = &StatsReport{}
+	stringify := js.Global.Get("JSON").Get("stringify")
+	report.Call("forEach", func(stat *js.Object) {
+		if e := r.addJSON([]byte(stringify.Invoke(stat).String())); e != nil && err == nil {
+			err = e
+		}
+	})
+	return r, err
 }
 
 // IceGatheringState ...
-func (pc *PeerConnection) IceGatheringState() string {
-	return pc.pc.Get("iceGatheringState").String()
+func (pc *PeerConnection) IceGatheringState() IceGatheringState {
+	state, _ := ParseIceGatheringState(pc.pc.Get("iceGatheringState").String())
+	return state
+}
+
+// IceConnectionState ...
+func (pc *PeerConnection) IceConnectionState() IceConnectionState {
+	state, _ := ParseIceConnectionState(pc.pc.Get("iceConnectionState").String())
+	return state
 }
 
 // LocalDescription ...
 func (pc *PeerConnection) LocalDescription() (sdp *SessionDescription) {
 	sd := pc.pc.Get("localDescription")
+	if sd == nil {
+		return nil
+	}
 	return NewSessionDescriptionFromObj(sd)
 }
 
 // RemoteDescription ...
 func (pc *PeerConnection) RemoteDescription() (sdp *SessionDescription) {
 	sd := pc.pc.Get("remoteDescription")
+	if sd == nil {
+		return nil
+	}
 	return NewSessionDescriptionFromObj(sd)
 }
 
 // SetLocalDescription ...
-func (pc *PeerConnection) SetLocalDescription(sdp *SessionDescription) (err error) {
+func (pc *PeerConnection) SetLocalDescription(sdp *SessionDescription) error {
+	return pc.SetLocalDescriptionContext(context.Background(), sdp)
+}
+
+// SetLocalDescriptionContext ...
+func (pc *PeerConnection) SetLocalDescriptionContext(ctx context.Context, sdp *SessionDescription) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("%s", r)
 		}
 	}()
-	pc.pc.Call("setLocalDescription", sdp.o)
+	_, err = await(ctx, pc.pc.Call("setLocalDescription", sdp.o))
 	return
 }
 
 // SetRemoteDescription ...
-func (pc *PeerConnection) SetRemoteDescription(sdp *SessionDescription) (err error) {
+func (pc *PeerConnection) SetRemoteDescription(sdp *SessionDescription) error {
+	return pc.SetRemoteDescriptionContext(context.Background(), sdp)
+}
+
+// SetRemoteDescriptionContext ...
+func (pc *PeerConnection) SetRemoteDescriptionContext(ctx context.Context, sdp *SessionDescription) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("%s", r)
 		}
 	}()
-	pc.pc.Call("setRemoteDescription", sdp.o)
+	if _, err = await(ctx, pc.pc.Call("setRemoteDescription", sdp.o)); err != nil {
+		return
+	}
+	if sdp.Type != "rollback" {
+		pc.candidates.flush(func(ic *IceCandidate) error {
+			return pc.addIceCandidate(ctx, ic)
+		})
+	}
 	return
 }
 
 // SignalingState ...
-func (pc *PeerConnection) SignalingState() string {
-	return pc.pc.Get("signalingState").String()
+func (pc *PeerConnection) SignalingState() SignalingState {
+	state, _ := ParseSignalingState(pc.pc.Get("signalingState").String())
+	return state
 }
 
 // DataChannel ...
 type DataChannel struct {
-	dc *js.Object
+	events broker
+	low    notifier
+	pc     *PeerConnection
+	dc     *js.Object
+}
+
+// newMessage converts the data of a message event. Binary messages arrive
+// as ArrayBuffer because newDataChannel sets binaryType.
+func newMessage(data *js.Object) Message {
+	if s, ok := data.Interface().(string); ok {
+		return Message{Data: []byte(s), IsString: true}
+	}
+	if data.Get("buffer") != js.Undefined {
+		data = js.Global.Get("Uint8Array").New(
+			data.Get("buffer"), data.Get("byteOffset"), data.Get("byteLength"),
+		)
+	} else {
+		data = js.Global.Get("Uint8Array").New(data)
+	}
+	return Message{Data: data.Interface().([]byte)}
+}
+
+func newDataChannel(pc *PeerConnection, dc *js.Object) *DataChannel {
+	c := &DataChannel{pc: pc, dc: dc}
+	c.events.hold()
+	// Blob payloads can only be read asynchronously, which would reorder
+	// messages.
+	dc.Set("binaryType", "arraybuffer")
+	dc.Call("addEventListener", "open",
+		func(ev *js.Object) {
+			c.events.publish(OpenEvent{})
+		}, false,
+	)
+	dc.Call("addEventListener", "close",
+		func(ev *js.Object) {
+			c.events.publish(CloseEvent{})
+			c.events.close()
+		}, false,
+	)
+	dc.Call("addEventListener", "message",
+		func(ev *js.Object) {
+			m := newMessage(ev.Get("data"))
+			c.events.publish(MessageEvent{Data: m.Data, IsString: m.IsString})
+		}, false,
+	)
+	dc.Call("addEventListener", "bufferedamountlow",
+		func(ev *js.Object) {
+			c.low.broadcast()
+			c.events.publish(BufferedAmountLowEvent{})
+		}, false,
+	)
+	return c
 }
 
 // OnOpen ...
@@ -324,9 +648,44 @@ func (c *DataChannel) OnClose(cb func()) {
 func (c *DataChannel) OnMessage(cb func([]byte)) {
 	c.dc.Call("addEventListener", "message",
 		func(ev *js.Object) {
-			cb(ev.Get("data").Interface().([]byte))
+			cb(newMessage(ev.Get("data")).Data)
+		}, false,
+	)
+	c.events.release()
+}
+
+// OnMessageData is OnMessage with the frame type.
+func (c *DataChannel) OnMessageData(cb func(Message)) {
+	c.dc.Call("addEventListener", "message",
+		func(ev *js.Object) {
+			cb(newMessage(ev.Get("data")))
 		}, false,
 	)
+	c.events.release()
+}
+
+// OnBufferedAmountLow ...
+func (c *DataChannel) OnBufferedAmountLow(cb func()) {
+	c.dc.Call("addEventListener", "bufferedamountlow",
+		func(ev *js.Object) {
+			cb()
+		}, false,
+	)
+}
+
+// BufferedAmount ...
+func (c *DataChannel) BufferedAmount() int {
+	return c.dc.Get("bufferedAmount").Int()
+}
+
+// BufferedAmountLowThreshold ...
+func (c *DataChannel) BufferedAmountLowThreshold() int {
+	return c.dc.Get("bufferedAmountLowThreshold").Int()
+}
+
+// SetBufferedAmountLowThreshold ...
+func (c *DataChannel) SetBufferedAmountLowThreshold(n int) {
+	c.dc.Set("bufferedAmountLowThreshold", n)
 }
 
 // Close ...
@@ -350,9 +709,28 @@ func (c *DataChannel) ReadyState() string {
 	return c.dc.Get("readyState").String()
 }
 
-// Send ...
+// Send sends data. Failures are logged; SendContext returns them.
 func (c *DataChannel) Send(data []byte) {
-	c.dc.Call("send", data)
+	c.sendFailed(c.sendMessage(data, false))
+}
+
+// SendText sends text as a string message.
+func (c *DataChannel) SendText(text string) {
+	c.sendFailed(c.sendMessage([]byte(text), true))
+}
+
+func (c *DataChannel) sendMessage(data []byte, isString bool) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s", r)
+		}
+	}()
+	if isString {
+		c.dc.Call("send", string(data))
+	} else {
+		c.dc.Call("send", data)
+	}
+	return nil
 }
 
 // Label ...
@@ -360,31 +738,189 @@ func (c *DataChannel) Label() string {
 	return c.dc.Get("label").String()
 }
 
+// Ordered ...
+func (c *DataChannel) Ordered() bool {
+	return c.dc.Get("ordered").Bool()
+}
+
+// MaxPacketLifeTime returns -1 if unset.
+func (c *DataChannel) MaxPacketLifeTime() int {
+	v := c.dc.Get("maxPacketLifeTime")
+	if v == nil || v == js.Undefined {
+		return -1
+	}
+	return v.Int()
+}
+
+// MaxRetransmits returns -1 if unset.
+func (c *DataChannel) MaxRetransmits() int {
+	v := c.dc.Get("maxRetransmits")
+	if v == nil || v == js.Undefined {
+		return -1
+	}
+	return v.Int()
+}
+
+// Protocol ...
+func (c *DataChannel) Protocol() string {
+	return c.dc.Get("protocol").String()
+}
+
+// Negotiated ...
+func (c *DataChannel) Negotiated() bool {
+	return c.dc.Get("negotiated").Bool()
+}
+
 // MediaStream ...
 type MediaStream struct {
 	o *js.Object
 }
 
+// NewMediaStream returns an empty stream. Browsers choose the id of the
+// stream themselves, so id is ignored.
+func NewMediaStream(id string) *MediaStream {
+	return &MediaStream{o: js.Global.Get("MediaStream").New()}
+}
+
+// ID ...
+func (s *MediaStream) ID() string {
+	return s.o.Get("id").String()
+}
+
+// GetTracks ...
+func (s *MediaStream) GetTracks() []*MediaStreamTrack {
+	tracks := []*MediaStreamTrack{}
+	list := s.o.Call("getTracks")
+	for i := 0; i < list.Length(); i++ {
+		tracks = append(tracks, &MediaStreamTrack{o: list.Index(i)})
+	}
+	return tracks
+}
+
+// AddTrack ...
+func (s *MediaStream) AddTrack(t *MediaStreamTrack) {
+	s.o.Call("addTrack", t.o)
+}
+
+// MediaStreamTrack ...
+type MediaStreamTrack struct {
+	o *js.Object
+}
+
+// ID ...
+func (t *MediaStreamTrack) ID() string {
+	return t.o.Get("id").String()
+}
+
+// Kind ...
+func (t *MediaStreamTrack) Kind() string {
+	return t.o.Get("kind").String()
+}
+
+// Stop ...
+func (t *MediaStreamTrack) Stop() {
+	t.o.Call("stop")
+}
+
 // GetUserMedia ...
-func GetUserMedia(constraints *Constraints) (stream *MediaStream, err error) {
+func GetUserMedia(constraints *Constraints) (*MediaStream, error) {
+	return GetUserMediaContext(context.Background(), constraints)
+}
+
+// GetUserMediaContext ...
+func GetUserMediaContext(ctx context.Context, constraints *Constraints) (stream *MediaStream, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("%s", r)
 		}
 	}()
-	wg := sync.WaitGroup{}
-	wg.Add(1)
-	go func() {
-		mediaDevices := navigator.Get("mediaDevices")
-		p := mediaDevices.Call("getUserMedia", constraints)
-		p.Call("then", func(ev *js.Object) {
-			stream = &MediaStream{o: ev}
-			wg.Done()
-		}).Call("catch", func(e *js.Object) {
-			err = fmt.Errorf("get user media failed: %s", e)
-			wg.Done()
-		})
+	mediaDevices := navigator.Get("mediaDevices")
+	o, err := await(ctx, mediaDevices.Call("getUserMedia", constraints))
+	if err != nil {
+		if ctx.Err() == nil {
+			err = fmt.Errorf("get user media failed: %s", err)
+		}
+		return nil, err
+	}
+	return &MediaStream{o: o}, nil
+}
+
+// RTPTransceiver ...
+type RTPTransceiver struct {
+	o *js.Object
+}
+
+// Mid returns "" until the transceiver is negotiated.
+func (t *RTPTransceiver) Mid() string {
+	if o := t.o.Get("mid"); o != nil {
+		return o.String()
+	}
+	return ""
+}
+
+// Kind ...
+func (t *RTPTransceiver) Kind() string {
+	return t.o.Get("receiver").Get("track").Get("kind").String()
+}
+
+// Direction ...
+func (t *RTPTransceiver) Direction() RTPTransceiverDirection {
+	d, _ := ParseRTPTransceiverDirection(t.o.Get("direction").String())
+	return d
+}
+
+// SetDirection ...
+func (t *RTPTransceiver) SetDirection(d RTPTransceiverDirection) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s", r)
+		}
 	}()
-	wg.Wait()
+	t.o.Set("direction", d.String())
 	return
 }
+
+// Stop ...
+func (t *RTPTransceiver) Stop() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s", r)
+		}
+	}()
+	t.o.Call("stop")
+	return
+}
+
+// Sender ...
+func (t *RTPTransceiver) Sender() *RTPSender {
+	return &RTPSender{o: t.o.Get("sender")}
+}
+
+// Receiver ...
+func (t *RTPTransceiver) Receiver() *RTPReceiver {
+	return &RTPReceiver{o: t.o.Get("receiver")}
+}
+
+// RTPSender ...
+type RTPSender struct {
+	o *js.Object
+}
+
+// Track returns nil if the sender has no track.
+func (s *RTPSender) Track() *MediaStreamTrack {
+	o := s.o.Get("track")
+	if o == nil {
+		return nil
+	}
+	return &MediaStreamTrack{o: o}
+}
+
+// RTPReceiver ...
+type RTPReceiver struct {
+	o *js.Object
+}
+
+// Track ...
+func (r *RTPReceiver) Track() *MediaStreamTrack {
+	return &MediaStreamTrack{o: r.o.Get("track")}
+}
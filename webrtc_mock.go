@@ -0,0 +1,1619 @@
+// +build !js,mock
+
+package webrtc
+
+// The mock backend, selected with the "mock" build tag, connects
+// PeerConnections of the same process in memory. It follows the W3C
+// signaling state machine, gathers one host candidate per PeerConnection
+// and delivers DataChannel messages in order, so applications can be tested
+// with plain "go test -tags mock". Tracks, transceivers and the first
+// DataChannel reach the peer only after the offer/answer exchange that
+// negotiationneeded asks for. Tracks carry the samples written to them as
+// minimal RTP packets; GetUserMedia is not supported.
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nobonobo/webrtc/sdp"
+)
+
+// mockPeerAttr carries the ID of the mock PeerConnection in its SDP.
+const mockPeerAttr = "x-mock-peer"
+
+var mockNet = struct {
+	sync.Mutex
+	lastID   int
+	lastSSRC uint32
+	peers    map[int]*PeerConnection
+}{peers: map[int]*PeerConnection{}}
+
+// canRollback reports whether rollback descriptions are accepted.
+var canRollback = true
+
+// SetLoggingVerbosity is a no-op on the mock backend.
+func SetLoggingVerbosity(level int) {}
+
+// PeerConnection ...
+type PeerConnection struct {
+	candidates candidateQueue
+	gathering  gatheringState
+	events     broker
+	seen       candidateLog
+	log        pcLog
+	loop       dispatcher
+	streams    streamSet
+
+	mu            sync.Mutex
+	channels      []*DataChannel
+	transceivers  []*RTPTransceiver
+	id            int
+	version       int
+	signaling     SignalingState
+	iceState      IceConnectionState
+	connState     PeerConnectionState
+	local         *SessionDescription
+	remote        *SessionDescription
+	stableLocal   *SessionDescription
+	stableRemote  *SessionDescription
+	offerer       bool
+	peer          *PeerConnection
+	nextChannelID int
+	sctp          bool // a data channel section is negotiated
+	pending       bool // changes wait for an offer/answer exchange
+	proposed      bool // the current offer carries changes
+	negotiating   bool
+	closed        bool
+
+	onNegotiationNeeded        func()
+	onIceCandidate             func(*IceCandidate)
+	onIceCandidateError        func()
+	onSignalingStateChange     func(SignalingState)
+	onIceConnectionStateChange func(IceConnectionState)
+	onIceGatheringStateChange  func(IceGatheringState)
+	onConnectionStateChange    func(PeerConnectionState)
+	onDataChannel              func(*DataChannel)
+	onTrack                    func(*MediaStreamTrack, []*MediaStream)
+}
+
+// NewPeerConnection ...
+func NewPeerConnection(config *Configuration) (*PeerConnection, error) {
+	pc := &PeerConnection{
+		signaling: SignalingStateStable,
+		iceState:  IceConnectionStateNew,
+		connState: PeerConnectionStateNew,
+	}
+	pc.log.init()
+	pc.gathering.set(IceGatheringStateNew)
+	mockNet.Lock()
+	mockNet.lastID++
+	pc.id = mockNet.lastID
+	mockNet.peers[pc.id] = pc
+	mockNet.Unlock()
+	return pc, nil
+}
+
+// emit publishes ev and then calls the callback cb returns, on the event
+// loop of the PeerConnection.
+func (pc *PeerConnection) emit(ev Event, cb func() func()) {
+	pc.loop.post(func() {
+		pc.publish(ev)
+		pc.mu.Lock()
+		f := cb()
+		pc.mu.Unlock()
+		if f != nil {
+			f()
+		}
+	})
+}
+
+// OnNegotiationNeeded ...
+func (pc *PeerConnection) OnNegotiationNeeded(cb func()) {
+	pc.mu.Lock()
+	pc.onNegotiationNeeded = cb
+	pc.mu.Unlock()
+}
+
+// OnIceCandidate ...
+func (pc *PeerConnection) OnIceCandidate(cb func(*IceCandidate)) {
+	pc.mu.Lock()
+	pc.onIceCandidate = cb
+	pc.mu.Unlock()
+}
+
+// OnIceCandidateError is never called on the mock backend.
+func (pc *PeerConnection) OnIceCandidateError(cb func()) {
+	pc.mu.Lock()
+	pc.onIceCandidateError = cb
+	pc.mu.Unlock()
+}
+
+// OnSignalingStateChange ...
+func (pc *PeerConnection) OnSignalingStateChange(cb func(SignalingState)) {
+	pc.mu.Lock()
+	pc.onSignalingStateChange = cb
+	pc.mu.Unlock()
+}
+
+// OnIceConnectionStateChange ...
+func (pc *PeerConnection) OnIceConnectionStateChange(cb func(IceConnectionState)) {
+	pc.mu.Lock()
+	pc.onIceConnectionStateChange = cb
+	pc.mu.Unlock()
+}
+
+// OnIceGatheringStateChange ...
+func (pc *PeerConnection) OnIceGatheringStateChange(cb func(IceGatheringState)) {
+	pc.mu.Lock()
+	pc.onIceGatheringStateChange = cb
+	pc.mu.Unlock()
+}
+
+// OnConnectionStateChange ...
+func (pc *PeerConnection) OnConnectionStateChange(cb func(PeerConnectionState)) {
+	pc.mu.Lock()
+	pc.onConnectionStateChange = cb
+	pc.mu.Unlock()
+}
+
+// OnDataChannel ...
+func (pc *PeerConnection) OnDataChannel(cb func(*DataChannel)) {
+	pc.mu.Lock()
+	pc.onDataChannel = cb
+	pc.mu.Unlock()
+}
+
+// OnAddStream is never called on the mock backend.
+func (pc *PeerConnection) OnAddStream(cb func(*MediaStream)) {
+	pc.neverCalled("OnAddStream")
+}
+
+// OnRemoveStream is never called on the mock backend.
+func (pc *PeerConnection) OnRemoveStream(cb func(*MediaStream)) {
+	pc.neverCalled("OnRemoveStream")
+}
+
+// AddStream ...
+func (pc *PeerConnection) AddStream(stream *MediaStream) error {
+	return ErrMediaNotSupported
+}
+
+// OnTrack is called with the tracks the peer adds and the streams they
+// belong to.
+func (pc *PeerConnection) OnTrack(cb func(*MediaStreamTrack, []*MediaStream)) {
+	pc.mu.Lock()
+	pc.onTrack = cb
+	pc.mu.Unlock()
+}
+
+// AddTrack sends a track made by NewSampleTrack, on a transceiver of its
+// kind added by AddTransceiver if one is unused. Only the first stream is
+// signaled.
+func (pc *PeerConnection) AddTrack(track *MediaStreamTrack, streams ...*MediaStream) error {
+	if track.remote {
+		return errors.New("add track: remote tracks cannot be sent")
+	}
+	pc.mu.Lock()
+	if pc.closed {
+		pc.mu.Unlock()
+		return errors.New("add track: closed")
+	}
+	var t *RTPTransceiver
+	for _, v := range pc.transceivers {
+		if v.sender.track == track && v.direction != RTPTransceiverDirectionStopped {
+			pc.mu.Unlock()
+			return errors.New("add track: track already added")
+		}
+		if t == nil && v.kind == track.kind && v.sender.track == nil && !v.sender.used &&
+			v.direction != RTPTransceiverDirectionStopped {
+			t = v
+		}
+	}
+	if t == nil {
+		t = pc.addTransceiver(track.kind, RTPTransceiverDirectionSendrecv)
+	}
+	switch t.direction {
+	case RTPTransceiverDirectionRecvonly:
+		t.direction = RTPTransceiverDirectionSendrecv
+	case RTPTransceiverDirectionInactive:
+		t.direction = RTPTransceiverDirectionSendonly
+	}
+	t.sender.track = track
+	t.sender.used = true
+	t.sender.streamID = streamID(streams)
+	pc.needNegotiation()
+	pc.mu.Unlock()
+	return nil
+}
+
+// RemoveTrack stops sending a track added by AddTrack. The track ends on the
+// peer after the next offer/answer exchange.
+func (pc *PeerConnection) RemoveTrack(track *MediaStreamTrack) error {
+	pc.mu.Lock()
+	var t *RTPTransceiver
+	for _, v := range pc.transceivers {
+		if v.sender.track == track {
+			t = v
+			break
+		}
+	}
+	if t == nil {
+		pc.mu.Unlock()
+		return errors.New("remove track: track not added")
+	}
+	t.sender.track = nil
+	switch t.direction {
+	case RTPTransceiverDirectionSendrecv:
+		t.direction = RTPTransceiverDirectionRecvonly
+	case RTPTransceiverDirectionSendonly:
+		t.direction = RTPTransceiverDirectionInactive
+	}
+	pc.needNegotiation()
+	pc.mu.Unlock()
+	return nil
+}
+
+// AddTransceiver adds a transceiver of kind "audio" or "video". The mock
+// backend sets its mid at once; the peer sees it after the next offer/answer
+// exchange.
+func (pc *PeerConnection) AddTransceiver(kind string, direction RTPTransceiverDirection) (*RTPTransceiver, error) {
+	if kind != "audio" && kind != "video" {
+		return nil, fmt.Errorf("add transceiver: unknown kind %q", kind)
+	}
+	if direction == RTPTransceiverDirectionUnknown || direction == RTPTransceiverDirectionStopped {
+		return nil, fmt.Errorf("add transceiver: invalid direction %s", direction)
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if pc.closed {
+		return nil, errors.New("add transceiver: closed")
+	}
+	t := pc.addTransceiver(kind, direction)
+	pc.needNegotiation()
+	return t, nil
+}
+
+func (pc *PeerConnection) addTransceiver(kind string, direction RTPTransceiverDirection) *RTPTransceiver {
+	t := &RTPTransceiver{
+		pc:        pc,
+		kind:      kind,
+		mid:       strconv.Itoa(len(pc.transceivers)),
+		direction: direction,
+		sender:    &RTPSender{pc: pc},
+		receiver:  &RTPReceiver{pc: pc},
+	}
+	pc.transceivers = append(pc.transceivers, t)
+	return t
+}
+
+// GetTransceivers returns the transceivers not stopped.
+func (pc *PeerConnection) GetTransceivers() []*RTPTransceiver {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	list := []*RTPTransceiver{}
+	for _, t := range pc.transceivers {
+		if t.direction != RTPTransceiverDirectionStopped {
+			list = append(list, t)
+		}
+	}
+	return list
+}
+
+// GetSenders ...
+func (pc *PeerConnection) GetSenders() []*RTPSender {
+	list := []*RTPSender{}
+	for _, t := range pc.GetTransceivers() {
+		list = append(list, t.sender)
+	}
+	return list
+}
+
+// GetReceivers ...
+func (pc *PeerConnection) GetReceivers() []*RTPReceiver {
+	list := []*RTPReceiver{}
+	for _, t := range pc.GetTransceivers() {
+		list = append(list, t.receiver)
+	}
+	return list
+}
+
+// needNegotiation records a change that the peer only sees after the next
+// offer/answer exchange, and fires negotiationneeded for it. It is called
+// with pc.mu held.
+func (pc *PeerConnection) needNegotiation() {
+	pc.pending = true
+	pc.fireNegotiationNeeded()
+}
+
+// fireNegotiationNeeded fires negotiationneeded once for the pending
+// changes, when the signaling state is stable. It is called with pc.mu held.
+func (pc *PeerConnection) fireNegotiationNeeded() {
+	if !pc.pending || pc.negotiating || pc.closed || pc.signaling != SignalingStateStable {
+		return
+	}
+	pc.negotiating = true
+	pc.emit(NegotiationNeededEvent{}, func() func() {
+		return pc.onNegotiationNeeded
+	})
+}
+
+// propose puts the pending changes in the offer being set or answered. It is
+// called with pc.mu held.
+func (pc *PeerConnection) propose() {
+	pc.proposed = pc.proposed || pc.pending
+	pc.pending = false
+	for _, t := range pc.transceivers {
+		t.offered = &sendState{
+			direction: t.direction,
+			track:     t.sender.track,
+			streamID:  t.sender.streamID,
+		}
+	}
+}
+
+// rollback returns the proposed changes to the pending ones. It is called
+// with pc.mu held.
+func (pc *PeerConnection) rollback() {
+	pc.local, pc.remote = pc.stableLocal, pc.stableRemote
+	pc.pending = pc.pending || pc.proposed
+	pc.proposed = false
+	pc.negotiating = false
+	for _, t := range pc.transceivers {
+		t.offered = nil
+	}
+	pc.setSignalingState(SignalingStateStable)
+	pc.fireNegotiationNeeded()
+}
+
+// receive hands a track sent by the peer to a transceiver of its kind that
+// receives and has no live track, or to a new recvonly one.
+func (pc *PeerConnection) receive(rt *MediaStreamTrack, streamID string) {
+	pc.mu.Lock()
+	if pc.closed {
+		pc.mu.Unlock()
+		rt.end()
+		return
+	}
+	var t *RTPTransceiver
+	for _, v := range pc.transceivers {
+		receives := v.direction == RTPTransceiverDirectionSendrecv || v.direction == RTPTransceiverDirectionRecvonly
+		if v.kind == rt.kind && receives && (v.receiver.track == nil || v.receiver.track.isEnded()) {
+			t = v
+			break
+		}
+	}
+	if t == nil {
+		t = pc.addTransceiver(rt.kind, RTPTransceiverDirectionRecvonly)
+	}
+	t.receiver.track = rt
+	pc.mu.Unlock()
+	streams := pc.streams.add(streamID, rt)
+	pc.emit(TrackEvent{Track: rt, Streams: streams}, func() func() {
+		if cb := pc.onTrack; cb != nil {
+			return func() { cb(rt, streams) }
+		}
+		return nil
+	})
+}
+
+// OnRemoteCandidateError is called with remote candidates that failed to
+// apply once the remote description was set, or were dropped with
+// ErrCandidateDropped.
+func (pc *PeerConnection) OnRemoteCandidateError(cb func(*IceCandidate, error)) {
+	pc.candidates.setOnError(cb)
+}
+
+// AddIceCandidate adds a remote candidate. Candidates added before the remote
+// description is set are queued and applied by SetRemoteDescription.
+func (pc *PeerConnection) AddIceCandidate(ic *IceCandidate) error {
+	return pc.candidates.add(ic, pc.addIceCandidate)
+}
+
+// AddIceCandidateContext ...
+func (pc *PeerConnection) AddIceCandidateContext(ctx context.Context, ic *IceCandidate) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return pc.AddIceCandidate(ic)
+}
+
+func (pc *PeerConnection) addIceCandidate(ic *IceCandidate) error {
+	if _, err := sdp.ParseCandidate(ic.Candidate); err != nil {
+		return err
+	}
+	pc.seen.addRemote(ic)
+	return nil
+}
+
+// candidatePair returns the only candidate of each side.
+func (pc *PeerConnection) candidatePair() (local, remote *IceCandidate) {
+	return pc.seen.pair(pc.LocalDescription(), pc.RemoteDescription())
+}
+
+// Close closes the PeerConnection and its DataChannels. The peer sees its
+// connection become disconnected.
+func (pc *PeerConnection) Close() error {
+	pc.mu.Lock()
+	if pc.closed {
+		pc.mu.Unlock()
+		return nil
+	}
+	pc.closed = true
+	pc.signaling = SignalingStateClosed
+	pc.iceState = IceConnectionStateClosed
+	pc.connState = PeerConnectionStateClosed
+	channels := append([]*DataChannel{}, pc.channels...)
+	transceivers := pc.transceivers
+	peer := pc.peer
+	pc.mu.Unlock()
+
+	mockNet.Lock()
+	delete(mockNet.peers, pc.id)
+	mockNet.Unlock()
+	pc.candidates.drop()
+	for _, c := range channels {
+		c.Close()
+	}
+	for _, t := range transceivers {
+		t.update()
+		if rt := t.Receiver().Track(); rt != nil {
+			rt.end()
+		}
+	}
+	if peer != nil {
+		peer.setIceState(IceConnectionStateDisconnected)
+		peer.setConnState(PeerConnectionStateDisconnected)
+	}
+	pc.loop.post(pc.events.close)
+	return nil
+}
+
+// ConnectionState ...
+func (pc *PeerConnection) ConnectionState() PeerConnectionState {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	return pc.connState
+}
+
+// IceConnectionState ...
+func (pc *PeerConnection) IceConnectionState() IceConnectionState {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	return pc.iceState
+}
+
+// IceGatheringState ...
+func (pc *PeerConnection) IceGatheringState() IceGatheringState {
+	return pc.gathering.get()
+}
+
+// SignalingState ...
+func (pc *PeerConnection) SignalingState() SignalingState {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	return pc.signaling
+}
+
+func (pc *PeerConnection) setSignalingState(s SignalingState) {
+	pc.signaling = s
+	pc.emit(SignalingStateChangeEvent{State: s}, func() func() {
+		if cb := pc.onSignalingStateChange; cb != nil {
+			return func() { cb(s) }
+		}
+		return nil
+	})
+}
+
+func (pc *PeerConnection) setIceState(s IceConnectionState) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if pc.closed || pc.iceState == s {
+		return
+	}
+	pc.iceState = s
+	pc.emit(IceConnectionStateChangeEvent{State: s}, func() func() {
+		if cb := pc.onIceConnectionStateChange; cb != nil {
+			return func() { cb(s) }
+		}
+		return nil
+	})
+}
+
+func (pc *PeerConnection) setConnState(s PeerConnectionState) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if pc.closed || pc.connState == s {
+		return
+	}
+	pc.connState = s
+	pc.emit(ConnectionStateChangeEvent{State: s}, func() func() {
+		if cb := pc.onConnectionStateChange; cb != nil {
+			return func() { cb(s) }
+		}
+		return nil
+	})
+}
+
+func (pc *PeerConnection) setGatheringState(s IceGatheringState) {
+	pc.emit(IceGatheringStateChangeEvent{State: s}, func() func() {
+		pc.gathering.set(s)
+		if cb := pc.onIceGatheringStateChange; cb != nil {
+			return func() { cb(s) }
+		}
+		return nil
+	})
+}
+
+// candidate is the host candidate of the PeerConnection.
+func (pc *PeerConnection) candidate() *IceCandidate {
+	return NewIceCandidate(
+		fmt.Sprintf("candidate:%d 1 udp 2122260223 127.0.0.1 %d typ host generation 0", pc.id, 50000+pc.id),
+		"0", 0,
+	)
+}
+
+// gather runs candidate gathering once, after the first local description.
+func (pc *PeerConnection) gather() {
+	if pc.gathering.get() != IceGatheringStateNew {
+		return
+	}
+	pc.gathering.set(IceGatheringStateGathering)
+	pc.setGatheringState(IceGatheringStateGathering)
+	ic := pc.candidate()
+	pc.emit(IceCandidateEvent{Candidate: ic}, func() func() {
+		pc.seen.addLocal(ic)
+		if pc.local != nil {
+			pc.local = withCandidate(pc.local, ic)
+		}
+		if cb := pc.onIceCandidate; cb != nil {
+			return func() { cb(ic) }
+		}
+		return nil
+	})
+	pc.setGatheringState(IceGatheringStateComplete)
+}
+
+// withCandidate returns sd with ic added to its media section.
+func withCandidate(sd *SessionDescription, ic *IceCandidate) *SessionDescription {
+	sd = NewSessionDescription(sd.Type, sd.Sdp)
+	sd.Edit(func(s *sdp.Session) error {
+		c, err := sdp.ParseCandidate(ic.Candidate)
+		if err != nil || len(s.Media) == 0 {
+			return err
+		}
+		s.Media[0].AddCandidate(c)
+		return nil
+	})
+	return sd
+}
+
+// description returns the SDP of an offer or answer of the PeerConnection.
+// It has a data channel section once a channel was created, or when
+// answering an offer with one.
+func (pc *PeerConnection) description(typ, setup string) *SessionDescription {
+	pc.version++
+	sd := fmt.Sprintf(
+		"v=0\r\n"+
+			"o=- %d %d IN IP4 127.0.0.1\r\n"+
+			"s=-\r\n"+
+			"t=0 0\r\n"+
+			"a=%s:%d\r\n",
+		pc.id, pc.version, mockPeerAttr, pc.id,
+	)
+	application := pc.sctp || len(pc.channels) > 0
+	if typ != "offer" {
+		application = hasApplication(pc.remote)
+	}
+	if !application {
+		return NewSessionDescription(typ, sd)
+	}
+	return NewSessionDescription(typ, sd+fmt.Sprintf(
+		"a=group:BUNDLE 0\r\n"+
+			"m=application 9 UDP/DTLS/SCTP webrtc-datachannel\r\n"+
+			"c=IN IP4 0.0.0.0\r\n"+
+			"a=ice-ufrag:mock%d\r\n"+
+			"a=ice-pwd:mockpassword%016d\r\n"+
+			"a=fingerprint:sha-256 %s\r\n"+
+			"a=setup:%s\r\n"+
+			"a=mid:0\r\n"+
+			"a=sctp-port:5000\r\n"+
+			"a=max-message-size:262144\r\n",
+		pc.id, pc.id, mockFingerprint(pc.id), setup,
+	))
+}
+
+// hasApplication reports whether sd has a data channel section.
+func hasApplication(sd *SessionDescription) bool {
+	if sd == nil {
+		return false
+	}
+	s, err := sd.Session()
+	if err != nil {
+		return false
+	}
+	for _, m := range s.Media {
+		if m.Kind() == "application" {
+			return true
+		}
+	}
+	return false
+}
+
+func mockFingerprint(id int) string {
+	fp := ""
+	for i := 0; i < 32; i++ {
+		if i > 0 {
+			fp += ":"
+		}
+		fp += fmt.Sprintf("%02X", (id*31+i)&0xff)
+	}
+	return fp
+}
+
+// mockPeerID returns the ID of the mock PeerConnection that produced sd.
+func mockPeerID(sd *SessionDescription) (int, error) {
+	s, err := sd.Session()
+	if err != nil {
+		return 0, err
+	}
+	v, ok := s.Attribute(mockPeerAttr)
+	if !ok {
+		return 0, fmt.Errorf("mock: description is not from a mock PeerConnection")
+	}
+	return strconv.Atoi(v)
+}
+
+// CreateOffer ...
+func (pc *PeerConnection) CreateOffer() (*SessionDescription, error) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if pc.closed {
+		return nil, fmt.Errorf("create offer failed: closed")
+	}
+	return pc.description("offer", "actpass"), nil
+}
+
+// CreateOfferContext ...
+func (pc *PeerConnection) CreateOfferContext(ctx context.Context) (*SessionDescription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return pc.CreateOffer()
+}
+
+// CreateAnswer ...
+func (pc *PeerConnection) CreateAnswer() (*SessionDescription, error) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if pc.signaling != SignalingStateHaveRemoteOffer && pc.signaling != SignalingStateHaveLocalPrAnswer {
+		return nil, fmt.Errorf("create answer failed: signaling state is %s", pc.signaling)
+	}
+	return pc.description("answer", "active"), nil
+}
+
+// CreateAnswerContext ...
+func (pc *PeerConnection) CreateAnswerContext(ctx context.Context) (*SessionDescription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return pc.CreateAnswer()
+}
+
+// LocalDescription ...
+func (pc *PeerConnection) LocalDescription() *SessionDescription {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if pc.local == nil {
+		return nil
+	}
+	return NewSessionDescription(pc.local.Type, pc.local.Sdp)
+}
+
+// RemoteDescription ...
+func (pc *PeerConnection) RemoteDescription() *SessionDescription {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if pc.remote == nil {
+		return nil
+	}
+	return NewSessionDescription(pc.remote.Type, pc.remote.Sdp)
+}
+
+// SetLocalDescription ...
+func (pc *PeerConnection) SetLocalDescription(sd *SessionDescription) error {
+	pc.mu.Lock()
+	if pc.closed {
+		pc.mu.Unlock()
+		return fmt.Errorf("set local description failed: closed")
+	}
+	var next SignalingState
+	switch {
+	case sd.Type == "offer" && (pc.signaling == SignalingStateStable || pc.signaling == SignalingStateHaveLocalOffer):
+		next = SignalingStateHaveLocalOffer
+		if pc.peer == nil {
+			pc.offerer = true
+		}
+	case sd.Type == "answer" && (pc.signaling == SignalingStateHaveRemoteOffer || pc.signaling == SignalingStateHaveLocalPrAnswer):
+		next = SignalingStateStable
+	case sd.Type == "pranswer" && (pc.signaling == SignalingStateHaveRemoteOffer || pc.signaling == SignalingStateHaveLocalPrAnswer):
+		next = SignalingStateHaveLocalPrAnswer
+	case sd.Type == "rollback" && pc.signaling == SignalingStateHaveLocalOffer:
+		pc.rollback()
+		pc.mu.Unlock()
+		return nil
+	default:
+		state := pc.signaling
+		pc.mu.Unlock()
+		return fmt.Errorf("set local description failed: %s in signaling state %s", sd.Type, state)
+	}
+	if _, err := sd.Session(); err != nil {
+		pc.mu.Unlock()
+		return fmt.Errorf("set local description failed: %v", err)
+	}
+	if sd.Type == "offer" {
+		pc.propose()
+	}
+	pc.local = NewSessionDescription(sd.Type, sd.Sdp)
+	if pc.gathering.get() == IceGatheringStateComplete {
+		pc.local = withCandidate(pc.local, pc.candidate())
+	}
+	pc.commit(next)
+	pc.mu.Unlock()
+	pc.gather()
+	pc.connect()
+	return nil
+}
+
+// SetLocalDescriptionContext ...
+func (pc *PeerConnection) SetLocalDescriptionContext(ctx context.Context, sd *SessionDescription) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return pc.SetLocalDescription(sd)
+}
+
+// SetRemoteDescription ...
+func (pc *PeerConnection) SetRemoteDescription(sd *SessionDescription) error {
+	pc.mu.Lock()
+	if pc.closed {
+		pc.mu.Unlock()
+		return fmt.Errorf("set remote description failed: closed")
+	}
+	var next SignalingState
+	switch {
+	case sd.Type == "offer" && (pc.signaling == SignalingStateStable || pc.signaling == SignalingStateHaveRemoteOffer):
+		next = SignalingStateHaveRemoteOffer
+	case sd.Type == "answer" && (pc.signaling == SignalingStateHaveLocalOffer || pc.signaling == SignalingStateHaveRemotePrAnswer):
+		next = SignalingStateStable
+	case sd.Type == "pranswer" && (pc.signaling == SignalingStateHaveLocalOffer || pc.signaling == SignalingStateHaveRemotePrAnswer):
+		next = SignalingStateHaveRemotePrAnswer
+	case sd.Type == "rollback" && pc.signaling == SignalingStateHaveRemoteOffer:
+		pc.rollback()
+		pc.mu.Unlock()
+		return nil
+	default:
+		state := pc.signaling
+		pc.mu.Unlock()
+		return fmt.Errorf("set remote description failed: %s in signaling state %s", sd.Type, state)
+	}
+	if _, err := mockPeerID(sd); err != nil {
+		pc.mu.Unlock()
+		return fmt.Errorf("set remote description failed: %v", err)
+	}
+	if sd.Type == "offer" {
+		pc.propose()
+	}
+	pc.remote = NewSessionDescription(sd.Type, sd.Sdp)
+	pc.commit(next)
+	pc.mu.Unlock()
+	pc.candidates.flush(pc.addIceCandidate)
+	pc.connect()
+	return nil
+}
+
+// SetRemoteDescriptionContext ...
+func (pc *PeerConnection) SetRemoteDescriptionContext(ctx context.Context, sd *SessionDescription) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return pc.SetRemoteDescription(sd)
+}
+
+// commit moves to the next signaling state. Back in stable, it remembers
+// the descriptions for rollback and makes the offered changes current; the
+// changes made since fire negotiationneeded again.
+func (pc *PeerConnection) commit(next SignalingState) {
+	if next == SignalingStateStable {
+		pc.stableLocal, pc.stableRemote = pc.local, pc.remote
+		pc.sctp = hasApplication(pc.local) && hasApplication(pc.remote)
+		pc.proposed = false
+		pc.negotiating = false
+		for _, t := range pc.transceivers {
+			if t.offered != nil {
+				t.current, t.offered = *t.offered, nil
+			}
+		}
+	}
+	if next != pc.signaling {
+		pc.setSignalingState(next)
+	}
+	pc.fireNegotiationNeeded()
+}
+
+// connect pairs the PeerConnection with its peer once both have completed
+// an offer/answer exchange with each other. Once paired, it applies the
+// changes of each later exchange.
+func (pc *PeerConnection) connect() {
+	mockNet.Lock()
+	defer mockNet.Unlock()
+	pc.mu.Lock()
+	if pc.peer != nil {
+		stable := !pc.closed && pc.signaling == SignalingStateStable
+		pc.mu.Unlock()
+		if stable {
+			pc.renegotiated()
+		}
+		return
+	}
+	ready := !pc.closed && pc.signaling == SignalingStateStable && pc.remote != nil
+	var peerID int
+	if ready {
+		id, err := mockPeerID(pc.remote)
+		ready, peerID = err == nil, id
+	}
+	pc.mu.Unlock()
+	if !ready {
+		return
+	}
+	peer := mockNet.peers[peerID]
+	if peer == nil {
+		return
+	}
+	peer.mu.Lock()
+	ready = !peer.closed && peer.peer == nil && peer.signaling == SignalingStateStable && peer.remote != nil
+	if ready {
+		id, err := mockPeerID(peer.remote)
+		ready = err == nil && id == pc.id
+	}
+	if ready {
+		peer.peer = pc
+	}
+	peer.mu.Unlock()
+	if !ready {
+		return
+	}
+	pc.mu.Lock()
+	pc.peer = peer
+	pc.mu.Unlock()
+	for _, p := range []*PeerConnection{pc, peer} {
+		p.setIceState(IceConnectionStateChecking)
+		p.setConnState(PeerConnectionStateConnecting)
+		p.setIceState(IceConnectionStateConnected)
+		p.setConnState(PeerConnectionStateConnected)
+	}
+	pc.renegotiated()
+	peer.renegotiated()
+}
+
+// renegotiated opens the channels waiting for a data channel section and
+// sends or stops the tracks of the transceivers as negotiated.
+func (pc *PeerConnection) renegotiated() {
+	pc.mu.Lock()
+	var channels []*DataChannel
+	if pc.sctp {
+		channels = append(channels, pc.channels...)
+	}
+	transceivers := append([]*RTPTransceiver{}, pc.transceivers...)
+	pc.mu.Unlock()
+	for _, c := range channels {
+		c.announce()
+	}
+	for _, t := range transceivers {
+		t.update()
+	}
+}
+
+// CreateDataChannel ...
+func (pc *PeerConnection) CreateDataChannel(label string, opts ...*DataChannelInit) (*DataChannel, error) {
+	opt, err := dataChannelOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	pc.mu.Lock()
+	if pc.closed {
+		pc.mu.Unlock()
+		return nil, fmt.Errorf("create data channel: closed")
+	}
+	c := newDataChannel(pc, label, opt)
+	pc.channels = append(pc.channels, c)
+	// once a data channel section is negotiated, channels open in-band.
+	connected := pc.peer != nil && pc.sctp
+	if !pc.sctp {
+		pc.needNegotiation()
+	}
+	pc.mu.Unlock()
+	if connected {
+		c.announce()
+	}
+	return c, nil
+}
+
+// GetUserMedia ...
+func GetUserMedia(constraints *Constraints) (*MediaStream, error) {
+	return nil, ErrMediaNotSupported
+}
+
+// GetUserMediaContext ...
+func GetUserMediaContext(ctx context.Context, constraints *Constraints) (*MediaStream, error) {
+	return nil, ErrMediaNotSupported
+}
+
+// DataChannel ...
+type DataChannel struct {
+	events   broker
+	low      notifier
+	counters dataChannelCounters
+	pc       *PeerConnection
+
+	mu        sync.Mutex
+	label     string
+	opt       dataChannelParams
+	id        int
+	state     string
+	remote    *DataChannel
+	buffered  int
+	threshold int
+
+	onOpen              func()
+	onClose             func()
+	onMessage           func([]byte)
+	onMessageData       func(Message)
+	onBufferedAmountLow func()
+}
+
+// removeChannel forgets a closed channel, so that the list does not grow
+// with every channel ever opened.
+func (pc *PeerConnection) removeChannel(c *DataChannel) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	for i, ch := range pc.channels {
+		if ch == c {
+			pc.channels = append(pc.channels[:i], pc.channels[i+1:]...)
+			return
+		}
+	}
+}
+
+func newDataChannel(pc *PeerConnection, label string, opt dataChannelParams) *DataChannel {
+	c := &DataChannel{
+		pc:    pc,
+		label: label,
+		opt:   opt,
+		id:    opt.id,
+		state: "connecting",
+	}
+	c.events.hold()
+	return c
+}
+
+// announce opens the channel on the peer, or pairs a negotiated channel
+// with the peer's one of the same ID.
+func (c *DataChannel) announce() {
+	pc := c.pc
+	pc.mu.Lock()
+	peer := pc.peer
+	if peer == nil || c.remote != nil || c.state != "connecting" {
+		pc.mu.Unlock()
+		return
+	}
+	if !c.opt.negotiated {
+		if pc.offerer {
+			c.id = pc.nextChannelID * 2
+		} else {
+			c.id = pc.nextChannelID*2 + 1
+		}
+		pc.nextChannelID++
+	}
+	pc.mu.Unlock()
+
+	var rc *DataChannel
+	if c.opt.negotiated {
+		peer.mu.Lock()
+		for _, p := range peer.channels {
+			if p.opt.negotiated && p.id == c.id && p.remote == nil {
+				rc = p
+				break
+			}
+		}
+		peer.mu.Unlock()
+		if rc == nil {
+			// the peer pairs it when creating its side.
+			return
+		}
+	} else {
+		opt := c.opt
+		opt.id = c.id
+		rc = newDataChannel(peer, c.label, opt)
+		peer.mu.Lock()
+		peer.channels = append(peer.channels, rc)
+		peer.mu.Unlock()
+	}
+	pc.mu.Lock()
+	c.remote = rc
+	pc.mu.Unlock()
+	peer.mu.Lock()
+	rc.remote = c
+	peer.mu.Unlock()
+	if !c.opt.negotiated {
+		peer.emit(DataChannelEvent{Channel: rc}, func() func() {
+			if cb := peer.onDataChannel; cb != nil {
+				return func() { cb(rc) }
+			}
+			return nil
+		})
+	}
+	rc.open()
+	c.open()
+}
+
+func (c *DataChannel) open() {
+	c.pc.loop.post(func() {
+		c.mu.Lock()
+		if c.state != "connecting" {
+			c.mu.Unlock()
+			return
+		}
+		c.state = "open"
+		cb := c.onOpen
+		c.mu.Unlock()
+		c.events.publish(OpenEvent{})
+		if cb != nil {
+			cb()
+		}
+	})
+}
+
+// finish closes this end after the events queued before.
+func (c *DataChannel) finish() {
+	c.pc.loop.post(func() {
+		c.mu.Lock()
+		if c.state == "closed" {
+			c.mu.Unlock()
+			return
+		}
+		c.state = "closed"
+		cb := c.onClose
+		c.mu.Unlock()
+		c.pc.removeChannel(c)
+		c.events.publish(CloseEvent{})
+		c.events.close()
+		if cb != nil {
+			cb()
+		}
+	})
+}
+
+// OnOpen ...
+func (c *DataChannel) OnOpen(cb func()) {
+	c.mu.Lock()
+	c.onOpen = cb
+	c.mu.Unlock()
+}
+
+// OnClose ...
+func (c *DataChannel) OnClose(cb func()) {
+	c.mu.Lock()
+	c.onClose = cb
+	c.mu.Unlock()
+}
+
+// OnMessage ...
+func (c *DataChannel) OnMessage(cb func([]byte)) {
+	c.mu.Lock()
+	c.onMessage = cb
+	c.mu.Unlock()
+	c.events.release()
+}
+
+// OnMessageData ...
+func (c *DataChannel) OnMessageData(cb func(Message)) {
+	c.mu.Lock()
+	c.onMessageData = cb
+	c.mu.Unlock()
+	c.events.release()
+}
+
+// OnBufferedAmountLow ...
+func (c *DataChannel) OnBufferedAmountLow(cb func()) {
+	c.mu.Lock()
+	c.onBufferedAmountLow = cb
+	c.mu.Unlock()
+}
+
+// BufferedAmount is the size of the messages not yet delivered to the peer.
+func (c *DataChannel) BufferedAmount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.buffered
+}
+
+// BufferedAmountLowThreshold ...
+func (c *DataChannel) BufferedAmountLowThreshold() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.threshold
+}
+
+// SetBufferedAmountLowThreshold ...
+func (c *DataChannel) SetBufferedAmountLowThreshold(n int) {
+	c.mu.Lock()
+	c.threshold = n
+	c.mu.Unlock()
+}
+
+// Close closes both ends of the channel once the messages already sent are
+// delivered.
+func (c *DataChannel) Close() error {
+	c.mu.Lock()
+	if c.state == "closing" || c.state == "closed" {
+		c.mu.Unlock()
+		return nil
+	}
+	c.state = "closing"
+	rc := c.remote
+	c.mu.Unlock()
+	if rc != nil {
+		rc.mu.Lock()
+		if rc.state != "closed" {
+			rc.state = "closing"
+		}
+		rc.mu.Unlock()
+		rc.finish()
+	}
+	c.finish()
+	return nil
+}
+
+// ID returns -1 until the channel is open, unless it was negotiated.
+func (c *DataChannel) ID() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.id
+}
+
+// ReadyState ...
+func (c *DataChannel) ReadyState() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+// Send sends data. Failures are logged; SendContext returns them.
+func (c *DataChannel) Send(data []byte) {
+	c.sendFailed(c.sendMessage(data, false))
+}
+
+// SendText sends text as a string message.
+func (c *DataChannel) SendText(text string) {
+	c.sendFailed(c.sendMessage([]byte(text), true))
+}
+
+func (c *DataChannel) sendMessage(data []byte, isString bool) error {
+	return c.send(Message{Data: append([]byte{}, data...), IsString: isString})
+}
+
+// send queues m for delivery on the event loop of the peer. Messages sent
+// while the channel is not open are dropped with ErrDataChannelNotOpen.
+func (c *DataChannel) send(m Message) error {
+	c.mu.Lock()
+	rc := c.remote
+	if c.state != "open" || rc == nil {
+		c.mu.Unlock()
+		return ErrDataChannelNotOpen
+	}
+	c.buffered += len(m.Data)
+	c.mu.Unlock()
+	c.counters.sent(len(m.Data))
+	rc.pc.loop.post(func() {
+		rc.deliver(m)
+		c.delivered(len(m.Data))
+	})
+	return nil
+}
+
+func (c *DataChannel) deliver(m Message) {
+	c.mu.Lock()
+	if c.state != "open" && c.state != "closing" {
+		c.mu.Unlock()
+		return
+	}
+	onMessage, onMessageData := c.onMessage, c.onMessageData
+	c.mu.Unlock()
+	c.counters.received(len(m.Data))
+	c.events.publish(MessageEvent{Data: m.Data, IsString: m.IsString})
+	if onMessage != nil {
+		onMessage(m.Data)
+	}
+	if onMessageData != nil {
+		onMessageData(m)
+	}
+}
+
+func (c *DataChannel) delivered(n int) {
+	c.mu.Lock()
+	before := c.buffered
+	c.buffered -= n
+	low := before > c.threshold && c.buffered <= c.threshold
+	c.mu.Unlock()
+	if !low {
+		return
+	}
+	c.pc.loop.post(func() {
+		c.low.broadcast()
+		c.events.publish(BufferedAmountLowEvent{})
+		c.mu.Lock()
+		cb := c.onBufferedAmountLow
+		c.mu.Unlock()
+		if cb != nil {
+			cb()
+		}
+	})
+}
+
+// Label ...
+func (c *DataChannel) Label() string {
+	return c.label
+}
+
+// Ordered ...
+func (c *DataChannel) Ordered() bool {
+	return c.opt.ordered
+}
+
+// MaxPacketLifeTime returns -1 if unset.
+func (c *DataChannel) MaxPacketLifeTime() int {
+	return c.opt.lifetime
+}
+
+// MaxRetransmits returns -1 if unset.
+func (c *DataChannel) MaxRetransmits() int {
+	return c.opt.retransmits
+}
+
+// Protocol ...
+func (c *DataChannel) Protocol() string {
+	return c.opt.protocol
+}
+
+// Negotiated ...
+func (c *DataChannel) Negotiated() bool {
+	return c.opt.negotiated
+}
+
+// RTPTransceiver ...
+type RTPTransceiver struct {
+	pc       *PeerConnection
+	kind     string
+	mid      string
+	sender   *RTPSender
+	receiver *RTPReceiver
+
+	// guarded by pc.mu
+	direction RTPTransceiverDirection
+	offered   *sendState
+	current   sendState
+}
+
+// sendState is what a transceiver sends, as offered or negotiated.
+type sendState struct {
+	direction RTPTransceiverDirection
+	track     *MediaStreamTrack
+	streamID  string
+}
+
+// Mid ...
+func (t *RTPTransceiver) Mid() string {
+	return t.mid
+}
+
+// Kind ...
+func (t *RTPTransceiver) Kind() string {
+	return t.kind
+}
+
+// Direction ...
+func (t *RTPTransceiver) Direction() RTPTransceiverDirection {
+	t.pc.mu.Lock()
+	defer t.pc.mu.Unlock()
+	return t.direction
+}
+
+// SetDirection starts or stops sending to the peer after the next
+// offer/answer exchange.
+func (t *RTPTransceiver) SetDirection(d RTPTransceiverDirection) error {
+	if d == RTPTransceiverDirectionUnknown || d == RTPTransceiverDirectionStopped {
+		return fmt.Errorf("set direction: invalid direction %s", d)
+	}
+	pc := t.pc
+	pc.mu.Lock()
+	if t.direction == RTPTransceiverDirectionStopped {
+		pc.mu.Unlock()
+		return errors.New("set direction: transceiver stopped")
+	}
+	t.direction = d
+	pc.needNegotiation()
+	pc.mu.Unlock()
+	return nil
+}
+
+// Stop ends the track of the receiver for good, and the peer's copy of the
+// track sent after the next offer/answer exchange.
+func (t *RTPTransceiver) Stop() error {
+	pc := t.pc
+	pc.mu.Lock()
+	if t.direction == RTPTransceiverDirectionStopped {
+		pc.mu.Unlock()
+		return nil
+	}
+	t.direction = RTPTransceiverDirectionStopped
+	rt := t.receiver.track
+	pc.needNegotiation()
+	pc.mu.Unlock()
+	if rt != nil {
+		rt.end()
+	}
+	return nil
+}
+
+// Sender ...
+func (t *RTPTransceiver) Sender() *RTPSender {
+	return t.sender
+}
+
+// Receiver ...
+func (t *RTPTransceiver) Receiver() *RTPReceiver {
+	return t.receiver
+}
+
+// update announces the negotiated track of the sender to the peer while the
+// transceiver sends, and ends the peer's copy otherwise.
+func (t *RTPTransceiver) update() {
+	pc := t.pc
+	pc.mu.Lock()
+	s, peer, cur := t.sender, pc.peer, t.current
+	send := peer != nil && !pc.closed && cur.direction.sends() && cur.track != nil
+	old := s.remote
+	if send && old != nil && old.source == cur.track {
+		pc.mu.Unlock()
+		return
+	}
+	s.remote = nil
+	var rt *MediaStreamTrack
+	if send {
+		rt = &MediaStreamTrack{
+			id:       cur.track.id,
+			kind:     cur.track.kind,
+			mimeType: cur.track.mimeType,
+			remote:   true,
+			source:   cur.track,
+			notify:   make(chan struct{}, 1),
+		}
+		s.remote = rt
+	}
+	streamID := cur.streamID
+	pc.mu.Unlock()
+	if old != nil {
+		src := old.source
+		src.mu.Lock()
+		for i, r := range src.remotes {
+			if r == old {
+				src.remotes = append(src.remotes[:i], src.remotes[i+1:]...)
+				break
+			}
+		}
+		src.mu.Unlock()
+		old.end()
+	}
+	if rt != nil {
+		src := rt.source
+		src.mu.Lock()
+		src.remotes = append(src.remotes, rt)
+		src.mu.Unlock()
+		peer.receive(rt, streamID)
+	}
+}
+
+// RTPSender ...
+type RTPSender struct {
+	pc *PeerConnection
+
+	// guarded by pc.mu
+	track    *MediaStreamTrack
+	streamID string
+	used     bool
+	remote   *MediaStreamTrack
+}
+
+// Track returns the track added with AddTrack, or nil.
+func (s *RTPSender) Track() *MediaStreamTrack {
+	s.pc.mu.Lock()
+	defer s.pc.mu.Unlock()
+	return s.track
+}
+
+// RTPReceiver ...
+type RTPReceiver struct {
+	pc *PeerConnection
+
+	track *MediaStreamTrack // guarded by pc.mu
+}
+
+// Track returns the last track the peer sent on the transceiver, or nil.
+func (r *RTPReceiver) Track() *MediaStreamTrack {
+	r.pc.mu.Lock()
+	defer r.pc.mu.Unlock()
+	return r.track
+}
+
+// MediaStreamTrack is a local track fed with samples, or a remote track
+// read as RTP packets.
+type MediaStreamTrack struct {
+	id       string
+	kind     string
+	mimeType string
+	remote   bool
+	source   *MediaStreamTrack
+	notify   chan struct{}
+
+	mu      sync.Mutex
+	stopped bool
+	ended   bool
+	ssrc    uint32
+	seq     uint16
+	ts      uint32
+	remotes []*MediaStreamTrack
+	packets [][]byte
+}
+
+// NewSampleTrack returns a local track encoded as mimeType, such as
+// "video/VP8" or "audio/opus", for samples written with WriteSample.
+func NewSampleTrack(id, mimeType string) (*MediaStreamTrack, error) {
+	kind, err := trackKind(mimeType)
+	if err != nil {
+		return nil, err
+	}
+	mockNet.Lock()
+	mockNet.lastSSRC++
+	ssrc := mockNet.lastSSRC
+	mockNet.Unlock()
+	return &MediaStreamTrack{id: id, kind: kind, mimeType: mimeType, ssrc: ssrc}, nil
+}
+
+// ID ...
+func (t *MediaStreamTrack) ID() string {
+	return t.id
+}
+
+// Kind ...
+func (t *MediaStreamTrack) Kind() string {
+	return t.kind
+}
+
+// MimeType ...
+func (t *MediaStreamTrack) MimeType() string {
+	return t.mimeType
+}
+
+// WriteSample sends data to the peers as a single RTP packet with a 12 byte
+// header; it is not packetized for the codec.
+func (t *MediaStreamTrack) WriteSample(data []byte, duration time.Duration) error {
+	if t.remote {
+		return errors.New("write sample: remote track")
+	}
+	pt, clock := byte(96), uint32(90000)
+	if t.kind == "audio" {
+		pt, clock = 111, 48000
+	}
+	t.mu.Lock()
+	if t.stopped {
+		t.mu.Unlock()
+		return ErrTrackStopped
+	}
+	b := make([]byte, 12+len(data))
+	b[0] = 0x80
+	b[1] = 0x80 | pt
+	binary.BigEndian.PutUint16(b[2:], t.seq)
+	binary.BigEndian.PutUint32(b[4:], t.ts)
+	binary.BigEndian.PutUint32(b[8:], t.ssrc)
+	copy(b[12:], data)
+	t.seq++
+	t.ts += uint32(duration * time.Duration(clock) / time.Second)
+	remotes := append([]*MediaStreamTrack{}, t.remotes...)
+	t.mu.Unlock()
+	for _, rt := range remotes {
+		rt.push(b)
+	}
+	return nil
+}
+
+func (t *MediaStreamTrack) push(b []byte) {
+	t.mu.Lock()
+	if t.ended || t.stopped {
+		t.mu.Unlock()
+		return
+	}
+	t.packets = append(t.packets, b)
+	t.mu.Unlock()
+	t.wake()
+}
+
+func (t *MediaStreamTrack) wake() {
+	if t.notify == nil {
+		return
+	}
+	select {
+	case t.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (t *MediaStreamTrack) isEnded() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.ended
+}
+
+// end lets ReadRTP return io.EOF once the queued packets are read.
+func (t *MediaStreamTrack) end() {
+	t.mu.Lock()
+	t.ended = true
+	t.mu.Unlock()
+	t.wake()
+}
+
+// ReadRTP reads one RTP packet of a remote track. It returns io.EOF once
+// the track was removed by the peer and io.ErrShortBuffer if b cannot hold
+// the packet.
+func (t *MediaStreamTrack) ReadRTP(b []byte) (int, error) {
+	if !t.remote {
+		return 0, errors.New("read rtp: local track")
+	}
+	for {
+		t.mu.Lock()
+		if t.stopped {
+			t.mu.Unlock()
+			return 0, ErrTrackStopped
+		}
+		if len(t.packets) > 0 {
+			p := t.packets[0]
+			if len(b) < len(p) {
+				t.mu.Unlock()
+				return 0, io.ErrShortBuffer
+			}
+			t.packets = t.packets[1:]
+			t.mu.Unlock()
+			return copy(b, p), nil
+		}
+		ended := t.ended
+		t.mu.Unlock()
+		if ended {
+			return 0, io.EOF
+		}
+		<-t.notify
+	}
+}
+
+// Stop ends WriteSample and ReadRTP.
+func (t *MediaStreamTrack) Stop() {
+	t.mu.Lock()
+	t.stopped = true
+	t.packets = nil
+	t.mu.Unlock()
+	t.wake()
+}
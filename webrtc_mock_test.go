@@ -0,0 +1,202 @@
+// +build mock
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+)
+
+// exchange runs one offer/answer from a to b by hand.
+func exchange(t *testing.T, a, b *PeerConnection) {
+	t.Helper()
+	offer, err := a.CreateOffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SetLocalDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SetRemoteDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	answer, err := b.CreateAnswer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SetLocalDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SetRemoteDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// negotiationNeeded reports whether pc fires negotiationneeded soon.
+func negotiationNeeded(t *testing.T, events <-chan Event) bool {
+	t.Helper()
+	timeout := time.After(100 * time.Millisecond)
+	for {
+		select {
+		case ev := <-events:
+			if _, ok := ev.(NegotiationNeededEvent); ok {
+				return true
+			}
+		case <-timeout:
+			return false
+		}
+	}
+}
+
+func TestMockRenegotiation(t *testing.T) {
+	a, err := NewPeerConnection(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := NewPeerConnection(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	events := a.Events()
+	defer a.Unsubscribe(events)
+	tracks := make(chan *MediaStreamTrack, 1)
+	b.OnTrack(func(track *MediaStreamTrack, _ []*MediaStream) { tracks <- track })
+
+	// the first channel needs a data channel section, later ones do not.
+	if _, err := a.CreateDataChannel("first"); err != nil {
+		t.Fatal(err)
+	}
+	if !negotiationNeeded(t, events) {
+		t.Fatal("no negotiationneeded for the first channel")
+	}
+	exchange(t, a, b)
+	if a.ConnectionState() != PeerConnectionStateConnected {
+		t.Fatalf("connection state %s", a.ConnectionState())
+	}
+	if _, err := a.CreateDataChannel("second"); err != nil {
+		t.Fatal(err)
+	}
+	if negotiationNeeded(t, events) {
+		t.Fatal("negotiationneeded for an in-band channel")
+	}
+
+	track, err := NewSampleTrack("cam", "video/VP8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AddTrack(track); err != nil {
+		t.Fatal(err)
+	}
+	if !negotiationNeeded(t, events) {
+		t.Fatal("no negotiationneeded for AddTrack")
+	}
+	select {
+	case <-tracks:
+		t.Fatal("track sent before the exchange")
+	case <-time.After(50 * time.Millisecond):
+	}
+	exchange(t, a, b)
+	var remote *MediaStreamTrack
+	select {
+	case remote = <-tracks:
+	case <-time.After(time.Second):
+		t.Fatal("track not sent after the exchange")
+	}
+
+	if err := a.RemoveTrack(track); err != nil {
+		t.Fatal(err)
+	}
+	if !negotiationNeeded(t, events) {
+		t.Fatal("no negotiationneeded for RemoveTrack")
+	}
+	if remote.isEnded() {
+		t.Fatal("track ended before the exchange")
+	}
+	exchange(t, a, b)
+	if !remote.isEnded() {
+		t.Fatal("track not ended after the exchange")
+	}
+
+	tr, err := a.AddTransceiver("audio", RTPTransceiverDirectionSendrecv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !negotiationNeeded(t, events) {
+		t.Fatal("no negotiationneeded for AddTransceiver")
+	}
+	exchange(t, a, b)
+	if err := tr.SetDirection(RTPTransceiverDirectionRecvonly); err != nil {
+		t.Fatal(err)
+	}
+	if !negotiationNeeded(t, events) {
+		t.Fatal("no negotiationneeded for SetDirection")
+	}
+}
+
+func TestMockCandidateQueue(t *testing.T) {
+	a, err := NewPeerConnection(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	offer, err := a.CreateOffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc, err := NewPeerConnection(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reported := make(chan error, 2)
+	pc.OnRemoteCandidateError(func(ic *IceCandidate, err error) { reported <- err })
+
+	// queued candidates are applied in order by SetRemoteDescription; one
+	// that can't be parsed is only found out then.
+	for _, c := range []string{
+		"candidate:1 1 udp 2130706431 192.0.2.1 5000 typ host",
+		"garbage",
+		"candidate:2 1 udp 2130706431 192.0.2.2 5000 typ host",
+	} {
+		if err := pc.AddIceCandidate(NewIceCandidate(c, "0", 0)); err != nil {
+			t.Fatalf("queued candidate: %v", err)
+		}
+	}
+	if _, remote := pc.seen.all(); len(remote) != 0 {
+		t.Fatalf("%d candidates applied before the remote description", len(remote))
+	}
+	if err := pc.SetRemoteDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	_, remote := pc.seen.all()
+	if len(remote) != 2 || remote[0].Candidate[10] != '1' || remote[1].Candidate[10] != '2' {
+		t.Fatalf("applied %v", remote)
+	}
+	select {
+	case err := <-reported:
+		if err == nil || err == ErrCandidateDropped {
+			t.Fatalf("reported %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("bad candidate not reported")
+	}
+	pc.Close()
+
+	// Close reports what is still queued.
+	pc, err = NewPeerConnection(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc.OnRemoteCandidateError(func(ic *IceCandidate, err error) { reported <- err })
+	pc.AddIceCandidate(NewIceCandidate("candidate:1 1 udp 2130706431 192.0.2.1 5000 typ host", "0", 0))
+	pc.Close()
+	select {
+	case err := <-reported:
+		if err != ErrCandidateDropped {
+			t.Fatalf("reported %v, want %v", err, ErrCandidateDropped)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("queued candidate not dropped on Close")
+	}
+}
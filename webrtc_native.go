@@ -1,12 +1,20 @@
-// +build !js
+// +build !js,!mock,!pion
 
 package webrtc
 
+// The native backend wraps go-webrtc, which only binds DataChannels. Media
+// is out of its reach: the stream and track functions return
+// ErrMediaNotSupported, and the media callbacks are logged and never
+// called. Use the pion backend to send or receive media from Go.
+
 import (
+	"context"
 	"fmt"
-	"log"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	org "github.com/keroserene/go-webrtc"
 )
@@ -15,6 +23,16 @@ func init() {
 	org.SetLoggingVerbosity(0)
 }
 
+// canRollback is false: go-webrtc rejects rollback descriptions.
+var canRollback = false
+
+// SetLoggingVerbosity sets how much go-webrtc and the native WebRTC library
+// log by themselves, 0 (the default) being silent. These logs bypass
+// SetLogger.
+func SetLoggingVerbosity(level int) {
+	org.SetLoggingVerbosity(level)
+}
+
 func find(f func(int) fmt.Stringer, search string) int {
 	m := ""
 	for i := 0; m != strconv.Itoa(i); i++ {
@@ -28,7 +46,25 @@ func find(f func(int) fmt.Stringer, search string) int {
 
 // PeerConnection ...
 type PeerConnection struct {
-	pc *org.PeerConnection
+	candidates         candidateQueue
+	gathering          gatheringState
+	events             broker
+	seen               candidateLog
+	iceConnectionState int32
+	log                pcLog
+	pc                 *org.PeerConnection
+
+	mu       sync.Mutex
+	channels []*DataChannel
+
+	onNegotiationNeeded        func()
+	onIceCandidate             func(*IceCandidate)
+	onIceCandidateError        func()
+	onSignalingStateChange     func(SignalingState)
+	onIceConnectionStateChange func(IceConnectionState)
+	onIceGatheringStateChange  func(IceGatheringState)
+	onConnectionStateChange    func(PeerConnectionState)
+	onDataChannel              func(*DataChannel)
 }
 
 // NewPeerConnection ...
@@ -57,100 +93,270 @@ func NewPeerConnection(config *Configuration) (*PeerConnection, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &PeerConnection{pc: pc}, nil
+	p := &PeerConnection{
+		pc:                 pc,
+		iceConnectionState: int32(IceConnectionStateNew),
+	}
+	p.log.init()
+	p.gathering.set(IceGatheringStateNew)
+	pc.OnNegotiationNeeded = func() {
+		p.emit(NegotiationNeededEvent{}, func() func() {
+			return p.onNegotiationNeeded
+		})
+	}
+	pc.OnIceCandidate = func(ic org.IceCandidate) {
+		c := &IceCandidate{
+			Candidate:     ic.Candidate,
+			SdpMid:        ic.SdpMid,
+			SdpMLineIndex: ic.SdpMLineIndex,
+		}
+		p.seen.addLocal(c)
+		p.emit(IceCandidateEvent{Candidate: c}, func() func() {
+			if cb := p.onIceCandidate; cb != nil {
+				return func() { cb(c) }
+			}
+			return nil
+		})
+	}
+	pc.OnIceCandidateError = func() {
+		p.emit(IceCandidateErrorEvent{}, func() func() {
+			return p.onIceCandidateError
+		})
+	}
+	pc.OnSignalingStateChange = func(s org.SignalingState) {
+		state, _ := ParseSignalingState(s.String())
+		p.emit(SignalingStateChangeEvent{State: state}, func() func() {
+			if cb := p.onSignalingStateChange; cb != nil {
+				return func() { cb(state) }
+			}
+			return nil
+		})
+	}
+	pc.OnIceConnectionStateChange = func(s org.IceConnectionState) {
+		state, _ := ParseIceConnectionState(s.String())
+		atomic.StoreInt32(&p.iceConnectionState, int32(state))
+		p.emit(IceConnectionStateChangeEvent{State: state}, func() func() {
+			if cb := p.onIceConnectionStateChange; cb != nil {
+				return func() { cb(state) }
+			}
+			return nil
+		})
+	}
+	pc.OnIceGatheringStateChange = func(s org.IceGatheringState) {
+		state, _ := ParseIceGatheringState(s.String())
+		p.gathering.set(state)
+		p.emit(IceGatheringStateChangeEvent{State: state}, func() func() {
+			if cb := p.onIceGatheringStateChange; cb != nil {
+				return func() { cb(state) }
+			}
+			return nil
+		})
+	}
+	pc.OnConnectionStateChange = func(s org.PeerConnectionState) {
+		state, _ := ParsePeerConnectionState(s.String())
+		p.emit(ConnectionStateChangeEvent{State: state}, func() func() {
+			if cb := p.onConnectionStateChange; cb != nil {
+				return func() { cb(state) }
+			}
+			return nil
+		})
+	}
+	pc.OnDataChannel = func(dc *org.DataChannel) {
+		c := newDataChannel(p, dc)
+		p.emit(DataChannelEvent{Channel: c}, func() func() {
+			if cb := p.onDataChannel; cb != nil {
+				return func() { cb(c) }
+			}
+			return nil
+		})
+	}
+	return p, nil
+}
+
+// emit publishes ev and then calls the callback cb returns. cb runs with
+// pc.mu held, so callbacks set from other goroutines are read without a race.
+func (pc *PeerConnection) emit(ev Event, cb func() func()) {
+	pc.publish(ev)
+	pc.mu.Lock()
+	f := cb()
+	pc.mu.Unlock()
+	if f != nil {
+		f()
+	}
 }
 
 // OnNegotiationNeeded ...
 func (pc *PeerConnection) OnNegotiationNeeded(cb func()) {
-	pc.pc.OnNegotiationNeeded = cb
+	pc.mu.Lock()
+	pc.onNegotiationNeeded = cb
+	pc.mu.Unlock()
 }
 
 // OnIceCandidate ...
 func (pc *PeerConnection) OnIceCandidate(cb func(*IceCandidate)) {
-	pc.pc.OnIceCandidate = func(ic org.IceCandidate) {
-		log.Println("ice:", ic)
-		cb(&IceCandidate{
-			Candidate:     ic.Candidate,
-			SdpMid:        ic.SdpMid,
-			SdpMLineIndex: ic.SdpMLineIndex,
-		})
-	}
+	pc.mu.Lock()
+	pc.onIceCandidate = cb
+	pc.mu.Unlock()
 }
 
 // OnIceCandidateError ...
 func (pc *PeerConnection) OnIceCandidateError(cb func()) {
-	pc.pc.OnIceCandidateError = cb
+	pc.mu.Lock()
+	pc.onIceCandidateError = cb
+	pc.mu.Unlock()
 }
 
 // OnSignalingStateChange ...
-func (pc *PeerConnection) OnSignalingStateChange(cb func(SignalingState string)) {
-	pc.pc.OnSignalingStateChange = func(s org.SignalingState) {
-		cb(s.String())
-	}
+func (pc *PeerConnection) OnSignalingStateChange(cb func(SignalingState)) {
+	pc.mu.Lock()
+	pc.onSignalingStateChange = cb
+	pc.mu.Unlock()
 }
 
 // OnIceConnectionStateChange ...
-func (pc *PeerConnection) OnIceConnectionStateChange(cb func(IceConnectionState string)) {
-	pc.pc.OnIceConnectionStateChange = func(s org.IceConnectionState) {
-		cb(s.String())
-	}
+func (pc *PeerConnection) OnIceConnectionStateChange(cb func(IceConnectionState)) {
+	pc.mu.Lock()
+	pc.onIceConnectionStateChange = cb
+	pc.mu.Unlock()
 }
 
 // OnIceGatheringStateChange ...
-func (pc *PeerConnection) OnIceGatheringStateChange(cb func(IceGatheringState string)) {
-	pc.pc.OnIceGatheringStateChange = func(s org.IceGatheringState) {
-		cb(s.String())
-	}
+func (pc *PeerConnection) OnIceGatheringStateChange(cb func(IceGatheringState)) {
+	pc.mu.Lock()
+	pc.onIceGatheringStateChange = cb
+	pc.mu.Unlock()
 }
 
 // OnConnectionStateChange ...
-func (pc *PeerConnection) OnConnectionStateChange(cb func(PeerConnectionState string)) {
-	pc.pc.OnConnectionStateChange = func(s org.PeerConnectionState) {
-		cb(s.String())
-	}
+func (pc *PeerConnection) OnConnectionStateChange(cb func(PeerConnectionState)) {
+	pc.mu.Lock()
+	pc.onConnectionStateChange = cb
+	pc.mu.Unlock()
 }
 
 // OnDataChannel ...
 func (pc *PeerConnection) OnDataChannel(cb func(*DataChannel)) {
-	pc.pc.OnDataChannel = func(dc *org.DataChannel) {
-		cb(&DataChannel{
-			dc: dc,
-		})
-	}
+	pc.mu.Lock()
+	pc.onDataChannel = cb
+	pc.mu.Unlock()
 }
 
-// OnAddStream ...
+// OnAddStream is never called: go-webrtc has no media support.
 func (pc *PeerConnection) OnAddStream(cb func(*MediaStream)) {
-	panic("not supported")
+	pc.neverCalled("OnAddStream")
 }
 
-// OnRemoveStream ...
+// OnRemoveStream is never called: go-webrtc has no media support.
 func (pc *PeerConnection) OnRemoveStream(cb func(*MediaStream)) {
-	panic("not supported")
+	pc.neverCalled("OnRemoveStream")
+}
+
+// AddStream returns ErrMediaNotSupported.
+func (pc *PeerConnection) AddStream(stream *MediaStream) error {
+	return ErrMediaNotSupported
+}
+
+// OnTrack is never called: go-webrtc has no media support.
+func (pc *PeerConnection) OnTrack(cb func(*MediaStreamTrack, []*MediaStream)) {
+	pc.neverCalled("OnTrack")
+}
+
+// AddTrack returns ErrMediaNotSupported.
+func (pc *PeerConnection) AddTrack(track *MediaStreamTrack, streams ...*MediaStream) error {
+	return ErrMediaNotSupported
+}
+
+// RemoveTrack returns ErrMediaNotSupported.
+func (pc *PeerConnection) RemoveTrack(track *MediaStreamTrack) error {
+	return ErrMediaNotSupported
 }
 
-// AddStream ...
-func (pc *PeerConnection) AddStream(stream *MediaStream) (err error) {
-	panic("not supported")
+// AddTransceiver returns ErrMediaNotSupported: go-webrtc has no
+// transceivers.
+func (pc *PeerConnection) AddTransceiver(kind string, direction RTPTransceiverDirection) (*RTPTransceiver, error) {
+	return nil, ErrMediaNotSupported
 }
 
-// AddIceCandidate ...
+// GetTransceivers returns nil: go-webrtc has no transceivers.
+func (pc *PeerConnection) GetTransceivers() []*RTPTransceiver {
+	return nil
+}
+
+// GetSenders returns nil: go-webrtc has no transceivers.
+func (pc *PeerConnection) GetSenders() []*RTPSender {
+	return nil
+}
+
+// GetReceivers returns nil: go-webrtc has no transceivers.
+func (pc *PeerConnection) GetReceivers() []*RTPReceiver {
+	return nil
+}
+
+// OnRemoteCandidateError is called with remote candidates that failed to
+// apply once the remote description was set, or were dropped with
+// ErrCandidateDropped.
+func (pc *PeerConnection) OnRemoteCandidateError(cb func(*IceCandidate, error)) {
+	pc.candidates.setOnError(cb)
+}
+
+// AddIceCandidate adds a remote candidate. Candidates added before the remote
+// description is set are queued and applied by SetRemoteDescription.
 func (pc *PeerConnection) AddIceCandidate(ic *IceCandidate) error {
-	return pc.pc.AddIceCandidate(org.IceCandidate{
+	return pc.candidates.add(ic, pc.addIceCandidate)
+}
+
+// AddIceCandidateContext ...
+func (pc *PeerConnection) AddIceCandidateContext(ctx context.Context, ic *IceCandidate) error {
+	return pc.candidates.add(ic, func(ic *IceCandidate) error {
+		return withContext(ctx, func() error {
+			return pc.addIceCandidate(ic)
+		})
+	})
+}
+
+func (pc *PeerConnection) addIceCandidate(ic *IceCandidate) error {
+	err := pc.pc.AddIceCandidate(org.IceCandidate{
 		Candidate:     ic.Candidate,
 		SdpMid:        ic.SdpMid,
 		SdpMLineIndex: ic.SdpMLineIndex,
 	})
+	if err != nil {
+		return err
+	}
+	pc.seen.addRemote(ic)
+	return nil
+}
+
+// candidatePair returns nil, nil: go-webrtc does not report the selected
+// candidate pair and guessing one could name an address that is not in use.
+func (pc *PeerConnection) candidatePair() (local, remote *IceCandidate) {
+	return nil, nil
 }
 
 // Close ...
 func (pc *PeerConnection) Close() error {
+	pc.candidates.drop()
+	defer pc.events.close()
 	return pc.pc.Close()
 }
 
 // ConnectionState ...
-func (pc *PeerConnection) ConnectionState() string {
-	return pc.pc.ConnectionState().String()
+func (pc *PeerConnection) ConnectionState() PeerConnectionState {
+	state, _ := ParsePeerConnectionState(pc.pc.ConnectionState().String())
+	return state
+}
+
+// CreateAnswerContext ...
+func (pc *PeerConnection) CreateAnswerContext(ctx context.Context) (*SessionDescription, error) {
+	var sd *SessionDescription
+	err := withContext(ctx, func() (err error) {
+		sd, err = pc.CreateAnswer()
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sd, nil
 }
 
 // CreateAnswer ...
@@ -166,12 +372,36 @@ func (pc *PeerConnection) CreateAnswer() (*SessionDescription, error) {
 }
 
 // CreateDataChannel ...
-func (pc *PeerConnection) CreateDataChannel(label string) (*DataChannel, error) {
-	dc, err := pc.pc.CreateDataChannel(label)
+func (pc *PeerConnection) CreateDataChannel(label string, opts ...*DataChannelInit) (*DataChannel, error) {
+	p, err := dataChannelOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	dc, err := pc.pc.CreateDataChannel(label, org.Init{
+		Ordered:           p.ordered,
+		MaxPacketLifeTime: p.lifetime,
+		MaxRetransmits:    p.retransmits,
+		Protocol:          p.protocol,
+		Negotiated:        p.negotiated,
+		ID:                p.id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newDataChannel(pc, dc), nil
+}
+
+// CreateOfferContext ...
+func (pc *PeerConnection) CreateOfferContext(ctx context.Context) (*SessionDescription, error) {
+	var sd *SessionDescription
+	err := withContext(ctx, func() (err error) {
+		sd, err = pc.CreateOffer()
+		return
+	})
 	if err != nil {
 		return nil, err
 	}
-	return &DataChannel{dc}, nil
+	return sd, nil
 }
 
 // CreateOffer ...
@@ -187,13 +417,21 @@ func (pc *PeerConnection) CreateOffer() (*SessionDescription, error) {
 }
 
 // IceGatheringState ...
-func (pc *PeerConnection) IceGatheringState() string {
-	return ""
+func (pc *PeerConnection) IceGatheringState() IceGatheringState {
+	return pc.gathering.get()
+}
+
+// IceConnectionState ...
+func (pc *PeerConnection) IceConnectionState() IceConnectionState {
+	return IceConnectionState(atomic.LoadInt32(&pc.iceConnectionState))
 }
 
 // LocalDescription ...
 func (pc *PeerConnection) LocalDescription() (sdp *SessionDescription) {
 	sd := pc.pc.LocalDescription()
+	if sd == nil {
+		return nil
+	}
 	return &SessionDescription{
 		Type: sd.Type,
 		Sdp:  sd.Sdp,
@@ -203,12 +441,22 @@ func (pc *PeerConnection) LocalDescription() (sdp *SessionDescription) {
 // RemoteDescription ...
 func (pc *PeerConnection) RemoteDescription() (sdp *SessionDescription) {
 	sd := pc.pc.RemoteDescription()
+	if sd == nil {
+		return nil
+	}
 	return &SessionDescription{
 		Type: sd.Type,
 		Sdp:  sd.Sdp,
 	}
 }
 
+// SetLocalDescriptionContext ...
+func (pc *PeerConnection) SetLocalDescriptionContext(ctx context.Context, sdp *SessionDescription) error {
+	return withContext(ctx, func() error {
+		return pc.SetLocalDescription(sdp)
+	})
+}
+
 // SetLocalDescription ...
 func (pc *PeerConnection) SetLocalDescription(sdp *SessionDescription) error {
 	return pc.pc.SetLocalDescription(&org.SessionDescription{
@@ -217,37 +465,171 @@ func (pc *PeerConnection) SetLocalDescription(sdp *SessionDescription) error {
 	})
 }
 
+// SetRemoteDescriptionContext ...
+func (pc *PeerConnection) SetRemoteDescriptionContext(ctx context.Context, sdp *SessionDescription) error {
+	return withContext(ctx, func() error {
+		return pc.SetRemoteDescription(sdp)
+	})
+}
+
 // SetRemoteDescription ...
 func (pc *PeerConnection) SetRemoteDescription(sdp *SessionDescription) error {
-	return pc.pc.SetRemoteDescription(&org.SessionDescription{
+	err := pc.pc.SetRemoteDescription(&org.SessionDescription{
 		Type: sdp.Type,
 		Sdp:  sdp.Sdp,
 	})
+	if err != nil {
+		return err
+	}
+	if sdp.Type != "rollback" {
+		pc.candidates.flush(pc.addIceCandidate)
+	}
+	return nil
 }
 
 // SignalingState ...
-func (pc *PeerConnection) SignalingState() string {
-	return pc.pc.SignalingState().String()
+func (pc *PeerConnection) SignalingState() SignalingState {
+	state, _ := ParseSignalingState(pc.pc.SignalingState().String())
+	return state
 }
 
 // DataChannel ...
 type DataChannel struct {
-	dc *org.DataChannel
+	events   broker
+	low      notifier
+	counters dataChannelCounters
+	pc       *PeerConnection
+	dc       *org.DataChannel
+
+	mu                  sync.Mutex
+	onOpen              func()
+	onClose             func()
+	onMessage           func([]byte)
+	onMessageData       func(Message)
+	onBufferedAmountLow func()
+}
+
+// removeChannel forgets a closed channel, so that the list does not grow
+// with every channel ever opened.
+func (pc *PeerConnection) removeChannel(c *DataChannel) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	for i, ch := range pc.channels {
+		if ch == c {
+			pc.channels = append(pc.channels[:i], pc.channels[i+1:]...)
+			return
+		}
+	}
+}
+
+func newDataChannel(pc *PeerConnection, dc *org.DataChannel) *DataChannel {
+	c := &DataChannel{pc: pc, dc: dc}
+	c.events.hold()
+	if pc != nil {
+		pc.mu.Lock()
+		pc.channels = append(pc.channels, c)
+		pc.mu.Unlock()
+	}
+	dc.OnOpen = func() {
+		c.emit(OpenEvent{}, func() func() {
+			return c.onOpen
+		})
+	}
+	dc.OnClose = func() {
+		if pc != nil {
+			pc.removeChannel(c)
+		}
+		c.emit(CloseEvent{}, func() func() {
+			return c.onClose
+		})
+		c.events.close()
+	}
+	dc.OnMessage = func(data []byte) {
+		c.counters.received(len(data))
+		c.emit(MessageEvent{Data: data}, func() func() {
+			onMessage, onMessageData := c.onMessage, c.onMessageData
+			return func() {
+				if onMessage != nil {
+					onMessage(data)
+				}
+				if onMessageData != nil {
+					onMessageData(Message{Data: data})
+				}
+			}
+		})
+	}
+	dc.OnBufferedAmountLow = func() {
+		c.low.broadcast()
+		c.emit(BufferedAmountLowEvent{}, func() func() {
+			return c.onBufferedAmountLow
+		})
+	}
+	return c
+}
+
+// emit publishes ev and then calls the callback cb returns, with c.mu held
+// while cb reads it.
+func (c *DataChannel) emit(ev Event, cb func() func()) {
+	c.events.publish(ev)
+	c.mu.Lock()
+	f := cb()
+	c.mu.Unlock()
+	if f != nil {
+		f()
+	}
 }
 
 // OnOpen ...
 func (c *DataChannel) OnOpen(cb func()) {
-	c.dc.OnOpen = cb
+	c.mu.Lock()
+	c.onOpen = cb
+	c.mu.Unlock()
 }
 
 // OnClose ...
 func (c *DataChannel) OnClose(cb func()) {
-	c.dc.OnClose = cb
+	c.mu.Lock()
+	c.onClose = cb
+	c.mu.Unlock()
 }
 
 // OnMessage ...
 func (c *DataChannel) OnMessage(cb func([]byte)) {
-	c.dc.OnMessage = cb
+	c.mu.Lock()
+	c.onMessage = cb
+	c.mu.Unlock()
+	c.events.release()
+}
+
+// OnMessageData is OnMessage with the frame type. go-webrtc does not report
+// it, so IsString is always false on this backend.
+func (c *DataChannel) OnMessageData(cb func(Message)) {
+	c.mu.Lock()
+	c.onMessageData = cb
+	c.mu.Unlock()
+	c.events.release()
+}
+
+// OnBufferedAmountLow ...
+func (c *DataChannel) OnBufferedAmountLow(cb func()) {
+	c.mu.Lock()
+	c.onBufferedAmountLow = cb
+	c.mu.Unlock()
+}
+
+// BufferedAmount ...
+func (c *DataChannel) BufferedAmount() int {
+	return c.dc.BufferedAmount()
+}
+
+// BufferedAmountLowThreshold ...
+func (c *DataChannel) BufferedAmountLowThreshold() int {
+	return c.dc.BufferedAmountLowThreshold
+}
+
+// SetBufferedAmountLowThreshold ...
+func (c *DataChannel) SetBufferedAmountLowThreshold(n int) {
+	c.dc.BufferedAmountLowThreshold = n
 }
 
 // Close ...
@@ -262,12 +644,28 @@ func (c *DataChannel) ID() int {
 
 // ReadyState ...
 func (c *DataChannel) ReadyState() string {
-	return ""
+	return strings.ToLower(c.dc.ReadyState().String())
 }
 
 // Send ...
 func (c *DataChannel) Send(data []byte) {
-	c.dc.Send(data)
+	c.sendMessage(data, false)
+}
+
+// SendText sends text as a string message.
+func (c *DataChannel) SendText(text string) {
+	c.sendMessage([]byte(text), true)
+}
+
+// sendMessage never fails: go-webrtc does not report send errors.
+func (c *DataChannel) sendMessage(data []byte, isString bool) error {
+	c.counters.sent(len(data))
+	if isString {
+		c.dc.SendText(string(data))
+	} else {
+		c.dc.Send(data)
+	}
+	return nil
 }
 
 // Label ...
@@ -275,10 +673,142 @@ func (c *DataChannel) Label() string {
 	return c.dc.Label()
 }
 
-// MediaStream ...
-type MediaStream struct{}
+// unsetUint16 is how libwebrtc reports an unset 16 bit option.
+const unsetUint16 = 0xffff
+
+// Ordered ...
+func (c *DataChannel) Ordered() bool {
+	return c.dc.Ordered()
+}
+
+// MaxPacketLifeTime returns -1 if unset.
+func (c *DataChannel) MaxPacketLifeTime() int {
+	if v := c.dc.MaxPacketLifeTime(); v < unsetUint16 {
+		return int(v)
+	}
+	return -1
+}
+
+// MaxRetransmits returns -1 if unset.
+func (c *DataChannel) MaxRetransmits() int {
+	if v := c.dc.MaxRetransmits(); v < unsetUint16 {
+		return int(v)
+	}
+	return -1
+}
+
+// Protocol ...
+func (c *DataChannel) Protocol() string {
+	return c.dc.Protocol()
+}
+
+// Negotiated ...
+func (c *DataChannel) Negotiated() bool {
+	return c.dc.Negotiated()
+}
+
+// GetUserMedia returns ErrMediaNotSupported.
+func GetUserMedia(constraints *Constraints) (*MediaStream, error) {
+	return nil, ErrMediaNotSupported
+}
+
+// GetUserMediaContext returns ErrMediaNotSupported.
+func GetUserMediaContext(ctx context.Context, constraints *Constraints) (*MediaStream, error) {
+	return nil, ErrMediaNotSupported
+}
+
+// MediaStreamTrack is never returned by go-webrtc.
+type MediaStreamTrack struct {
+	id       string
+	kind     string
+	mimeType string
+}
+
+// NewSampleTrack returns ErrMediaNotSupported.
+func NewSampleTrack(id, mimeType string) (*MediaStreamTrack, error) {
+	return nil, ErrMediaNotSupported
+}
+
+// ID ...
+func (t *MediaStreamTrack) ID() string {
+	return t.id
+}
+
+// Kind ...
+func (t *MediaStreamTrack) Kind() string {
+	return t.kind
+}
+
+// MimeType ...
+func (t *MediaStreamTrack) MimeType() string {
+	return t.mimeType
+}
+
+// WriteSample returns ErrMediaNotSupported.
+func (t *MediaStreamTrack) WriteSample(data []byte, duration time.Duration) error {
+	return ErrMediaNotSupported
+}
+
+// ReadRTP returns ErrMediaNotSupported.
+func (t *MediaStreamTrack) ReadRTP(b []byte) (int, error) {
+	return 0, ErrMediaNotSupported
+}
+
+// Stop ...
+func (t *MediaStreamTrack) Stop() {}
+
+// RTPTransceiver is never returned by go-webrtc. It only exists so that code
+// written for the other backends builds; its methods report nothing and
+// fail with ErrMediaNotSupported.
+type RTPTransceiver struct{}
+
+// Mid ...
+func (t *RTPTransceiver) Mid() string {
+	return ""
+}
+
+// Kind ...
+func (t *RTPTransceiver) Kind() string {
+	return ""
+}
+
+// Direction ...
+func (t *RTPTransceiver) Direction() RTPTransceiverDirection {
+	return RTPTransceiverDirectionUnknown
+}
+
+// SetDirection returns ErrMediaNotSupported.
+func (t *RTPTransceiver) SetDirection(d RTPTransceiverDirection) error {
+	return ErrMediaNotSupported
+}
+
+// Stop returns ErrMediaNotSupported.
+func (t *RTPTransceiver) Stop() error {
+	return ErrMediaNotSupported
+}
+
+// Sender ...
+func (t *RTPTransceiver) Sender() *RTPSender {
+	return nil
+}
+
+// Receiver ...
+func (t *RTPTransceiver) Receiver() *RTPReceiver {
+	return nil
+}
+
+// RTPSender is never returned by go-webrtc.
+type RTPSender struct{}
+
+// Track ...
+func (s *RTPSender) Track() *MediaStreamTrack {
+	return nil
+}
+
+// RTPReceiver is never returned by go-webrtc.
+type RTPReceiver struct{}
 
-// GetUserMedia ...
-func GetUserMedia(constraints *Constraints) (stream *MediaStream, err error) {
-	panic("not supported")
+// Track ...
+func (r *RTPReceiver) Track() *MediaStreamTrack {
+	return nil
 }
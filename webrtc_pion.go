@@ -0,0 +1,1142 @@
+// +build !js,pion,!mock
+
+package webrtc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/logging"
+	pion "github.com/pion/webrtc/v3"
+	"github.com/pion/webrtc/v3/pkg/media"
+
+	"github.com/nobonobo/webrtc/sdp"
+)
+
+var logLevel int32
+
+// canRollback reports whether rollback descriptions are accepted.
+var canRollback = true
+
+// SetLoggingVerbosity sets how much pion logs by itself for PeerConnections
+// created afterwards: 0 (the default) is silent, 1 errors, 2 warnings,
+// 3 info, 4 debug and 5 trace. These logs bypass SetLogger.
+func SetLoggingVerbosity(level int) {
+	atomic.StoreInt32(&logLevel, int32(level))
+}
+
+func iceTransportPolicy(s string) pion.ICETransportPolicy {
+	if strings.ToLower(s) == "relay" {
+		return pion.ICETransportPolicyRelay
+	}
+	return pion.ICETransportPolicyAll
+}
+
+func bundlePolicy(s string) pion.BundlePolicy {
+	switch strings.ToLower(s) {
+	case "max-compat":
+		return pion.BundlePolicyMaxCompat
+	case "max-bundle":
+		return pion.BundlePolicyMaxBundle
+	}
+	return pion.BundlePolicyBalanced
+}
+
+func rtcpMuxPolicy(s string) pion.RTCPMuxPolicy {
+	if strings.ToLower(s) == "negotiate" {
+		return pion.RTCPMuxPolicyNegotiate
+	}
+	return pion.RTCPMuxPolicyRequire
+}
+
+// PeerConnection ...
+type PeerConnection struct {
+	candidates candidateQueue
+	gathering  gatheringState
+	events     broker
+	seen       candidateLog
+	log        pcLog
+	streams    streamSet
+	pc         *pion.PeerConnection
+
+	mu       sync.Mutex
+	sent     map[*pion.RTPSender]*MediaStreamTrack
+	received map[*pion.TrackRemote]*MediaStreamTrack
+	parked   map[*pion.RTPTransceiver]parkedTrack
+
+	onNegotiationNeeded        func()
+	onIceCandidate             func(*IceCandidate)
+	onIceCandidateError        func()
+	onSignalingStateChange     func(SignalingState)
+	onIceConnectionStateChange func(IceConnectionState)
+	onIceGatheringStateChange  func(IceGatheringState)
+	onConnectionStateChange    func(PeerConnectionState)
+	onDataChannel              func(*DataChannel)
+	onTrack                    func(*MediaStreamTrack, []*MediaStream)
+}
+
+// NewPeerConnection ...
+func NewPeerConnection(config *Configuration) (*PeerConnection, error) {
+	if config == nil {
+		config = NewConfiguration()
+	}
+	conf := pion.Configuration{
+		ICETransportPolicy: iceTransportPolicy(config.IceTransportPolicy),
+		BundlePolicy:       bundlePolicy(config.BundlePolicy),
+		RTCPMuxPolicy:      rtcpMuxPolicy(config.RTCPMuxPolicy),
+		PeerIdentity:       config.PeerIdentity,
+	}
+	for _, s := range config.IceServers {
+		server := pion.ICEServer{
+			URLs:     s.Urls,
+			Username: s.Username,
+		}
+		if len(s.Credential) > 0 {
+			server.Credential = s.Credential
+			server.CredentialType = pion.ICECredentialTypePassword
+		}
+		conf.ICEServers = append(conf.ICEServers, server)
+	}
+	factory := logging.NewDefaultLoggerFactory()
+	factory.DefaultLogLevel = logging.LogLevel(atomic.LoadInt32(&logLevel))
+	m := &pion.MediaEngine{}
+	if err := m.RegisterDefaultCodecs(); err != nil {
+		return nil, err
+	}
+	registry := &interceptor.Registry{}
+	if err := pion.RegisterDefaultInterceptors(m, registry); err != nil {
+		return nil, err
+	}
+	api := pion.NewAPI(
+		pion.WithMediaEngine(m),
+		pion.WithInterceptorRegistry(registry),
+		pion.WithSettingEngine(pion.SettingEngine{LoggerFactory: factory}),
+	)
+	pc, err := api.NewPeerConnection(conf)
+	if err != nil {
+		return nil, err
+	}
+	p := &PeerConnection{pc: pc}
+	p.log.init()
+	p.gathering.set(IceGatheringStateNew)
+	pc.OnNegotiationNeeded(func() {
+		p.emit(NegotiationNeededEvent{}, func() func() {
+			return p.onNegotiationNeeded
+		})
+	})
+	pc.OnICEGatheringStateChange(func(s pion.ICEGathererState) {
+		// pion also reports a closed gatherer, which has no W3C name.
+		if state, err := ParseIceGatheringState(s.String()); err == nil {
+			p.setGatheringState(state)
+		}
+	})
+	pc.OnICECandidate(func(ic *pion.ICECandidate) {
+		if ic == nil {
+			return
+		}
+		c := newIceCandidate(ic.ToJSON())
+		p.seen.addLocal(c)
+		p.emit(IceCandidateEvent{Candidate: c}, func() func() {
+			if cb := p.onIceCandidate; cb != nil {
+				return func() { cb(c) }
+			}
+			return nil
+		})
+	})
+	pc.OnSignalingStateChange(func(s pion.SignalingState) {
+		state, _ := ParseSignalingState(s.String())
+		p.emit(SignalingStateChangeEvent{State: state}, func() func() {
+			if cb := p.onSignalingStateChange; cb != nil {
+				return func() { cb(state) }
+			}
+			return nil
+		})
+	})
+	pc.OnICEConnectionStateChange(func(s pion.ICEConnectionState) {
+		state, _ := ParseIceConnectionState(s.String())
+		p.emit(IceConnectionStateChangeEvent{State: state}, func() func() {
+			if cb := p.onIceConnectionStateChange; cb != nil {
+				return func() { cb(state) }
+			}
+			return nil
+		})
+	})
+	pc.OnConnectionStateChange(func(s pion.PeerConnectionState) {
+		state, _ := ParsePeerConnectionState(s.String())
+		p.emit(ConnectionStateChangeEvent{State: state}, func() func() {
+			if cb := p.onConnectionStateChange; cb != nil {
+				return func() { cb(state) }
+			}
+			return nil
+		})
+	})
+	pc.OnDataChannel(func(dc *pion.DataChannel) {
+		c := newDataChannel(p, dc)
+		p.emit(DataChannelEvent{Channel: c}, func() func() {
+			if cb := p.onDataChannel; cb != nil {
+				return func() { cb(c) }
+			}
+			return nil
+		})
+	})
+	pc.OnTrack(func(remote *pion.TrackRemote, r *pion.RTPReceiver) {
+		t := &MediaStreamTrack{
+			id:       remote.ID(),
+			kind:     remote.Kind().String(),
+			mimeType: remote.Codec().MimeType,
+			remote:   remote,
+		}
+		p.mu.Lock()
+		if p.received == nil {
+			p.received = map[*pion.TrackRemote]*MediaStreamTrack{}
+		}
+		p.received[remote] = t
+		p.mu.Unlock()
+		streams := p.streams.add(remote.StreamID(), t)
+		p.emit(TrackEvent{Track: t, Streams: streams}, func() func() {
+			if cb := p.onTrack; cb != nil {
+				return func() { cb(t, streams) }
+			}
+			return nil
+		})
+	})
+	return p, nil
+}
+
+func newIceCandidate(ic pion.ICECandidateInit) *IceCandidate {
+	c := &IceCandidate{Candidate: ic.Candidate}
+	if ic.SDPMid != nil {
+		c.SdpMid = *ic.SDPMid
+	}
+	if ic.SDPMLineIndex != nil {
+		c.SdpMLineIndex = int(*ic.SDPMLineIndex)
+	}
+	return c
+}
+
+// setGatheringState reports a change of the gathering state.
+func (pc *PeerConnection) setGatheringState(state IceGatheringState) {
+	pc.mu.Lock()
+	changed := pc.gathering.get() != state
+	if changed {
+		pc.gathering.set(state)
+	}
+	pc.mu.Unlock()
+	if !changed {
+		return
+	}
+	pc.emit(IceGatheringStateChangeEvent{State: state}, func() func() {
+		if cb := pc.onIceGatheringStateChange; cb != nil {
+			return func() { cb(state) }
+		}
+		return nil
+	})
+}
+
+// emit publishes ev and then calls the callback cb returns. cb runs with
+// pc.mu held, so callbacks set from other goroutines are read without a race.
+func (pc *PeerConnection) emit(ev Event, cb func() func()) {
+	pc.publish(ev)
+	pc.mu.Lock()
+	f := cb()
+	pc.mu.Unlock()
+	if f != nil {
+		f()
+	}
+}
+
+// OnNegotiationNeeded ...
+func (pc *PeerConnection) OnNegotiationNeeded(cb func()) {
+	pc.mu.Lock()
+	pc.onNegotiationNeeded = cb
+	pc.mu.Unlock()
+}
+
+// OnIceCandidate ...
+func (pc *PeerConnection) OnIceCandidate(cb func(*IceCandidate)) {
+	pc.mu.Lock()
+	pc.onIceCandidate = cb
+	pc.mu.Unlock()
+}
+
+// OnIceCandidateError is never called: pion does not report candidate errors.
+func (pc *PeerConnection) OnIceCandidateError(cb func()) {
+	pc.mu.Lock()
+	pc.onIceCandidateError = cb
+	pc.mu.Unlock()
+}
+
+// OnSignalingStateChange ...
+func (pc *PeerConnection) OnSignalingStateChange(cb func(SignalingState)) {
+	pc.mu.Lock()
+	pc.onSignalingStateChange = cb
+	pc.mu.Unlock()
+}
+
+// OnIceConnectionStateChange ...
+func (pc *PeerConnection) OnIceConnectionStateChange(cb func(IceConnectionState)) {
+	pc.mu.Lock()
+	pc.onIceConnectionStateChange = cb
+	pc.mu.Unlock()
+}
+
+// OnIceGatheringStateChange ...
+func (pc *PeerConnection) OnIceGatheringStateChange(cb func(IceGatheringState)) {
+	pc.mu.Lock()
+	pc.onIceGatheringStateChange = cb
+	pc.mu.Unlock()
+}
+
+// OnConnectionStateChange ...
+func (pc *PeerConnection) OnConnectionStateChange(cb func(PeerConnectionState)) {
+	pc.mu.Lock()
+	pc.onConnectionStateChange = cb
+	pc.mu.Unlock()
+}
+
+// OnDataChannel ...
+func (pc *PeerConnection) OnDataChannel(cb func(*DataChannel)) {
+	pc.mu.Lock()
+	pc.onDataChannel = cb
+	pc.mu.Unlock()
+}
+
+// OnTrack is called with remote tracks and the streams they belong to.
+func (pc *PeerConnection) OnTrack(cb func(*MediaStreamTrack, []*MediaStream)) {
+	pc.mu.Lock()
+	pc.onTrack = cb
+	pc.mu.Unlock()
+}
+
+// AddTrack sends a track made by NewSampleTrack. Only the first stream is
+// signaled.
+func (pc *PeerConnection) AddTrack(track *MediaStreamTrack, streams ...*MediaStream) error {
+	_, err := pc.addTrack(track, streamID(streams))
+	return err
+}
+
+func (pc *PeerConnection) addTrack(track *MediaStreamTrack, stream string) (*pion.RTPSender, error) {
+	if track.remote != nil {
+		return nil, errors.New("add track: remote tracks cannot be sent")
+	}
+	local, err := pion.NewTrackLocalStaticSample(
+		pion.RTPCodecCapability{MimeType: track.mimeType}, track.id, stream,
+	)
+	if err != nil {
+		return nil, err
+	}
+	sender, err := pc.pc.AddTrack(local)
+	if err != nil {
+		return nil, err
+	}
+	track.mu.Lock()
+	if track.senders == nil {
+		track.senders = map[*PeerConnection]*trackSender{}
+	}
+	track.senders[pc] = &trackSender{local: local, sender: sender}
+	track.mu.Unlock()
+	pc.mu.Lock()
+	if pc.sent == nil {
+		pc.sent = map[*pion.RTPSender]*MediaStreamTrack{}
+	}
+	pc.sent[sender] = track
+	pc.mu.Unlock()
+	// interceptors only run while RTCP is read.
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			if _, _, err := sender.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+	return sender, nil
+}
+
+// RemoveTrack stops sending a track added by AddTrack.
+func (pc *PeerConnection) RemoveTrack(track *MediaStreamTrack) error {
+	track.mu.Lock()
+	s := track.senders[pc]
+	delete(track.senders, pc)
+	track.mu.Unlock()
+	if s == nil {
+		return errors.New("remove track: track not added")
+	}
+	pc.mu.Lock()
+	delete(pc.sent, s.sender)
+	pc.mu.Unlock()
+	return pc.pc.RemoveTrack(s.sender)
+}
+
+// AddTransceiver adds a transceiver of kind "audio" or "video". Tracks
+// added later with AddTrack reuse it.
+func (pc *PeerConnection) AddTransceiver(kind string, direction RTPTransceiverDirection) (*RTPTransceiver, error) {
+	k := pion.NewRTPCodecType(kind)
+	if k == 0 {
+		return nil, fmt.Errorf("add transceiver: unknown kind %q", kind)
+	}
+	d := pion.NewRTPTransceiverDirection(direction.String())
+	if d == pion.RTPTransceiverDirectionUnknown {
+		return nil, fmt.Errorf("add transceiver: invalid direction %s", direction)
+	}
+	t, err := pc.pc.AddTransceiverFromKind(k, pion.RTPTransceiverInit{Direction: d})
+	if err != nil {
+		return nil, err
+	}
+	return &RTPTransceiver{pc: pc, t: t}, nil
+}
+
+// GetTransceivers ...
+func (pc *PeerConnection) GetTransceivers() []*RTPTransceiver {
+	list := []*RTPTransceiver{}
+	for _, t := range pc.pc.GetTransceivers() {
+		list = append(list, &RTPTransceiver{pc: pc, t: t})
+	}
+	return list
+}
+
+// GetSenders ...
+func (pc *PeerConnection) GetSenders() []*RTPSender {
+	list := []*RTPSender{}
+	for _, s := range pc.pc.GetSenders() {
+		list = append(list, &RTPSender{pc: pc, s: s})
+	}
+	return list
+}
+
+// GetReceivers ...
+func (pc *PeerConnection) GetReceivers() []*RTPReceiver {
+	list := []*RTPReceiver{}
+	for _, r := range pc.pc.GetReceivers() {
+		list = append(list, &RTPReceiver{pc: pc, r: r})
+	}
+	return list
+}
+
+// OnAddStream is never called: pion only reports tracks, use OnTrack.
+func (pc *PeerConnection) OnAddStream(cb func(*MediaStream)) {
+	pc.neverCalled("OnAddStream")
+}
+
+// OnRemoveStream is never called: pion only reports tracks, use OnTrack.
+func (pc *PeerConnection) OnRemoveStream(cb func(*MediaStream)) {
+	pc.neverCalled("OnRemoveStream")
+}
+
+// AddStream returns ErrMediaNotSupported: use AddTrack.
+func (pc *PeerConnection) AddStream(stream *MediaStream) error {
+	return ErrMediaNotSupported
+}
+
+// OnRemoteCandidateError is called with remote candidates that failed to
+// apply once the remote description was set, or were dropped with
+// ErrCandidateDropped.
+func (pc *PeerConnection) OnRemoteCandidateError(cb func(*IceCandidate, error)) {
+	pc.candidates.setOnError(cb)
+}
+
+// AddIceCandidate adds a remote candidate. Candidates added before the remote
+// description is set are queued and applied by SetRemoteDescription.
+func (pc *PeerConnection) AddIceCandidate(ic *IceCandidate) error {
+	return pc.candidates.add(ic, pc.addIceCandidate)
+}
+
+// AddIceCandidateContext ...
+func (pc *PeerConnection) AddIceCandidateContext(ctx context.Context, ic *IceCandidate) error {
+	return pc.candidates.add(ic, func(ic *IceCandidate) error {
+		return withContext(ctx, func() error {
+			return pc.addIceCandidate(ic)
+		})
+	})
+}
+
+func (pc *PeerConnection) addIceCandidate(ic *IceCandidate) error {
+	index := uint16(ic.SdpMLineIndex)
+	opt := pion.ICECandidateInit{
+		Candidate:     ic.Candidate,
+		SDPMLineIndex: &index,
+	}
+	if ic.SdpMid != "" {
+		mid := ic.SdpMid
+		opt.SDPMid = &mid
+	}
+	if err := pc.pc.AddICECandidate(opt); err != nil {
+		return err
+	}
+	pc.seen.addRemote(ic)
+	return nil
+}
+
+// candidatePair returns the pair pion selected, as the candidates seen by
+// the wrapper when they match.
+func (pc *PeerConnection) candidatePair() (local, remote *IceCandidate) {
+	pair, err := pc.pc.SCTP().Transport().ICETransport().GetSelectedCandidatePair()
+	if err != nil || pair == nil {
+		return pc.seen.pair(pc.LocalDescription(), pc.RemoteDescription())
+	}
+	seenLocal, seenRemote := pc.seen.all()
+	return matchCandidate(seenLocal, pair.Local), matchCandidate(seenRemote, pair.Remote)
+}
+
+// matchCandidate finds c in cands by its transport address, since pion
+// formats candidates its own way.
+func matchCandidate(cands []*IceCandidate, c *pion.ICECandidate) *IceCandidate {
+	for _, ic := range cands {
+		p, err := sdp.ParseCandidate(ic.Candidate)
+		if err != nil {
+			continue
+		}
+		if p.Address == c.Address && p.Port == int(c.Port) &&
+			strings.EqualFold(p.Protocol, c.Protocol.String()) {
+			return ic
+		}
+	}
+	return newIceCandidate(c.ToJSON())
+}
+
+// Close ...
+func (pc *PeerConnection) Close() error {
+	pc.candidates.drop()
+	defer pc.events.close()
+	return pc.pc.Close()
+}
+
+// ConnectionState ...
+func (pc *PeerConnection) ConnectionState() PeerConnectionState {
+	state, _ := ParsePeerConnectionState(pc.pc.ConnectionState().String())
+	return state
+}
+
+// CreateAnswerContext ...
+func (pc *PeerConnection) CreateAnswerContext(ctx context.Context) (*SessionDescription, error) {
+	var sd *SessionDescription
+	err := withContext(ctx, func() (err error) {
+		sd, err = pc.CreateAnswer()
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sd, nil
+}
+
+// CreateAnswer ...
+func (pc *PeerConnection) CreateAnswer() (*SessionDescription, error) {
+	sd, err := pc.pc.CreateAnswer(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &SessionDescription{
+		Type: sd.Type.String(),
+		Sdp:  sd.SDP,
+	}, nil
+}
+
+// CreateDataChannel ...
+func (pc *PeerConnection) CreateDataChannel(label string, opts ...*DataChannelInit) (*DataChannel, error) {
+	p, err := dataChannelOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	opt := &pion.DataChannelInit{
+		Ordered:    &p.ordered,
+		Negotiated: &p.negotiated,
+	}
+	if p.lifetime >= 0 {
+		v := uint16(p.lifetime)
+		opt.MaxPacketLifeTime = &v
+	}
+	if p.retransmits >= 0 {
+		v := uint16(p.retransmits)
+		opt.MaxRetransmits = &v
+	}
+	if p.protocol != "" {
+		opt.Protocol = &p.protocol
+	}
+	if p.id >= 0 {
+		v := uint16(p.id)
+		opt.ID = &v
+	}
+	dc, err := pc.pc.CreateDataChannel(label, opt)
+	if err != nil {
+		return nil, err
+	}
+	return newDataChannel(pc, dc), nil
+}
+
+// CreateOfferContext ...
+func (pc *PeerConnection) CreateOfferContext(ctx context.Context) (*SessionDescription, error) {
+	var sd *SessionDescription
+	err := withContext(ctx, func() (err error) {
+		sd, err = pc.CreateOffer()
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sd, nil
+}
+
+// CreateOffer ...
+func (pc *PeerConnection) CreateOffer() (*SessionDescription, error) {
+	sd, err := pc.pc.CreateOffer(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &SessionDescription{
+		Type: sd.Type.String(),
+		Sdp:  sd.SDP,
+	}, nil
+}
+
+// IceGatheringState ...
+func (pc *PeerConnection) IceGatheringState() IceGatheringState {
+	return pc.gathering.get()
+}
+
+// IceConnectionState ...
+func (pc *PeerConnection) IceConnectionState() IceConnectionState {
+	state, _ := ParseIceConnectionState(pc.pc.ICEConnectionState().String())
+	return state
+}
+
+// LocalDescription ...
+func (pc *PeerConnection) LocalDescription() (sdp *SessionDescription) {
+	sd := pc.pc.LocalDescription()
+	if sd == nil {
+		return nil
+	}
+	return &SessionDescription{
+		Type: sd.Type.String(),
+		Sdp:  sd.SDP,
+	}
+}
+
+// RemoteDescription ...
+func (pc *PeerConnection) RemoteDescription() (sdp *SessionDescription) {
+	sd := pc.pc.RemoteDescription()
+	if sd == nil {
+		return nil
+	}
+	return &SessionDescription{
+		Type: sd.Type.String(),
+		Sdp:  sd.SDP,
+	}
+}
+
+// SetLocalDescriptionContext ...
+func (pc *PeerConnection) SetLocalDescriptionContext(ctx context.Context, sdp *SessionDescription) error {
+	return withContext(ctx, func() error {
+		return pc.SetLocalDescription(sdp)
+	})
+}
+
+// SetLocalDescription ...
+func (pc *PeerConnection) SetLocalDescription(sdp *SessionDescription) error {
+	return pc.pc.SetLocalDescription(pion.SessionDescription{
+		Type: pion.NewSDPType(sdp.Type),
+		SDP:  sdp.Sdp,
+	})
+}
+
+// SetRemoteDescriptionContext ...
+func (pc *PeerConnection) SetRemoteDescriptionContext(ctx context.Context, sdp *SessionDescription) error {
+	return withContext(ctx, func() error {
+		return pc.SetRemoteDescription(sdp)
+	})
+}
+
+// SetRemoteDescription ...
+func (pc *PeerConnection) SetRemoteDescription(sdp *SessionDescription) error {
+	err := pc.pc.SetRemoteDescription(pion.SessionDescription{
+		Type: pion.NewSDPType(sdp.Type),
+		SDP:  sdp.Sdp,
+	})
+	if err != nil {
+		return err
+	}
+	if sdp.Type != "rollback" {
+		pc.candidates.flush(pc.addIceCandidate)
+	}
+	return nil
+}
+
+// SignalingState ...
+func (pc *PeerConnection) SignalingState() SignalingState {
+	state, _ := ParseSignalingState(pc.pc.SignalingState().String())
+	return state
+}
+
+// DataChannel ...
+type DataChannel struct {
+	events broker
+	low    notifier
+	pc     *PeerConnection
+	dc     *pion.DataChannel
+
+	mu                  sync.Mutex
+	onOpen              func()
+	onClose             func()
+	onMessage           func([]byte)
+	onMessageData       func(Message)
+	onBufferedAmountLow func()
+}
+
+func newDataChannel(pc *PeerConnection, dc *pion.DataChannel) *DataChannel {
+	c := &DataChannel{pc: pc, dc: dc}
+	c.events.hold()
+	dc.OnOpen(func() {
+		c.emit(OpenEvent{}, func() func() {
+			return c.onOpen
+		})
+	})
+	dc.OnClose(func() {
+		c.emit(CloseEvent{}, func() func() {
+			return c.onClose
+		})
+		c.events.close()
+	})
+	dc.OnMessage(func(msg pion.DataChannelMessage) {
+		c.emit(MessageEvent{Data: msg.Data, IsString: msg.IsString}, func() func() {
+			onMessage, onMessageData := c.onMessage, c.onMessageData
+			return func() {
+				if onMessage != nil {
+					onMessage(msg.Data)
+				}
+				if onMessageData != nil {
+					onMessageData(Message{Data: msg.Data, IsString: msg.IsString})
+				}
+			}
+		})
+	})
+	dc.OnBufferedAmountLow(func() {
+		c.low.broadcast()
+		c.emit(BufferedAmountLowEvent{}, func() func() {
+			return c.onBufferedAmountLow
+		})
+	})
+	return c
+}
+
+// emit publishes ev and then calls the callback cb returns, with c.mu held
+// while cb reads it.
+func (c *DataChannel) emit(ev Event, cb func() func()) {
+	c.events.publish(ev)
+	c.mu.Lock()
+	f := cb()
+	c.mu.Unlock()
+	if f != nil {
+		f()
+	}
+}
+
+// OnOpen ...
+func (c *DataChannel) OnOpen(cb func()) {
+	c.mu.Lock()
+	c.onOpen = cb
+	c.mu.Unlock()
+}
+
+// OnClose ...
+func (c *DataChannel) OnClose(cb func()) {
+	c.mu.Lock()
+	c.onClose = cb
+	c.mu.Unlock()
+}
+
+// OnMessage ...
+func (c *DataChannel) OnMessage(cb func([]byte)) {
+	c.mu.Lock()
+	c.onMessage = cb
+	c.mu.Unlock()
+	c.events.release()
+}
+
+// OnMessageData is OnMessage with the frame type.
+func (c *DataChannel) OnMessageData(cb func(Message)) {
+	c.mu.Lock()
+	c.onMessageData = cb
+	c.mu.Unlock()
+	c.events.release()
+}
+
+// OnBufferedAmountLow ...
+func (c *DataChannel) OnBufferedAmountLow(cb func()) {
+	c.mu.Lock()
+	c.onBufferedAmountLow = cb
+	c.mu.Unlock()
+}
+
+// BufferedAmount ...
+func (c *DataChannel) BufferedAmount() int {
+	return int(c.dc.BufferedAmount())
+}
+
+// BufferedAmountLowThreshold ...
+func (c *DataChannel) BufferedAmountLowThreshold() int {
+	return int(c.dc.BufferedAmountLowThreshold())
+}
+
+// SetBufferedAmountLowThreshold ...
+func (c *DataChannel) SetBufferedAmountLowThreshold(n int) {
+	c.dc.SetBufferedAmountLowThreshold(uint64(n))
+}
+
+// Close ...
+func (c *DataChannel) Close() error {
+	return c.dc.Close()
+}
+
+// ID returns -1 until the ID is assigned.
+func (c *DataChannel) ID() int {
+	if id := c.dc.ID(); id != nil {
+		return int(*id)
+	}
+	return -1
+}
+
+// ReadyState ...
+func (c *DataChannel) ReadyState() string {
+	return c.dc.ReadyState().String()
+}
+
+// Send sends data. Failures are logged; SendContext returns them.
+func (c *DataChannel) Send(data []byte) {
+	c.sendFailed(c.sendMessage(data, false))
+}
+
+// SendText sends text as a string message.
+func (c *DataChannel) SendText(text string) {
+	c.sendFailed(c.sendMessage([]byte(text), true))
+}
+
+func (c *DataChannel) sendMessage(data []byte, isString bool) error {
+	if isString {
+		return c.dc.SendText(string(data))
+	}
+	return c.dc.Send(data)
+}
+
+// Label ...
+func (c *DataChannel) Label() string {
+	return c.dc.Label()
+}
+
+// Ordered ...
+func (c *DataChannel) Ordered() bool {
+	return c.dc.Ordered()
+}
+
+// MaxPacketLifeTime returns -1 if unset.
+func (c *DataChannel) MaxPacketLifeTime() int {
+	if v := c.dc.MaxPacketLifeTime(); v != nil {
+		return int(*v)
+	}
+	return -1
+}
+
+// MaxRetransmits returns -1 if unset.
+func (c *DataChannel) MaxRetransmits() int {
+	if v := c.dc.MaxRetransmits(); v != nil {
+		return int(*v)
+	}
+	return -1
+}
+
+// Protocol ...
+func (c *DataChannel) Protocol() string {
+	return c.dc.Protocol()
+}
+
+// Negotiated ...
+func (c *DataChannel) Negotiated() bool {
+	return c.dc.Negotiated()
+}
+
+// GetUserMedia returns ErrMediaNotSupported: pion has no capture devices,
+// feed a track made by NewSampleTrack instead.
+func GetUserMedia(constraints *Constraints) (*MediaStream, error) {
+	return nil, ErrMediaNotSupported
+}
+
+// GetUserMediaContext returns ErrMediaNotSupported.
+func GetUserMediaContext(ctx context.Context, constraints *Constraints) (*MediaStream, error) {
+	return nil, ErrMediaNotSupported
+}
+
+type trackSender struct {
+	local  *pion.TrackLocalStaticSample
+	sender *pion.RTPSender
+}
+
+// MediaStreamTrack is a local track fed with samples, or a remote track
+// read as RTP packets.
+type MediaStreamTrack struct {
+	id       string
+	kind     string
+	mimeType string
+	remote   *pion.TrackRemote
+
+	mu      sync.Mutex
+	senders map[*PeerConnection]*trackSender
+	stopped bool
+}
+
+// NewSampleTrack returns a local track encoded as mimeType, such as
+// "video/VP8" or "audio/opus", for samples written with WriteSample.
+func NewSampleTrack(id, mimeType string) (*MediaStreamTrack, error) {
+	kind, err := trackKind(mimeType)
+	if err != nil {
+		return nil, err
+	}
+	return &MediaStreamTrack{id: id, kind: kind, mimeType: mimeType}, nil
+}
+
+// ID ...
+func (t *MediaStreamTrack) ID() string {
+	return t.id
+}
+
+// Kind ...
+func (t *MediaStreamTrack) Kind() string {
+	return t.kind
+}
+
+// MimeType ...
+func (t *MediaStreamTrack) MimeType() string {
+	return t.mimeType
+}
+
+// WriteSample sends one encoded frame lasting duration to every
+// PeerConnection the track was added to.
+func (t *MediaStreamTrack) WriteSample(data []byte, duration time.Duration) error {
+	t.mu.Lock()
+	if t.stopped {
+		t.mu.Unlock()
+		return ErrTrackStopped
+	}
+	if t.remote != nil {
+		t.mu.Unlock()
+		return errors.New("write sample: remote track")
+	}
+	locals := make([]*pion.TrackLocalStaticSample, 0, len(t.senders))
+	for _, s := range t.senders {
+		locals = append(locals, s.local)
+	}
+	t.mu.Unlock()
+	for _, local := range locals {
+		if err := local.WriteSample(media.Sample{Data: data, Duration: duration}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadRTP reads one RTP packet of a remote track.
+func (t *MediaStreamTrack) ReadRTP(b []byte) (int, error) {
+	t.mu.Lock()
+	stopped := t.stopped
+	t.mu.Unlock()
+	if stopped {
+		return 0, ErrTrackStopped
+	}
+	if t.remote == nil {
+		return 0, errors.New("read rtp: local track")
+	}
+	n, _, err := t.remote.Read(b)
+	return n, err
+}
+
+// Stop ends WriteSample and ReadRTP, and removes the track from every
+// PeerConnection it was added to.
+func (t *MediaStreamTrack) Stop() {
+	t.mu.Lock()
+	t.stopped = true
+	pcs := make([]*PeerConnection, 0, len(t.senders))
+	for pc := range t.senders {
+		pcs = append(pcs, pc)
+	}
+	t.mu.Unlock()
+	for _, pc := range pcs {
+		if err := pc.RemoveTrack(t); err != nil {
+			pc.log.logger().Warn("stop track", "track", t.id, "error", err)
+		}
+	}
+}
+
+// RTPTransceiver ...
+type RTPTransceiver struct {
+	pc *PeerConnection
+	t  *pion.RTPTransceiver
+}
+
+// Mid returns "" until the transceiver is negotiated.
+func (t *RTPTransceiver) Mid() string {
+	return t.t.Mid()
+}
+
+// Kind ...
+func (t *RTPTransceiver) Kind() string {
+	return t.t.Kind().String()
+}
+
+// Direction ...
+func (t *RTPTransceiver) Direction() RTPTransceiverDirection {
+	d, _ := ParseRTPTransceiverDirection(t.t.Direction().String())
+	return d
+}
+
+// SetDirection changes the direction by removing or adding back the track
+// of the sender, as pion derives the direction from AddTrack and
+// RemoveTrack. The supported changes are
+//
+//	sendrecv <-> recvonly
+//	sendonly <-> inactive
+//
+// where sending starts again with the track that SetDirection stopped. A
+// transceiver that had no track can't start sending; use AddTrack. Other
+// changes fail with ErrDirectionNotSupported.
+func (t *RTPTransceiver) SetDirection(d RTPTransceiverDirection) error {
+	cur := t.Direction()
+	if d == cur {
+		return nil
+	}
+	var err error
+	switch {
+	case cur == RTPTransceiverDirectionSendrecv && d == RTPTransceiverDirectionRecvonly,
+		cur == RTPTransceiverDirectionSendonly && d == RTPTransceiverDirectionInactive:
+		err = t.stopSending()
+	case cur == RTPTransceiverDirectionRecvonly && d == RTPTransceiverDirectionSendrecv,
+		cur == RTPTransceiverDirectionInactive && d == RTPTransceiverDirectionSendonly:
+		err = t.resumeSending()
+	default:
+		err = ErrDirectionNotSupported
+	}
+	if err != nil {
+		return fmt.Errorf("set direction %s to %s: %w", cur, d, err)
+	}
+	return nil
+}
+
+// parkedTrack is a track that SetDirection stopped sending on a
+// transceiver, kept to be sent again.
+type parkedTrack struct {
+	track  *MediaStreamTrack
+	stream string
+}
+
+func (t *RTPTransceiver) stopSending() error {
+	var track *MediaStreamTrack
+	if s := t.Sender(); s != nil {
+		track = s.Track()
+	}
+	if track == nil {
+		return ErrDirectionNotSupported
+	}
+	track.mu.Lock()
+	stream := noStream
+	if s := track.senders[t.pc]; s != nil {
+		stream = s.local.StreamID()
+	}
+	track.mu.Unlock()
+	if err := t.pc.RemoveTrack(track); err != nil {
+		return err
+	}
+	t.pc.mu.Lock()
+	if t.pc.parked == nil {
+		t.pc.parked = map[*pion.RTPTransceiver]parkedTrack{}
+	}
+	t.pc.parked[t.t] = parkedTrack{track: track, stream: stream}
+	t.pc.mu.Unlock()
+	return nil
+}
+
+func (t *RTPTransceiver) resumeSending() error {
+	t.pc.mu.Lock()
+	p, ok := t.pc.parked[t.t]
+	t.pc.mu.Unlock()
+	if !ok {
+		return ErrDirectionNotSupported
+	}
+	p.track.mu.Lock()
+	stopped := p.track.stopped
+	p.track.mu.Unlock()
+	if stopped {
+		return ErrTrackStopped
+	}
+	sender, err := t.pc.addTrack(p.track, p.stream)
+	if err != nil {
+		return err
+	}
+	// pion gives the track to the first transceiver of its kind that has
+	// no sender, which need not be this one.
+	if t.t.Sender() != sender {
+		t.pc.RemoveTrack(p.track)
+		return ErrDirectionNotSupported
+	}
+	t.pc.mu.Lock()
+	delete(t.pc.parked, t.t)
+	t.pc.mu.Unlock()
+	return nil
+}
+
+// Stop ...
+func (t *RTPTransceiver) Stop() error {
+	t.pc.mu.Lock()
+	delete(t.pc.parked, t.t)
+	t.pc.mu.Unlock()
+	return t.t.Stop()
+}
+
+// Sender ...
+func (t *RTPTransceiver) Sender() *RTPSender {
+	if s := t.t.Sender(); s != nil {
+		return &RTPSender{pc: t.pc, s: s}
+	}
+	return nil
+}
+
+// Receiver ...
+func (t *RTPTransceiver) Receiver() *RTPReceiver {
+	if r := t.t.Receiver(); r != nil {
+		return &RTPReceiver{pc: t.pc, r: r}
+	}
+	return nil
+}
+
+// RTPSender ...
+type RTPSender struct {
+	pc *PeerConnection
+	s  *pion.RTPSender
+}
+
+// Track returns the track added with AddTrack, or nil.
+func (s *RTPSender) Track() *MediaStreamTrack {
+	s.pc.mu.Lock()
+	defer s.pc.mu.Unlock()
+	return s.pc.sent[s.s]
+}
+
+// RTPReceiver ...
+type RTPReceiver struct {
+	pc *PeerConnection
+	r  *pion.RTPReceiver
+}
+
+// Track returns the remote track passed to OnTrack, or nil before it
+// arrived.
+func (r *RTPReceiver) Track() *MediaStreamTrack {
+	remote := r.r.Track()
+	if remote == nil {
+		return nil
+	}
+	r.pc.mu.Lock()
+	defer r.pc.mu.Unlock()
+	return r.pc.received[remote]
+}
@@ -0,0 +1,986 @@
+// +build js,wasm
+
+package webrtc
+
+// Go functions called from JS run on the browser's event loop, which waits
+// for them to return. Listeners therefore only read the event and publish
+// it; user callbacks run on the dispatcher of the PeerConnection, where they
+// may block, and await is only called outside of listeners.
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"syscall/js"
+)
+
+var (
+	navigator      = js.Global().Get("navigator")
+	peerConnection = js.Global().Get("RTCPeerConnection")
+	promise        = js.Global().Get("Promise")
+	uint8Array     = js.Global().Get("Uint8Array")
+)
+
+// canRollback reports whether rollback descriptions are accepted.
+var canRollback = true
+
+// SetLoggingVerbosity is a no-op; browsers have no such setting.
+func SetLoggingVerbosity(level int) {}
+
+// jsString returns String(v), as JS prints errors.
+func jsString(v js.Value) string {
+	return js.Global().Get("String").Invoke(v).String()
+}
+
+// await waits for the JS promise p to settle or ctx to be done. p races
+// with a promise settled when ctx is done, so that exactly one of the
+// callbacks runs and releases both even if p never settles, and a late
+// result of p is ignored.
+func await(ctx context.Context, p js.Value) (js.Value, error) {
+	type result struct {
+		v   js.Value
+		err error
+	}
+	ch := make(chan result, 1)
+	var stop js.Value
+	executor := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		stop = args[0]
+		return nil
+	})
+	stopped := promise.New(executor)
+	executor.Release()
+	var then, catch js.Func
+	settle := func(r result) {
+		ch <- r
+		then.Release()
+		catch.Release()
+	}
+	then = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		v := js.Undefined()
+		if len(args) > 0 {
+			v = args[0]
+		}
+		settle(result{v: v})
+		return nil
+	})
+	catch = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		settle(result{err: fmt.Errorf("%s", jsString(args[0]))})
+		return nil
+	})
+	promise.Call("race", []interface{}{p, stopped}).Call("then", then, catch)
+	select {
+	case r := <-ch:
+		return r.v, r.err
+	case <-ctx.Done():
+		stop.Invoke()
+		return js.Undefined(), ctx.Err()
+	}
+}
+
+type listener struct {
+	name string
+	f    js.Func
+}
+
+// listeners are the event listeners added to one JS object, removed and
+// released together once the object is closed.
+type listeners struct {
+	target js.Value
+	mu     sync.Mutex
+	funcs  []listener
+	done   bool
+}
+
+func (l *listeners) add(name string, f func(ev js.Value)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.done {
+		return
+	}
+	fn := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		f(args[0])
+		return nil
+	})
+	l.target.Call("addEventListener", name, fn, false)
+	l.funcs = append(l.funcs, listener{name: name, f: fn})
+}
+
+// release removes the listeners on a later task, so that the event being
+// dispatched still reaches all of them.
+func (l *listeners) release() {
+	l.mu.Lock()
+	funcs := l.funcs
+	l.funcs, l.done = nil, true
+	l.mu.Unlock()
+	var cleanup js.Func
+	cleanup = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		for _, x := range funcs {
+			l.target.Call("removeEventListener", x.name, x.f, false)
+			x.f.Release()
+		}
+		cleanup.Release()
+		return nil
+	})
+	js.Global().Call("setTimeout", cleanup, 0)
+}
+
+// PeerConnection ...
+type PeerConnection struct {
+	candidates candidateQueue
+	gathering  gatheringState
+	events     broker
+	seen       candidateLog
+	log        pcLog
+	loop       dispatcher
+	listeners  listeners
+	pc         js.Value
+
+	mu            sync.Mutex
+	onDataChannel []func(*DataChannel)
+	onTrack       []func(*MediaStreamTrack, []*MediaStream)
+}
+
+// NewPeerConnection ...
+func NewPeerConnection(config *Configuration) (pc *PeerConnection, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("create peer connection: %s", r)
+			pc = nil
+		}
+	}()
+	jpc := peerConnection.New(config.value())
+	pc = &PeerConnection{pc: jpc}
+	pc.listeners.target = jpc
+	pc.log.init()
+	pc.gathering.set(pc.IceGatheringState())
+	pc.listeners.add("icegatheringstatechange", func(ev js.Value) {
+		pc.gathering.set(pc.IceGatheringState())
+	})
+	pc.listeners.add("icecandidate", func(ev js.Value) {
+		if !ev.Get("candidate").Truthy() {
+			pc.gathering.set(IceGatheringStateComplete)
+		}
+	})
+	pc.publishEvents()
+	return
+}
+
+func (pc *PeerConnection) publishEvents() {
+	listen := func(name string, f func(ev js.Value) Event) {
+		pc.listeners.add(name, func(ev js.Value) {
+			if e := f(ev); e != nil {
+				pc.publish(e)
+			}
+		})
+	}
+	listen("negotiationneeded", func(ev js.Value) Event {
+		return NegotiationNeededEvent{}
+	})
+	listen("icecandidate", func(ev js.Value) Event {
+		candidate := ev.Get("candidate")
+		if !candidate.Truthy() {
+			return nil
+		}
+		ic := NewIceCandidateFromObj(candidate)
+		pc.seen.addLocal(ic)
+		return IceCandidateEvent{Candidate: ic}
+	})
+	listen("icecandidateerror", func(ev js.Value) Event {
+		return IceCandidateErrorEvent{}
+	})
+	listen("signalingstatechange", func(ev js.Value) Event {
+		return SignalingStateChangeEvent{State: pc.SignalingState()}
+	})
+	listen("iceconnectionstatechange", func(ev js.Value) Event {
+		return IceConnectionStateChangeEvent{State: pc.IceConnectionState()}
+	})
+	listen("icegatheringstatechange", func(ev js.Value) Event {
+		return IceGatheringStateChangeEvent{State: pc.IceGatheringState()}
+	})
+	listen("connectionstatechange", func(ev js.Value) Event {
+		return ConnectionStateChangeEvent{State: pc.ConnectionState()}
+	})
+	listen("addstream", func(ev js.Value) Event {
+		return AddStreamEvent{Stream: &MediaStream{o: ev.Get("stream")}}
+	})
+	listen("removestream", func(ev js.Value) Event {
+		return RemoveStreamEvent{Stream: &MediaStream{o: ev.Get("stream")}}
+	})
+	// one wrapper per channel, shared by Events and OnDataChannel.
+	listen("datachannel", func(ev js.Value) Event {
+		c := newDataChannel(pc, ev.Get("channel"))
+		pc.mu.Lock()
+		callbacks := pc.onDataChannel
+		pc.mu.Unlock()
+		for _, cb := range callbacks {
+			cb := cb
+			pc.loop.post(func() { cb(c) })
+		}
+		return DataChannelEvent{Channel: c}
+	})
+	listen("track", func(ev js.Value) Event {
+		t := &MediaStreamTrack{o: ev.Get("track")}
+		streams := []*MediaStream{}
+		list := ev.Get("streams")
+		for i := 0; i < list.Length(); i++ {
+			streams = append(streams, &MediaStream{o: list.Index(i)})
+		}
+		pc.mu.Lock()
+		callbacks := pc.onTrack
+		pc.mu.Unlock()
+		for _, cb := range callbacks {
+			cb := cb
+			pc.loop.post(func() { cb(t, streams) })
+		}
+		return TrackEvent{Track: t, Streams: streams}
+	})
+}
+
+// OnNegotiationNeeded ...
+func (pc *PeerConnection) OnNegotiationNeeded(cb func()) {
+	pc.listeners.add("negotiationneeded", func(ev js.Value) {
+		pc.loop.post(cb)
+	})
+}
+
+// OnIceCandidate ...
+func (pc *PeerConnection) OnIceCandidate(cb func(*IceCandidate)) {
+	pc.listeners.add("icecandidate", func(ev js.Value) {
+		candidate := ev.Get("candidate")
+		if !candidate.Truthy() {
+			return
+		}
+		ic := NewIceCandidateFromObj(candidate)
+		pc.loop.post(func() { cb(ic) })
+	})
+}
+
+// OnIceCandidateError ...
+func (pc *PeerConnection) OnIceCandidateError(cb func()) {
+	pc.listeners.add("icecandidateerror", func(ev js.Value) {
+		pc.loop.post(cb)
+	})
+}
+
+// OnSignalingStateChange ...
+func (pc *PeerConnection) OnSignalingStateChange(cb func(SignalingState)) {
+	pc.listeners.add("signalingstatechange", func(ev js.Value) {
+		state := pc.SignalingState()
+		pc.loop.post(func() { cb(state) })
+	})
+}
+
+// OnIceConnectionStateChange ...
+func (pc *PeerConnection) OnIceConnectionStateChange(cb func(IceConnectionState)) {
+	pc.listeners.add("iceconnectionstatechange", func(ev js.Value) {
+		state := pc.IceConnectionState()
+		pc.loop.post(func() { cb(state) })
+	})
+}
+
+// OnIceGatheringStateChange ...
+func (pc *PeerConnection) OnIceGatheringStateChange(cb func(IceGatheringState)) {
+	pc.listeners.add("icegatheringstatechange", func(ev js.Value) {
+		state := pc.IceGatheringState()
+		pc.loop.post(func() { cb(state) })
+	})
+}
+
+// OnConnectionStateChange ...
+func (pc *PeerConnection) OnConnectionStateChange(cb func(PeerConnectionState)) {
+	pc.listeners.add("connectionstatechange", func(ev js.Value) {
+		state := pc.ConnectionState()
+		pc.loop.post(func() { cb(state) })
+	})
+}
+
+// OnDataChannel ...
+func (pc *PeerConnection) OnDataChannel(cb func(*DataChannel)) {
+	pc.mu.Lock()
+	pc.onDataChannel = append(pc.onDataChannel, cb)
+	pc.mu.Unlock()
+}
+
+// OnAddStream ...
+func (pc *PeerConnection) OnAddStream(cb func(*MediaStream)) {
+	pc.listeners.add("addstream", func(ev js.Value) {
+		stream := &MediaStream{o: ev.Get("stream")}
+		pc.loop.post(func() { cb(stream) })
+	})
+}
+
+// OnRemoveStream ...
+func (pc *PeerConnection) OnRemoveStream(cb func(*MediaStream)) {
+	pc.listeners.add("removestream", func(ev js.Value) {
+		stream := &MediaStream{o: ev.Get("stream")}
+		pc.loop.post(func() { cb(stream) })
+	})
+}
+
+// AddStream ...
+func (pc *PeerConnection) AddStream(stream *MediaStream) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s", r)
+		}
+	}()
+	pc.pc.Call("addStream", stream.o)
+	return
+}
+
+// OnTrack is called with remote tracks and the streams they belong to.
+func (pc *PeerConnection) OnTrack(cb func(*MediaStreamTrack, []*MediaStream)) {
+	pc.mu.Lock()
+	pc.onTrack = append(pc.onTrack, cb)
+	pc.mu.Unlock()
+}
+
+// AddTrack ...
+func (pc *PeerConnection) AddTrack(track *MediaStreamTrack, streams ...*MediaStream) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s", r)
+		}
+	}()
+	args := []interface{}{track.o}
+	for _, s := range streams {
+		args = append(args, s.o)
+	}
+	pc.pc.Call("addTrack", args...)
+	return
+}
+
+// RemoveTrack removes the sender of track.
+func (pc *PeerConnection) RemoveTrack(track *MediaStreamTrack) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s", r)
+		}
+	}()
+	senders := pc.pc.Call("getSenders")
+	for i := 0; i < senders.Length(); i++ {
+		sender := senders.Index(i)
+		if sender.Get("track").Equal(track.o) {
+			pc.pc.Call("removeTrack", sender)
+			return nil
+		}
+	}
+	return fmt.Errorf("remove track: track not added")
+}
+
+// AddTransceiver adds a transceiver of kind "audio" or "video".
+func (pc *PeerConnection) AddTransceiver(kind string, direction RTPTransceiverDirection) (t *RTPTransceiver, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s", r)
+			t = nil
+		}
+	}()
+	o := pc.pc.Call("addTransceiver", kind, map[string]interface{}{
+		"direction": direction.String(),
+	})
+	return &RTPTransceiver{o: o}, nil
+}
+
+// GetTransceivers ...
+func (pc *PeerConnection) GetTransceivers() []*RTPTransceiver {
+	list := []*RTPTransceiver{}
+	a := pc.pc.Call("getTransceivers")
+	for i := 0; i < a.Length(); i++ {
+		list = append(list, &RTPTransceiver{o: a.Index(i)})
+	}
+	return list
+}
+
+// GetSenders ...
+func (pc *PeerConnection) GetSenders() []*RTPSender {
+	list := []*RTPSender{}
+	a := pc.pc.Call("getSenders")
+	for i := 0; i < a.Length(); i++ {
+		list = append(list, &RTPSender{o: a.Index(i)})
+	}
+	return list
+}
+
+// GetReceivers ...
+func (pc *PeerConnection) GetReceivers() []*RTPReceiver {
+	list := []*RTPReceiver{}
+	a := pc.pc.Call("getReceivers")
+	for i := 0; i < a.Length(); i++ {
+		list = append(list, &RTPReceiver{o: a.Index(i)})
+	}
+	return list
+}
+
+// OnRemoteCandidateError is called with remote candidates that failed to
+// apply once the remote description was set, or were dropped with
+// ErrCandidateDropped.
+func (pc *PeerConnection) OnRemoteCandidateError(cb func(*IceCandidate, error)) {
+	pc.candidates.setOnError(cb)
+}
+
+// AddIceCandidate adds a remote candidate. Candidates added before the remote
+// description is set are queued and applied by SetRemoteDescription.
+func (pc *PeerConnection) AddIceCandidate(ic *IceCandidate) error {
+	return pc.AddIceCandidateContext(context.Background(), ic)
+}
+
+// AddIceCandidateContext ...
+func (pc *PeerConnection) AddIceCandidateContext(ctx context.Context, ic *IceCandidate) error {
+	return pc.candidates.add(ic, func(ic *IceCandidate) error {
+		return pc.addIceCandidate(ctx, ic)
+	})
+}
+
+func (pc *PeerConnection) addIceCandidate(ctx context.Context, ic *IceCandidate) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s", r)
+		}
+	}()
+	if _, err = await(ctx, pc.pc.Call("addIceCandidate", ic.value())); err != nil {
+		return
+	}
+	pc.seen.addRemote(ic)
+	return
+}
+
+// candidatePair returns the selected candidate pair of the SCTP transport,
+// or an approximation where the browser does not expose it.
+func (pc *PeerConnection) candidatePair() (local, remote *IceCandidate) {
+	defer func() {
+		if r := recover(); r != nil {
+			local, remote = pc.seen.pair(pc.LocalDescription(), pc.RemoteDescription())
+		}
+	}()
+	ice := pc.pc.Get("sctp").Get("transport").Get("iceTransport")
+	p := ice.Call("getSelectedCandidatePair")
+	if p.IsNull() || p.IsUndefined() {
+		panic("no selected candidate pair")
+	}
+	return NewIceCandidateFromObj(p.Get("local")), NewIceCandidateFromObj(p.Get("remote"))
+}
+
+// Close closes the connection and releases its listeners.
+func (pc *PeerConnection) Close() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s", r)
+		}
+	}()
+	pc.candidates.drop()
+	defer pc.events.close()
+	defer pc.listeners.release()
+	pc.pc.Call("close")
+	return
+}
+
+// ConnectionState ...
+func (pc *PeerConnection) ConnectionState() PeerConnectionState {
+	state, _ := ParsePeerConnectionState(str(pc.pc.Get("connectionState")))
+	return state
+}
+
+// CreateAnswer ...
+func (pc *PeerConnection) CreateAnswer() (*SessionDescription, error) {
+	return pc.CreateAnswerContext(context.Background())
+}
+
+// CreateAnswerContext ...
+func (pc *PeerConnection) CreateAnswerContext(ctx context.Context) (s *SessionDescription, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s", r)
+		}
+	}()
+	desc, err := await(ctx, pc.pc.Call("createAnswer"))
+	if err != nil {
+		if ctx.Err() == nil {
+			err = fmt.Errorf("create answer failed: %s", err)
+		}
+		return nil, err
+	}
+	return NewSessionDescriptionFromObj(desc), nil
+}
+
+// CreateDataChannel ...
+func (pc *PeerConnection) CreateDataChannel(label string, opts ...*DataChannelInit) (dc *DataChannel, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s", r)
+		}
+	}()
+	p, err := dataChannelOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	jdc := pc.pc.Call("createDataChannel", label, p.dict())
+	dc = newDataChannel(pc, jdc)
+	return
+}
+
+// CreateOffer ...
+func (pc *PeerConnection) CreateOffer() (*SessionDescription, error) {
+	return pc.CreateOfferContext(context.Background())
+}
+
+// CreateOfferContext ...
+func (pc *PeerConnection) CreateOfferContext(ctx context.Context) (s *SessionDescription, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s", r)
+		}
+	}()
+	desc, err := await(ctx, pc.pc.Call("createOffer"))
+	if err != nil {
+		if ctx.Err() == nil {
+			err = fmt.Errorf("create offer failed: %s", err)
+		}
+		return nil, err
+	}
+	return NewSessionDescriptionFromObj(desc), nil
+}
+
+// GetStats ...
+func (pc *PeerConnection) GetStats(ctx context.Context) (r *StatsReport, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("%s", e)
+		}
+	}()
+	report, err := await(ctx, pc.pc.Call("getStats"))
+	if err != nil {
+		if ctx.Err() == nil {
+			err = fmt.Errorf("get stats failed: %s", err)
+		}
+		return nil, err
+	}
+	r = &StatsReport{}
+	stringify := js.Global().Get("JSON").Get("stringify")
+	each := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if e := r.addJSON([]byte(stringify.Invoke(args[0]).String())); e != nil && err == nil {
+			err = e
+		}
+		return nil
+	})
+	defer each.Release()
+	report.Call("forEach", each)
+	return r, err
+}
+
+// IceGatheringState ...
+func (pc *PeerConnection) IceGatheringState() IceGatheringState {
+	state, _ := ParseIceGatheringState(str(pc.pc.Get("iceGatheringState")))
+	return state
+}
+
+// IceConnectionState ...
+func (pc *PeerConnection) IceConnectionState() IceConnectionState {
+	state, _ := ParseIceConnectionState(str(pc.pc.Get("iceConnectionState")))
+	return state
+}
+
+// LocalDescription ...
+func (pc *PeerConnection) LocalDescription() (sdp *SessionDescription) {
+	sd := pc.pc.Get("localDescription")
+	if sd.IsNull() {
+		return nil
+	}
+	return NewSessionDescriptionFromObj(sd)
+}
+
+// RemoteDescription ...
+func (pc *PeerConnection) RemoteDescription() (sdp *SessionDescription) {
+	sd := pc.pc.Get("remoteDescription")
+	if sd.IsNull() {
+		return nil
+	}
+	return NewSessionDescriptionFromObj(sd)
+}
+
+// SetLocalDescription ...
+func (pc *PeerConnection) SetLocalDescription(sdp *SessionDescription) error {
+	return pc.SetLocalDescriptionContext(context.Background(), sdp)
+}
+
+// SetLocalDescriptionContext ...
+func (pc *PeerConnection) SetLocalDescriptionContext(ctx context.Context, sdp *SessionDescription) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s", r)
+		}
+	}()
+	_, err = await(ctx, pc.pc.Call("setLocalDescription", sdp.value()))
+	return
+}
+
+// SetRemoteDescription ...
+func (pc *PeerConnection) SetRemoteDescription(sdp *SessionDescription) error {
+	return pc.SetRemoteDescriptionContext(context.Background(), sdp)
+}
+
+// SetRemoteDescriptionContext ...
+func (pc *PeerConnection) SetRemoteDescriptionContext(ctx context.Context, sdp *SessionDescription) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s", r)
+		}
+	}()
+	if _, err = await(ctx, pc.pc.Call("setRemoteDescription", sdp.value())); err != nil {
+		return
+	}
+	if sdp.Type != "rollback" {
+		pc.candidates.flush(func(ic *IceCandidate) error {
+			return pc.addIceCandidate(ctx, ic)
+		})
+	}
+	return
+}
+
+// SignalingState ...
+func (pc *PeerConnection) SignalingState() SignalingState {
+	state, _ := ParseSignalingState(str(pc.pc.Get("signalingState")))
+	return state
+}
+
+// DataChannel ...
+type DataChannel struct {
+	events    broker
+	low       notifier
+	pc        *PeerConnection
+	listeners listeners
+	dc        js.Value
+}
+
+// newMessage copies the data of a message event. Binary messages arrive as
+// ArrayBuffer because newDataChannel sets binaryType.
+func newMessage(data js.Value) Message {
+	if data.Type() == js.TypeString {
+		return Message{Data: []byte(data.String()), IsString: true}
+	}
+	if buf := data.Get("buffer"); !buf.IsUndefined() {
+		data = uint8Array.New(buf, data.Get("byteOffset"), data.Get("byteLength"))
+	} else {
+		data = uint8Array.New(data)
+	}
+	b := make([]byte, data.Get("byteLength").Int())
+	js.CopyBytesToGo(b, data)
+	return Message{Data: b}
+}
+
+func newDataChannel(pc *PeerConnection, dc js.Value) *DataChannel {
+	c := &DataChannel{pc: pc, dc: dc}
+	c.events.hold()
+	c.listeners.target = dc
+	// Blob payloads can only be read asynchronously, which would reorder
+	// messages.
+	dc.Set("binaryType", "arraybuffer")
+	c.listeners.add("open", func(ev js.Value) {
+		c.events.publish(OpenEvent{})
+	})
+	c.listeners.add("close", func(ev js.Value) {
+		c.events.publish(CloseEvent{})
+		c.events.close()
+		c.listeners.release()
+	})
+	c.listeners.add("message", func(ev js.Value) {
+		m := newMessage(ev.Get("data"))
+		c.events.publish(MessageEvent{Data: m.Data, IsString: m.IsString})
+	})
+	c.listeners.add("bufferedamountlow", func(ev js.Value) {
+		c.low.broadcast()
+		c.events.publish(BufferedAmountLowEvent{})
+	})
+	return c
+}
+
+// OnOpen ...
+func (c *DataChannel) OnOpen(cb func()) {
+	c.listeners.add("open", func(ev js.Value) {
+		c.pc.loop.post(cb)
+	})
+}
+
+// OnClose ...
+func (c *DataChannel) OnClose(cb func()) {
+	c.listeners.add("close", func(ev js.Value) {
+		c.pc.loop.post(cb)
+	})
+}
+
+// OnMessage ...
+func (c *DataChannel) OnMessage(cb func([]byte)) {
+	c.listeners.add("message", func(ev js.Value) {
+		m := newMessage(ev.Get("data"))
+		c.pc.loop.post(func() { cb(m.Data) })
+	})
+	c.events.release()
+}
+
+// OnMessageData is OnMessage with the frame type.
+func (c *DataChannel) OnMessageData(cb func(Message)) {
+	c.listeners.add("message", func(ev js.Value) {
+		m := newMessage(ev.Get("data"))
+		c.pc.loop.post(func() { cb(m) })
+	})
+	c.events.release()
+}
+
+// OnBufferedAmountLow ...
+func (c *DataChannel) OnBufferedAmountLow(cb func()) {
+	c.listeners.add("bufferedamountlow", func(ev js.Value) {
+		c.pc.loop.post(cb)
+	})
+}
+
+// BufferedAmount ...
+func (c *DataChannel) BufferedAmount() int {
+	return c.dc.Get("bufferedAmount").Int()
+}
+
+// BufferedAmountLowThreshold ...
+func (c *DataChannel) BufferedAmountLowThreshold() int {
+	return c.dc.Get("bufferedAmountLowThreshold").Int()
+}
+
+// SetBufferedAmountLowThreshold ...
+func (c *DataChannel) SetBufferedAmountLowThreshold(n int) {
+	c.dc.Set("bufferedAmountLowThreshold", n)
+}
+
+// Close ...
+func (c *DataChannel) Close() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s", r)
+		}
+	}()
+	c.dc.Call("close")
+	return
+}
+
+// ID returns -1 until the ID is assigned.
+func (c *DataChannel) ID() int {
+	return optionalInt(c.dc.Get("id"))
+}
+
+// ReadyState ...
+func (c *DataChannel) ReadyState() string {
+	return str(c.dc.Get("readyState"))
+}
+
+// Send copies data into a Uint8Array and sends it. Failures are logged;
+// SendContext returns them.
+func (c *DataChannel) Send(data []byte) {
+	c.sendFailed(c.sendMessage(data, false))
+}
+
+// SendText sends text as a string message.
+func (c *DataChannel) SendText(text string) {
+	c.sendFailed(c.sendMessage([]byte(text), true))
+}
+
+func (c *DataChannel) sendMessage(data []byte, isString bool) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s", r)
+		}
+	}()
+	if isString {
+		c.dc.Call("send", string(data))
+		return nil
+	}
+	buf := uint8Array.New(len(data))
+	js.CopyBytesToJS(buf, data)
+	c.dc.Call("send", buf)
+	return nil
+}
+
+// Label ...
+func (c *DataChannel) Label() string {
+	return str(c.dc.Get("label"))
+}
+
+// Ordered ...
+func (c *DataChannel) Ordered() bool {
+	return c.dc.Get("ordered").Truthy()
+}
+
+// optionalInt returns v, or -1 for null and undefined.
+func optionalInt(v js.Value) int {
+	if v.Type() != js.TypeNumber {
+		return -1
+	}
+	return v.Int()
+}
+
+// MaxPacketLifeTime returns -1 if unset.
+func (c *DataChannel) MaxPacketLifeTime() int {
+	return optionalInt(c.dc.Get("maxPacketLifeTime"))
+}
+
+// MaxRetransmits returns -1 if unset.
+func (c *DataChannel) MaxRetransmits() int {
+	return optionalInt(c.dc.Get("maxRetransmits"))
+}
+
+// Protocol ...
+func (c *DataChannel) Protocol() string {
+	return str(c.dc.Get("protocol"))
+}
+
+// Negotiated ...
+func (c *DataChannel) Negotiated() bool {
+	return c.dc.Get("negotiated").Truthy()
+}
+
+// MediaStream ...
+type MediaStream struct {
+	o js.Value
+}
+
+// NewMediaStream returns an empty stream. Browsers choose the id of the
+// stream themselves, so id is ignored.
+func NewMediaStream(id string) *MediaStream {
+	return &MediaStream{o: js.Global().Get("MediaStream").New()}
+}
+
+// ID ...
+func (s *MediaStream) ID() string {
+	return str(s.o.Get("id"))
+}
+
+// GetTracks ...
+func (s *MediaStream) GetTracks() []*MediaStreamTrack {
+	tracks := []*MediaStreamTrack{}
+	list := s.o.Call("getTracks")
+	for i := 0; i < list.Length(); i++ {
+		tracks = append(tracks, &MediaStreamTrack{o: list.Index(i)})
+	}
+	return tracks
+}
+
+// AddTrack ...
+func (s *MediaStream) AddTrack(t *MediaStreamTrack) {
+	s.o.Call("addTrack", t.o)
+}
+
+// MediaStreamTrack ...
+type MediaStreamTrack struct {
+	o js.Value
+}
+
+// ID ...
+func (t *MediaStreamTrack) ID() string {
+	return str(t.o.Get("id"))
+}
+
+// Kind ...
+func (t *MediaStreamTrack) Kind() string {
+	return str(t.o.Get("kind"))
+}
+
+// Stop ...
+func (t *MediaStreamTrack) Stop() {
+	t.o.Call("stop")
+}
+
+// GetUserMedia ...
+func GetUserMedia(constraints *Constraints) (*MediaStream, error) {
+	return GetUserMediaContext(context.Background(), constraints)
+}
+
+// GetUserMediaContext ...
+func GetUserMediaContext(ctx context.Context, constraints *Constraints) (stream *MediaStream, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s", r)
+		}
+	}()
+	mediaDevices := navigator.Get("mediaDevices")
+	o, err := await(ctx, mediaDevices.Call("getUserMedia", constraints.value()))
+	if err != nil {
+		if ctx.Err() == nil {
+			err = fmt.Errorf("get user media failed: %s", err)
+		}
+		return nil, err
+	}
+	return &MediaStream{o: o}, nil
+}
+
+// RTPTransceiver ...
+type RTPTransceiver struct {
+	o js.Value
+}
+
+// Mid returns "" until the transceiver is negotiated.
+func (t *RTPTransceiver) Mid() string {
+	return str(t.o.Get("mid"))
+}
+
+// Kind ...
+func (t *RTPTransceiver) Kind() string {
+	return str(t.o.Get("receiver").Get("track").Get("kind"))
+}
+
+// Direction ...
+func (t *RTPTransceiver) Direction() RTPTransceiverDirection {
+	d, _ := ParseRTPTransceiverDirection(str(t.o.Get("direction")))
+	return d
+}
+
+// SetDirection ...
+func (t *RTPTransceiver) SetDirection(d RTPTransceiverDirection) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s", r)
+		}
+	}()
+	t.o.Set("direction", d.String())
+	return
+}
+
+// Stop ...
+func (t *RTPTransceiver) Stop() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s", r)
+		}
+	}()
+	t.o.Call("stop")
+	return
+}
+
+// Sender ...
+func (t *RTPTransceiver) Sender() *RTPSender {
+	return &RTPSender{o: t.o.Get("sender")}
+}
+
+// Receiver ...
+func (t *RTPTransceiver) Receiver() *RTPReceiver {
+	return &RTPReceiver{o: t.o.Get("receiver")}
+}
+
+// RTPSender ...
+type RTPSender struct {
+	o js.Value
+}
+
+// Track returns nil if the sender has no track.
+func (s *RTPSender) Track() *MediaStreamTrack {
+	o := s.o.Get("track")
+	if o.IsNull() {
+		return nil
+	}
+	return &MediaStreamTrack{o: o}
+}
+
+// RTPReceiver ...
+type RTPReceiver struct {
+	o js.Value
+}
+
+// Track ...
+func (r *RTPReceiver) Track() *MediaStreamTrack {
+	return &MediaStreamTrack{o: r.o.Get("track")}
+}
@@ -0,0 +1,71 @@
+// Package webrtctest provides connected PeerConnections and DataChannels
+// for tests of packages built on webrtc.
+//
+// Built with the mock tag, the pairs stay in memory; with a real backend
+// they connect over the loopback interface.
+//
+//	dc, rc := webrtctest.Channels(t, "chat", nil)
+//	dc.SendText("hi") // arrives on rc
+package webrtctest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nobonobo/webrtc"
+)
+
+// timeout bounds the waits for a channel to be announced and opened.
+const timeout = 5 * time.Second
+
+// Pair returns a connected pair of PeerConnections, closed when the test
+// ends.
+func Pair(t testing.TB) (offerer, answerer *webrtc.PeerConnection) {
+	t.Helper()
+	offerer, answerer, err := webrtc.NewPair(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		offerer.Close()
+		answerer.Close()
+	})
+	return offerer, answerer
+}
+
+// Channels returns both ends of the DataChannel label between a new Pair,
+// once the offerer's end is open. opt may be nil.
+func Channels(t testing.TB, label string, opt *webrtc.DataChannelInit) (dc, rc *webrtc.DataChannel) {
+	t.Helper()
+	offerer, answerer := Pair(t)
+	accepted := make(chan *webrtc.DataChannel, 1)
+	answerer.OnDataChannel(func(dc *webrtc.DataChannel) {
+		if dc.Label() == label {
+			accepted <- dc
+		}
+	})
+	dc, err := offerer.CreateDataChannel(label, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.After(timeout)
+	events := dc.Events()
+	defer dc.Unsubscribe(events)
+	for dc.ReadyState() != "open" {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				t.Fatal("data channel closed")
+			}
+		case <-deadline:
+			t.Fatal("data channel not open")
+		}
+	}
+	select {
+	case rc = <-accepted:
+		return dc, rc
+	case <-deadline:
+		t.Fatal("data channel not announced")
+		return nil, nil
+	}
+}